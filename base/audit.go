@@ -0,0 +1,100 @@
+package base
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// TimestampedModel is a model with creation and update times. InsertOne sets
+// both, and UpdateOne and bulk replacements set the update time. Creation
+// times are only set when empty, and replacements without one keep the
+// document's.
+type TimestampedModel interface {
+	GetCreatedAt() time.Time
+	SetCreatedAt(time.Time)
+	GetUpdatedAt() time.Time
+	SetUpdatedAt(time.Time)
+}
+
+// AuditedModel is a model recording who created and last updated it, set
+// like the times of a TimestampedModel to the actor in the context
+type AuditedModel interface {
+	GetCreatedBy() string
+	SetCreatedBy(string)
+	GetUpdatedBy() string
+	SetUpdatedBy(string)
+}
+
+type actorKey struct{}
+
+// WithActor gets a context with the actor, such as a user ID, recorded on
+// the audited models written with it
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext gets the actor set with WithActor, or an empty string
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// now gets the current time as stored by MongoDB, in UTC to the millisecond
+func (h *helper) now() time.Time {
+	now := time.Now
+	if h.opts.Now != nil {
+		now = h.opts.Now
+	}
+	return now().UTC().Truncate(time.Millisecond)
+}
+
+// stamp sets the update time and actor of the item, and its creation time
+// and actor when they're empty and the write may insert it
+func (h *helper) stamp(ctx context.Context, item interface{}, inserting bool) {
+	if m, ok := item.(TimestampedModel); ok {
+		now := h.now()
+		if inserting && m.GetCreatedAt().IsZero() {
+			m.SetCreatedAt(now)
+		}
+		m.SetUpdatedAt(now)
+	}
+	if m, ok := item.(AuditedModel); ok {
+		actor := ActorFromContext(ctx)
+		if inserting && m.GetCreatedBy() == "" {
+			m.SetCreatedBy(actor)
+		}
+		m.SetUpdatedBy(actor)
+	}
+}
+
+// keepCreated sets the empty creation time and actor of an item to those
+// of the document it replaces, if there is one
+func (h *helper) keepCreated(ctx context.Context, coll string, filter interface{}, item interface{}) error {
+	tm, timed := item.(TimestampedModel)
+	am, audited := item.(AuditedModel)
+	timed = timed && tm.GetCreatedAt().IsZero()
+	audited = audited && am.GetCreatedBy() == ""
+	t := reflect.TypeOf(item)
+	if (!timed && !audited) || t.Kind() != reflect.Ptr {
+		return nil
+	}
+
+	existing := reflect.New(t.Elem()).Interface()
+	err := h.db.Collection(coll).FindOne(ctx, filter).Decode(existing)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return MapError(err)
+	}
+
+	if timed {
+		tm.SetCreatedAt(existing.(TimestampedModel).GetCreatedAt())
+	}
+	if audited {
+		am.SetCreatedBy(existing.(AuditedModel).GetCreatedBy())
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package base_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/mongotest"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type auditedModel struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Str       string             `bson:"str"`
+	CreatedAt time.Time          `bson:"createdAt"`
+	UpdatedAt time.Time          `bson:"updatedAt"`
+	CreatedBy string             `bson:"createdBy"`
+	UpdatedBy string             `bson:"updatedBy"`
+}
+
+func (m *auditedModel) GetCreatedAt() time.Time  { return m.CreatedAt }
+func (m *auditedModel) SetCreatedAt(t time.Time) { m.CreatedAt = t }
+func (m *auditedModel) GetUpdatedAt() time.Time  { return m.UpdatedAt }
+func (m *auditedModel) SetUpdatedAt(t time.Time) { m.UpdatedAt = t }
+func (m *auditedModel) GetCreatedBy() string     { return m.CreatedBy }
+func (m *auditedModel) SetCreatedBy(a string)    { m.CreatedBy = a }
+func (m *auditedModel) GetUpdatedBy() string     { return m.UpdatedBy }
+func (m *auditedModel) SetUpdatedBy(a string)    { m.UpdatedBy = a }
+
+// fixedClock gets a clock returning the times in turn
+func fixedClock(times ...time.Time) func() time.Time {
+	return func() time.Time {
+		t := times[0]
+		if len(times) > 1 {
+			times = times[1:]
+		}
+		return t
+	}
+}
+
+var (
+	created = time.Date(2024, 3, 1, 9, 30, 0, 123456789, time.UTC)
+	updated = time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)
+)
+
+func Test_ActorFromContext(t *testing.T) {
+	ctx := context.Background()
+
+	assert.Equal(t, "", base.ActorFromContext(ctx), "Expected no actor by default")
+	assert.Equal(t, "user-1", base.ActorFromContext(base.WithActor(ctx, "user-1")))
+}
+
+func Test_InsertOne_UpdateOne_Audited(t *testing.T) {
+	ctx := context.Background()
+	db := mongotest.New(t)
+	h := base.NewHelperWithOptions(db, base.HelperOptions{Now: fixedClock(created, updated)})
+
+	item := &auditedModel{Str: "a"}
+	id, err := h.InsertOne(base.WithActor(ctx, "alice"), "test", item)
+	assert.Nil(t, err)
+	createdMillis := created.Truncate(time.Millisecond)
+	assert.Equal(t, createdMillis, item.CreatedAt, "Expected the creation time to the millisecond")
+	assert.Equal(t, createdMillis, item.UpdatedAt)
+	assert.Equal(t, "alice", item.CreatedBy)
+	assert.Equal(t, "alice", item.UpdatedBy)
+
+	var stored auditedModel
+	h.FindOne(ctx, "test", bson.M{"_id": id}, &stored)
+	stored.Str = "b"
+	err = h.UpdateOne(base.WithActor(ctx, "bob"), "test", bson.M{"_id": id}, &stored)
+	assert.Nil(t, err)
+	assert.Equal(t, createdMillis, stored.CreatedAt, "Expected the creation time to be kept")
+	assert.Equal(t, updated, stored.UpdatedAt)
+	assert.Equal(t, "alice", stored.CreatedBy, "Expected the creator to be kept")
+	assert.Equal(t, "bob", stored.UpdatedBy)
+	mongotest.AssertDocumentExists(t, db, "test", bson.M{"_id": id, "createdBy": "alice", "updatedBy": "bob", "updatedAt": updated})
+}
+
+func Test_BulkWrite_Audited(t *testing.T) {
+	ctx := base.WithActor(context.Background(), "importer")
+	db := mongotest.New(t)
+	h := base.NewHelperWithOptions(db, base.HelperOptions{Now: fixedClock(updated)})
+
+	inserted := &auditedModel{Str: "a"}
+	replaced := &auditedModel{Str: "b", CreatedAt: created, CreatedBy: "alice"}
+	upserted := &auditedModel{Str: "c"}
+	_, err := h.BulkWrite(ctx, "test", []mongo.WriteModel{
+		mongo.NewInsertOneModel().SetDocument(inserted),
+		mongo.NewReplaceOneModel().SetFilter(bson.M{"str": "b"}).SetReplacement(replaced),
+		mongo.NewReplaceOneModel().SetFilter(bson.M{"str": "c"}).SetReplacement(upserted).SetUpsert(true),
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, auditedModel{Str: "a", CreatedAt: updated, UpdatedAt: updated, CreatedBy: "importer", UpdatedBy: "importer"}, *inserted)
+	assert.Equal(t, auditedModel{Str: "b", CreatedAt: created, UpdatedAt: updated, CreatedBy: "alice", UpdatedBy: "importer"}, *replaced)
+	assert.Equal(t, auditedModel{Str: "c", CreatedAt: updated, UpdatedAt: updated, CreatedBy: "importer", UpdatedBy: "importer"}, *upserted,
+		"Expected upserts to set the creation fields when empty")
+}
+
+func Test_UpdateOne_AuditedFreshModel(t *testing.T) {
+	ctx := context.Background()
+	db := mongotest.New(t)
+	h := base.NewHelperWithOptions(db, base.HelperOptions{Now: fixedClock(created, updated)})
+
+	id, err := h.InsertOne(base.WithActor(ctx, "alice"), "test", &auditedModel{Str: "a"})
+	assert.Nil(t, err)
+
+	// The replacement is built afresh rather than read
+	item := &auditedModel{ID: id.(primitive.ObjectID), Str: "b"}
+	err = h.UpdateOne(base.WithActor(ctx, "bob"), "test", bson.M{"_id": id}, item)
+
+	assert.Nil(t, err)
+	createdMillis := created.Truncate(time.Millisecond)
+	assert.Equal(t, createdMillis, item.CreatedAt, "Expected the document's creation time to be kept")
+	assert.Equal(t, "alice", item.CreatedBy, "Expected the document's creator to be kept")
+	mongotest.AssertDocumentExists(t, db, "test", bson.M{"_id": id, "str": "b", "createdAt": createdMillis, "createdBy": "alice", "updatedBy": "bob"})
+}
+
+func Test_BulkWrite_AuditedUpsertExisting(t *testing.T) {
+	ctx := context.Background()
+	db := mongotest.New(t)
+	h := base.NewHelperWithOptions(db, base.HelperOptions{Now: fixedClock(created, updated)})
+
+	_, err := h.InsertOne(base.WithActor(ctx, "alice"), "test", &auditedModel{Str: "a"})
+	assert.Nil(t, err)
+
+	// The upserted item wasn't read so has no creation fields
+	upserted := &auditedModel{Str: "a"}
+	_, err = h.BulkWrite(base.WithActor(ctx, "bob"), "test", []mongo.WriteModel{
+		mongo.NewReplaceOneModel().SetFilter(bson.M{"str": "a"}).SetReplacement(upserted).SetUpsert(true),
+	})
+
+	assert.Nil(t, err)
+	createdMillis := created.Truncate(time.Millisecond)
+	assert.Equal(t, auditedModel{Str: "a", CreatedAt: createdMillis, UpdatedAt: updated, CreatedBy: "alice", UpdatedBy: "bob"}, *upserted,
+		"Expected upserts to keep the existing document's creation fields")
+	mongotest.AssertDocumentExists(t, db, "test", bson.M{"str": "a", "createdAt": createdMillis, "createdBy": "alice", "updatedBy": "bob"})
+}
+
+func Test_InsertOne_Timestamped_DefaultClock(t *testing.T) {
+	ctx := context.Background()
+	db := mongotest.New(t)
+	before := time.Now().Truncate(time.Millisecond)
+
+	item := &auditedModel{Str: "a"}
+	_, err := db.Helper.InsertOne(ctx, "test", item)
+
+	assert.Nil(t, err)
+	assert.False(t, item.CreatedAt.Before(before), "Expected the current time")
+	assert.Equal(t, time.UTC, item.CreatedAt.Location(), "Expected times in UTC")
+	assert.Equal(t, "", item.CreatedBy, "Expected no actor without one in the context")
+}
@@ -0,0 +1,128 @@
+package base
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Collection types listed by GetCollection
+const (
+	CollectionTypeCollection = "collection"
+	CollectionTypeView       = "view"
+	CollectionTypeTimeSeries = "timeseries"
+)
+
+// CollectionInfo describes a collection or view
+type CollectionInfo struct {
+	Name string `bson:"name"`
+	// Type is one of the CollectionType constants
+	Type    string            `bson:"type"`
+	Options CollectionOptions `bson:"options"`
+}
+
+// CollectionOptions are the options a collection or view is created with
+type CollectionOptions struct {
+	// Capped collections hold at most Size bytes, and Max documents if set,
+	// removing the oldest documents to make room
+	Capped bool  `bson:"capped,omitempty"`
+	Size   int64 `bson:"size,omitempty"`
+	Max    int64 `bson:"max,omitempty"`
+	// TimeSeries stores the documents as measurements over time
+	TimeSeries *TimeSeriesOptions `bson:"timeseries,omitempty"`
+	// ExpireAfterSeconds removes documents from time-series and clustered
+	// collections once they're this old
+	ExpireAfterSeconds int64 `bson:"expireAfterSeconds,omitempty"`
+	// ClusteredIndex stores the documents in the order of the index
+	ClusteredIndex *ClusteredIndex `bson:"clusteredIndex,omitempty"`
+	// Collation is the default collation of the collection's queries and indexes
+	Collation *Collation `bson:"collation,omitempty"`
+	// CollectionValidator is the documents' validation
+	CollectionValidator `bson:",inline"`
+	// ViewOn makes a view of the named collection's documents, transformed
+	// by the Pipeline
+	ViewOn   string         `bson:"viewOn,omitempty"`
+	Pipeline mongo.Pipeline `bson:"pipeline,omitempty"`
+}
+
+// TimeSeriesOptions are the options of a time-series collection
+type TimeSeriesOptions struct {
+	// TimeField is the field holding each measurement's date
+	TimeField string `bson:"timeField"`
+	// MetaField is the field identifying a series of measurements
+	MetaField string `bson:"metaField,omitempty"`
+	// Granularity is "seconds", "minutes" or "hours"
+	Granularity string `bson:"granularity,omitempty"`
+}
+
+// ClusteredIndex is the index a clustered collection is ordered by. The
+// server only supports unique indexes on _id.
+type ClusteredIndex struct {
+	Key    bson.D `bson:"key"`
+	Unique bool   `bson:"unique"`
+	Name   string `bson:"name,omitempty"`
+}
+
+// Collation is the language rules used to compare strings
+type Collation struct {
+	Locale          string `bson:"locale"`
+	CaseLevel       bool   `bson:"caseLevel,omitempty"`
+	CaseFirst       string `bson:"caseFirst,omitempty"`
+	Strength        int    `bson:"strength,omitempty"`
+	NumericOrdering bool   `bson:"numericOrdering,omitempty"`
+	Alternate       string `bson:"alternate,omitempty"`
+	MaxVariable     string `bson:"maxVariable,omitempty"`
+	Normalization   bool   `bson:"normalization,omitempty"`
+	Backwards       bool   `bson:"backwards,omitempty"`
+}
+
+// GetCollection gets the collection or view with the name, or nil if there
+// isn't one
+func (h *helper) GetCollection(ctx context.Context, name string) (*CollectionInfo, error) {
+	var res struct {
+		Cursor struct {
+			FirstBatch []CollectionInfo `bson:"firstBatch"`
+		} `bson:"cursor"`
+	}
+	cmd := bson.D{{Key: "listCollections", Value: 1}, {Key: "filter", Value: bson.D{{Key: "name", Value: name}}}}
+	if err := h.db.RunCommand(ctx, cmd).Decode(&res); err != nil {
+		return nil, MapError(err)
+	}
+	if len(res.Cursor.FirstBatch) == 0 {
+		return nil, nil
+	}
+	return &res.Cursor.FirstBatch[0], nil
+}
+
+// CreateCollection creates a collection, or a view when the options have
+// ViewOn set
+func (h *helper) CreateCollection(ctx context.Context, name string, opts CollectionOptions) error {
+	b, err := bson.Marshal(opts)
+	if err != nil {
+		return err
+	}
+	var optsDoc bson.D
+	if err := bson.Unmarshal(b, &optsDoc); err != nil {
+		return err
+	}
+
+	cmd := append(bson.D{{Key: "create", Value: name}}, optsDoc...)
+	return MapError(h.db.RunCommand(ctx, cmd).Err())
+}
+
+// RenameCollection renames the collection within the database, replacing
+// any collection with the new name if dropTarget is set
+func (h *helper) RenameCollection(ctx context.Context, from string, to string, dropTarget bool) error {
+	cmd := bson.D{
+		{Key: "renameCollection", Value: h.db.Name() + "." + from},
+		{Key: "to", Value: h.db.Name() + "." + to},
+		{Key: "dropTarget", Value: dropTarget},
+	}
+	return MapError(h.db.Client().Database("admin").RunCommand(ctx, cmd).Err())
+}
+
+// DropCollection drops the collection or view if it exists
+func (h *helper) DropCollection(ctx context.Context, name string) error {
+	return MapError(h.db.Collection(name).Drop(ctx))
+}
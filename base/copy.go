@@ -0,0 +1,62 @@
+package base
+
+import "reflect"
+
+// DeepCopy sets dst to a copy of src, copying what its pointers, slices and
+// maps refer to so the copy doesn't share them with the original
+func DeepCopy(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Ptr:
+		if src.IsNil() {
+			dst.Set(src)
+			return
+		}
+		p := reflect.New(src.Type().Elem())
+		DeepCopy(p.Elem(), src.Elem())
+		dst.Set(p)
+	case reflect.Interface:
+		if src.IsNil() {
+			dst.Set(src)
+			return
+		}
+		c := reflect.New(src.Elem().Type()).Elem()
+		DeepCopy(c, src.Elem())
+		dst.Set(c)
+	case reflect.Slice:
+		if src.IsNil() {
+			dst.Set(src)
+			return
+		}
+		s := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			DeepCopy(s.Index(i), src.Index(i))
+		}
+		dst.Set(s)
+	case reflect.Map:
+		if src.IsNil() {
+			dst.Set(src)
+			return
+		}
+		m := reflect.MakeMapWithSize(src.Type(), src.Len())
+		for _, k := range src.MapKeys() {
+			c := reflect.New(src.Type().Elem()).Elem()
+			DeepCopy(c, src.MapIndex(k))
+			m.SetMapIndex(k, c)
+		}
+		dst.Set(m)
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			DeepCopy(dst.Index(i), src.Index(i))
+		}
+	case reflect.Struct:
+		// Unexported fields can't be copied deeply, so are copied as they are
+		dst.Set(src)
+		for i := 0; i < src.NumField(); i++ {
+			if dst.Field(i).CanSet() {
+				DeepCopy(dst.Field(i), src.Field(i))
+			}
+		}
+	default:
+		dst.Set(src)
+	}
+}
@@ -0,0 +1,109 @@
+package base
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultCountersCollection is the collection counters are stored in if none is given
+const DefaultCountersCollection = "counters"
+
+// CounterOptions represents options for a sequence counter
+type CounterOptions struct {
+	// Collection holds a document per sequence, defaults to DefaultCountersCollection
+	Collection string
+	// BlockSize reserves this many values per round-trip, serving the rest from
+	// memory. Values reserved but not used before shutdown are skipped.
+	BlockSize int64
+}
+
+// counterBlock is a range of values reserved for a sequence
+type counterBlock struct {
+	// mu is held while taking a value or reserving the next block, so only
+	// one call refills the block and the others wait to use it
+	mu   sync.Mutex
+	next int64
+	last int64
+}
+
+type counter struct {
+	db   MongoDB
+	opts CounterOptions
+	// mu guards the blocks map only, the stored sequences are incremented atomically
+	mu     sync.Mutex
+	blocks map[string]*counterBlock
+}
+
+// Next gets the next value of the named sequence, starting at 1
+func (c *counter) Next(ctx context.Context, name string) (int64, error) {
+	size := c.opts.BlockSize
+	if size <= 1 {
+		// Every value is its own round trip, so there's nothing to share
+		return c.reserve(ctx, name, 1)
+	}
+
+	c.mu.Lock()
+	b, ok := c.blocks[name]
+	if !ok {
+		b = &counterBlock{next: 1}
+		c.blocks[name] = b
+	}
+	c.mu.Unlock()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.next > b.last {
+		last, err := c.reserve(ctx, name, size)
+		if err != nil {
+			return 0, err
+		}
+		b.next, b.last = last-size+1, last
+	}
+	v := b.next
+	b.next++
+	return v, nil
+}
+
+// reserve atomically increments the stored sequence, returning its new value
+func (c *counter) reserve(ctx context.Context, name string, n int64) (int64, error) {
+	seq, err := c.increment(ctx, name, n)
+	if errors.Is(MapError(err), ErrDuplicateKey) {
+		// Another upsert created the sequence first, so it now exists to increment
+		seq, err = c.increment(ctx, name, n)
+	}
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed reserving values for counter '%s'", name)
+	}
+	return seq, nil
+}
+
+// increment adds n to the stored sequence, creating it if needed
+func (c *counter) increment(ctx context.Context, name string, n int64) (int64, error) {
+	coll := c.db.Collection(c.opts.Collection)
+
+	opt := options.FindOneAndUpdate()
+	opt.SetUpsert(true)
+	opt.SetReturnDocument(options.After)
+
+	var doc struct {
+		Seq int64 `bson:"seq"`
+	}
+	res := coll.FindOneAndUpdate(ctx, bson.M{"_id": name}, bson.M{"$inc": bson.M{"seq": n}}, opt)
+	if err := res.Decode(&doc); err != nil {
+		return 0, err
+	}
+	return doc.Seq, nil
+}
+
+// NewCounter get a Counter storing its sequences in the database.
+// Use it with NewCounterIDGenerator to give models sequential IDs on InsertOne.
+func NewCounter(db MongoDB, opts CounterOptions) Counter {
+	if opts.Collection == "" {
+		opts.Collection = DefaultCountersCollection
+	}
+	return &counter{db: db, opts: opts, blocks: make(map[string]*counterBlock)}
+}
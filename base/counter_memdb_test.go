@@ -0,0 +1,189 @@
+package base_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	bMocks "github.com/archy-bold/mongo-go-helper/mocks/base"
+	"github.com/archy-bold/mongo-go-helper/mongotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var counterNextTests = map[string]struct {
+	blockSize int64
+	calls     int
+	expected  []int64
+}{
+	"no block":   {0, 3, []int64{1, 2, 3}},
+	"block of 1": {1, 3, []int64{1, 2, 3}},
+	"block of 2": {2, 5, []int64{1, 2, 3, 4, 5}},
+}
+
+func Test_Counter_Next(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range counterNextTests {
+		db := mongotest.New(t)
+		c := base.NewCounter(db, base.CounterOptions{Collection: "test_counters", BlockSize: tt.blockSize})
+
+		values := make([]int64, 0, tt.calls)
+		for i := 0; i < tt.calls; i++ {
+			v, err := c.Next(ctx, "orders")
+			assert.Nilf(t, err, "Expected nil err for Next on test '%s'", tn)
+			values = append(values, v)
+		}
+
+		assert.Equalf(t, tt.expected, values, "Expected values to match for Next on test '%s'", tn)
+	}
+}
+
+func Test_Counter_Blocks(t *testing.T) {
+	ctx := context.Background()
+	db := mongotest.New(t)
+
+	// Two counters sharing a sequence should get distinct blocks
+	a := base.NewCounter(db, base.CounterOptions{Collection: "test_counters", BlockSize: 10})
+	b := base.NewCounter(db, base.CounterOptions{Collection: "test_counters", BlockSize: 10})
+
+	va, _ := a.Next(ctx, "invoices")
+	vb, _ := b.Next(ctx, "invoices")
+	va2, _ := a.Next(ctx, "invoices")
+	other, _ := a.Next(ctx, "receipts")
+
+	assert.Equal(t, int64(1), va)
+	assert.Equal(t, int64(11), vb)
+	assert.Equal(t, int64(2), va2)
+	assert.Equal(t, int64(1), other, "Expected each name to have its own sequence")
+}
+
+// slowDB is a database with slow FindOneAndUpdate round trips
+type slowDB struct {
+	base.MongoDB
+}
+
+func (db slowDB) Collection(name string, opts ...*options.CollectionOptions) base.MongoCollection {
+	return slowCollection{db.MongoDB.Collection(name, opts...)}
+}
+
+type slowCollection struct {
+	base.MongoCollection
+}
+
+func (c slowCollection) FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) base.SingleResult {
+	time.Sleep(5 * time.Millisecond)
+	return c.MongoCollection.FindOneAndUpdate(ctx, filter, update, opts...)
+}
+
+func Test_Counter_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	db := mongotest.New(t)
+
+	// Slow round trips so callers find the block empty at the same time
+	c := base.NewCounter(slowDB{db}, base.CounterOptions{Collection: "test_counters", BlockSize: 5})
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	seen := make(map[int64]bool)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var prev int64
+			for j := 0; j < 4; j++ {
+				v, err := c.Next(ctx, "concurrent")
+				assert.Nil(t, err)
+				assert.Greater(t, v, prev, "Expected each caller's values to increase")
+				prev = v
+				mu.Lock()
+				seen[v] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, seen, 200, "Expected every value to be unique")
+	for v := int64(1); v <= 200; v++ {
+		assert.Truef(t, seen[v], "Expected value %d not to be skipped", v)
+	}
+	mongotest.AssertDocumentExists(t, db, "test_counters", bson.M{"_id": "concurrent", "seq": 200}, "Expected one block reserved per refill")
+}
+
+// counterResult gets a mocked result of incrementing a sequence
+func counterResult(seq int64, err error) *bMocks.SingleResult {
+	res := &bMocks.SingleResult{}
+	res.On("Decode", mock.Anything).Return(func(v interface{}) error {
+		if err != nil {
+			return err
+		}
+		b, _ := bson.Marshal(bson.M{"_id": "orders", "seq": seq})
+		return bson.Unmarshal(b, v)
+	})
+	return res
+}
+
+var counterDuplicateTests = map[string]struct {
+	results []*bMocks.SingleResult
+	calls   int
+	err     string
+}{
+	"retried": {
+		[]*bMocks.SingleResult{counterResult(0, mongo.CommandError{Code: 11000, Message: "E11000 duplicate key"}), counterResult(2, nil)},
+		2, "",
+	},
+	"retried once": {
+		[]*bMocks.SingleResult{counterResult(0, errMock), counterResult(2, nil)},
+		1, "failed reserving values for counter 'orders': mock error",
+	},
+}
+
+func Test_Counter_DuplicateKey(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range counterDuplicateTests {
+		db := &bMocks.MongoDB{}
+		coll := &bMocks.MongoCollection{}
+		db.On("Collection", "counters").Return(coll)
+		calls := 0
+		coll.On("FindOneAndUpdate", ctx, bson.M{"_id": "orders"}, bson.M{"$inc": bson.M{"seq": int64(1)}}, mock.Anything).
+			Return(func(context.Context, interface{}, interface{}, ...*options.FindOneAndUpdateOptions) base.SingleResult {
+				calls++
+				return tt.results[calls-1]
+			})
+
+		v, err := base.NewCounter(db, base.CounterOptions{}).Next(ctx, "orders")
+
+		assert.Equalf(t, tt.calls, calls, "Expected the number of increments to match on test '%s'", tn)
+		if tt.err == "" {
+			assert.Nilf(t, err, "Expected nil err on test '%s'", tn)
+			assert.Equalf(t, int64(2), v, "Expected the value of the retry on test '%s'", tn)
+		} else {
+			assert.EqualErrorf(t, err, tt.err, "Expected err to match on test '%s'", tn)
+		}
+	}
+}
+
+func Test_Counter_InsertOne(t *testing.T) {
+	ctx := context.Background()
+	db := mongotest.New(t)
+
+	gen := base.NewCounterIDGenerator(base.NewCounter(db, base.CounterOptions{}))
+	h := base.NewHelperWithOptions(db, base.HelperOptions{IDGenerator: gen})
+
+	first := &exampleModelIntID{Num: 1}
+	second := &exampleModelIntID{Num: 2}
+	id1, err1 := h.InsertOne(ctx, "test", first)
+	id2, err2 := h.InsertOne(ctx, "test", second)
+
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.Equal(t, int64(1), id1)
+	assert.Equal(t, int64(2), id2)
+	assert.Equal(t, int64(2), second.ID, "Expected the model to have been given its ID")
+}
@@ -0,0 +1,95 @@
+package base
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// driverClient wraps a driver client as a MongoClient
+type driverClient struct {
+	*mongo.Client
+}
+
+func (c *driverClient) Database(name string, opts ...*options.DatabaseOptions) MongoDB {
+	return WrapDatabase(c.Client.Database(name, opts...))
+}
+
+// driverDatabase wraps a driver database as a MongoDB
+type driverDatabase struct {
+	*mongo.Database
+}
+
+func (db *driverDatabase) Client() MongoClient {
+	return WrapClient(db.Database.Client())
+}
+
+func (db *driverDatabase) Collection(name string, opts ...*options.CollectionOptions) MongoCollection {
+	return WrapCollection(db.Database.Collection(name, opts...))
+}
+
+func (db *driverDatabase) RunCommand(ctx context.Context, cmd interface{}, opts ...*options.RunCmdOptions) SingleResult {
+	return db.Database.RunCommand(ctx, cmd, opts...)
+}
+
+// driverCollection wraps a driver collection as a MongoCollection
+type driverCollection struct {
+	*mongo.Collection
+}
+
+func (c *driverCollection) Aggregate(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (MongoCursor, error) {
+	cur, err := c.Collection.Aggregate(ctx, pipeline, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return cur, nil
+}
+
+func (c *driverCollection) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (MongoCursor, error) {
+	cur, err := c.Collection.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return cur, nil
+}
+
+func (c *driverCollection) FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) SingleResult {
+	return c.Collection.FindOne(ctx, filter, opts...)
+}
+
+func (c *driverCollection) FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) SingleResult {
+	return c.Collection.FindOneAndUpdate(ctx, filter, update, opts...)
+}
+
+func (c *driverCollection) Indexes() MongoIndexView {
+	return &driverIndexView{c.Collection.Indexes()}
+}
+
+// driverIndexView wraps a driver index view as a MongoIndexView
+type driverIndexView struct {
+	mongo.IndexView
+}
+
+func (iv *driverIndexView) List(ctx context.Context, opts ...*options.ListIndexesOptions) (MongoCursor, error) {
+	cur, err := iv.IndexView.List(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return cur, nil
+}
+
+// WrapClient gets a MongoClient backed by the driver's client
+func WrapClient(client *mongo.Client) MongoClient {
+	return &driverClient{client}
+}
+
+// WrapDatabase gets a MongoDB backed by the driver's database
+func WrapDatabase(db *mongo.Database) MongoDB {
+	return &driverDatabase{db}
+}
+
+// WrapCollection gets a MongoCollection backed by the driver's collection
+func WrapCollection(coll *mongo.Collection) MongoCollection {
+	return &driverCollection{coll}
+}
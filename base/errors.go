@@ -0,0 +1,175 @@
+package base
+
+import (
+	"context"
+	"net"
+	"regexp"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The kinds of error returned by the helper. Errors are matched to a kind
+// with errors.Is, and errors.As still finds the driver's original error.
+var (
+	// ErrNotFound indicates that no document matched
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicateKey indicates that a write broke a unique index. The
+	// error is a *DuplicateKeyError with the index and key.
+	ErrDuplicateKey = errors.New("duplicate key")
+	// ErrValidation indicates that a document failed validation
+	ErrValidation = errors.New("validation failed")
+	// ErrTimeout indicates that an operation or its write concern timed out
+	ErrTimeout = errors.New("timed out")
+	// ErrConflict indicates that a write conflicted with another
+	ErrConflict = errors.New("conflict")
+	// ErrWriteConcern indicates that a write couldn't satisfy its write concern
+	ErrWriteConcern = errors.New("write concern failed")
+)
+
+// errorKinds are the kinds MapError maps to
+var errorKinds = []error{ErrNotFound, ErrDuplicateKey, ErrValidation, ErrTimeout, ErrConflict, ErrWriteConcern}
+
+// Server error codes mapped to error kinds
+var codeKinds = map[int]error{
+	50:    ErrTimeout,    // MaxTimeMSExpired
+	64:    ErrTimeout,    // WriteConcernFailed, from wtimeout
+	112:   ErrConflict,   // WriteConflict
+	121:   ErrValidation, // DocumentValidationFailure
+	262:   ErrTimeout,    // ExceededTimeLimit
+	11000: ErrDuplicateKey,
+	11001: ErrDuplicateKey,
+	12582: ErrDuplicateKey,
+}
+
+// kindError is an error of one of the kinds, keeping the original error
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap gets the original error
+func (e *kindError) Unwrap() error {
+	return e.err
+}
+
+// Is gets whether the error is of the target kind
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
+
+// DuplicateKeyError is returned when a write breaks a unique index
+type DuplicateKeyError struct {
+	// Collection is the namespace of the collection, such as "shop.products"
+	Collection string
+	// Index is the name of the unique index
+	Index string
+	// Key is the duplicated key as reported by the server, such as `{ sku: "a1" }`
+	Key string
+	// Err is the driver's original error
+	Err error
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap gets the driver's original error
+func (e *DuplicateKeyError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches ErrDuplicateKey
+func (e *DuplicateKeyError) Is(target error) bool {
+	return target == ErrDuplicateKey
+}
+
+var duplicateKeyPattern = regexp.MustCompile(`collection: (\S+) index: (\S+) dup key: (\{.*\})`)
+
+// newDuplicateKeyError gets the error with the details from the server's message
+func newDuplicateKeyError(msg string, err error) *DuplicateKeyError {
+	e := &DuplicateKeyError{Err: err}
+	if m := duplicateKeyPattern.FindStringSubmatch(msg); m != nil {
+		e.Collection, e.Index, e.Key = m[1], m[2], m[3]
+	}
+	return e
+}
+
+// newKindError gets the error of the kind for a server error code and message
+func newKindError(code int, msg string, err error) error {
+	kind, ok := codeKinds[code]
+	if !ok {
+		return nil
+	}
+	if kind == ErrDuplicateKey {
+		return newDuplicateKeyError(msg, err)
+	}
+	return &kindError{kind, err}
+}
+
+// MapError maps driver errors to the helper's kinds of error, so they can be
+// checked with errors.Is(err, ErrDuplicateKey) and the like. Errors of no
+// kind are returned unchanged.
+func MapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind) {
+			return err
+		}
+	}
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return &kindError{ErrNotFound, err}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &kindError{ErrTimeout, err}
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return &kindError{ErrTimeout, err}
+	}
+
+	var ce mongo.CommandError
+	if errors.As(err, &ce) {
+		if mapped := newKindError(int(ce.Code), ce.Message, err); mapped != nil {
+			return mapped
+		}
+		return err
+	}
+	var we mongo.WriteException
+	if errors.As(err, &we) {
+		return mapWriteErrors(err, we.WriteErrors, we.WriteConcernError)
+	}
+	var bwe mongo.BulkWriteException
+	if errors.As(err, &bwe) {
+		writeErrors := make([]mongo.WriteError, len(bwe.WriteErrors))
+		for i, e := range bwe.WriteErrors {
+			writeErrors[i] = e.WriteError
+		}
+		return mapWriteErrors(err, writeErrors, bwe.WriteConcernError)
+	}
+	return err
+}
+
+// mapWriteErrors maps a write exception by its first write error of a kind,
+// or else its write concern error
+func mapWriteErrors(err error, writeErrors []mongo.WriteError, wce *mongo.WriteConcernError) error {
+	for _, we := range writeErrors {
+		if mapped := newKindError(we.Code, we.Message, err); mapped != nil {
+			return mapped
+		}
+	}
+	if wce != nil {
+		if mapped := newKindError(wce.Code, wce.Message, err); mapped != nil {
+			return mapped
+		}
+		return &kindError{ErrWriteConcern, err}
+	}
+	return err
+}
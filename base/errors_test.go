@@ -0,0 +1,121 @@
+package base_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/mongotest"
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const dupKeyMessage = `E11000 duplicate key error collection: shop.products index: sku_1 dup key: { sku: "a1" }`
+
+var mapErrorTests = map[string]struct {
+	err  error
+	kind error
+}{
+	"nil":               {nil, nil},
+	"other":             {errMock, nil},
+	"no documents":      {mongo.ErrNoDocuments, base.ErrNotFound},
+	"no matches":        {base.ErrNoMatches, base.ErrNotFound},
+	"deadline":          {context.DeadlineExceeded, base.ErrTimeout},
+	"wrapped deadline":  {pkgerrors.Wrap(context.DeadlineExceeded, "failed on Find"), base.ErrTimeout},
+	"net timeout":       {timeoutError{true}, base.ErrTimeout},
+	"max time":          {mongo.CommandError{Code: 50, Name: "MaxTimeMSExpired"}, base.ErrTimeout},
+	"write conflict":    {mongo.CommandError{Code: 112, Name: "WriteConflict"}, base.ErrConflict},
+	"other command":     {mongo.CommandError{Code: 73, Name: "InvalidNamespace"}, nil},
+	"command duplicate": {mongo.CommandError{Code: 11000, Message: dupKeyMessage}, base.ErrDuplicateKey},
+	"duplicate key": {
+		mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000, Message: dupKeyMessage}}},
+		base.ErrDuplicateKey,
+	},
+	"validation": {
+		mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 121, Message: "Document failed validation"}}},
+		base.ErrValidation,
+	},
+	"other write error": {mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 2}}}, nil},
+	"write concern timeout": {
+		mongo.WriteException{WriteConcernError: &mongo.WriteConcernError{Code: 64, Message: "waiting for replication timed out"}},
+		base.ErrTimeout,
+	},
+	"write concern": {
+		mongo.WriteException{WriteConcernError: &mongo.WriteConcernError{Code: 100, Message: "Not enough data-bearing nodes"}},
+		base.ErrWriteConcern,
+	},
+	"bulk duplicate key": {
+		mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{
+			{WriteError: mongo.WriteError{Index: 0, Code: 2}},
+			{WriteError: mongo.WriteError{Index: 1, Code: 11000, Message: dupKeyMessage}},
+		}},
+		base.ErrDuplicateKey,
+	},
+}
+
+func Test_MapError(t *testing.T) {
+	for tn, tt := range mapErrorTests {
+		err := base.MapError(tt.err)
+
+		if tt.kind == nil {
+			assert.Equalf(t, tt.err, err, "Expected the error to be unchanged on test '%s'", tn)
+			continue
+		}
+		assert.Truef(t, errors.Is(err, tt.kind), "Expected the error to be a '%v' on test '%s'", tt.kind, tn)
+		assert.Equalf(t, tt.err.Error(), err.Error(), "Expected the original message on test '%s'", tn)
+		assert.Equalf(t, err, base.MapError(err), "Expected mapping twice to be unchanged on test '%s'", tn)
+	}
+}
+
+func Test_DuplicateKeyError(t *testing.T) {
+	we := mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000, Message: dupKeyMessage}}}
+
+	var dupErr *base.DuplicateKeyError
+	if assert.True(t, errors.As(base.MapError(we), &dupErr)) {
+		assert.Equal(t, "shop.products", dupErr.Collection)
+		assert.Equal(t, "sku_1", dupErr.Index)
+		assert.Equal(t, `{ sku: "a1" }`, dupErr.Key)
+		assert.Equal(t, we, dupErr.Err)
+	}
+
+	// Messages in other formats still map
+	err := base.MapError(mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000, Message: "duplicate"}}})
+	if assert.True(t, errors.As(err, &dupErr)) {
+		assert.Equal(t, "", dupErr.Index)
+	}
+}
+
+func Test_Helper_Errors(t *testing.T) {
+	ctx := context.Background()
+	db := mongotest.New(t)
+	_, err := db.Collection("items").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"str": 1},
+		Options: options.Index().SetUnique(true),
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	_, err = db.Helper.InsertOne(ctx, "items", &exampleModel{Str: "a"})
+	assert.Nil(t, err)
+	_, err = db.Helper.InsertOne(ctx, "items", &exampleModel{Str: "a"})
+	assert.True(t, errors.Is(err, base.ErrDuplicateKey), "Expected a duplicate key error from InsertOne")
+	var dupErr *base.DuplicateKeyError
+	if assert.True(t, errors.As(err, &dupErr)) {
+		assert.Equal(t, "str_1", dupErr.Index)
+		assert.Equal(t, `{ str: "a" }`, dupErr.Key)
+	}
+	var we mongo.WriteException
+	assert.True(t, errors.As(err, &we), "Expected the driver's error to be kept")
+
+	_, err = db.Helper.BulkWrite(ctx, "items", []mongo.WriteModel{mongo.NewInsertOneModel().SetDocument(bson.M{"str": "a"})})
+	assert.True(t, errors.Is(err, base.ErrDuplicateKey), "Expected a duplicate key error from BulkWrite")
+
+	err = db.Helper.UpdateOne(ctx, "items", bson.M{"str": "z"}, &exampleModel{Str: "z"})
+	assert.True(t, errors.Is(err, base.ErrNotFound), "Expected no matches to be not found")
+	assert.Equal(t, base.ErrNoMatches, err)
+}
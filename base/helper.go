@@ -4,11 +4,12 @@ import (
 	"context"
 	"math"
 	"reflect"
+	"time"
 
 	"github.com/archy-bold/mongo-go-helper/pagination"
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -16,8 +17,8 @@ import (
 // ErrUnexpectedInsertResult indicates that a result set is not a valid insert result
 var ErrUnexpectedInsertResult = errors.New("unexpected insert result")
 
-// ErrNoMatches indicates that no matches were found for a query
-var ErrNoMatches = errors.New("no matches found for query")
+// ErrNoMatches indicates that no matches were found for a query. It's an ErrNotFound.
+var ErrNoMatches error = &kindError{ErrNotFound, errors.New("no matches found for query")}
 
 // FindOptions represents Find function options
 type FindOptions struct {
@@ -26,37 +27,75 @@ type FindOptions struct {
 	Sorting  map[string]interface{}
 }
 
+// HelperOptions represents options for the helper
+type HelperOptions struct {
+	// IDGenerator sets the ID of new models passed to InsertOne
+	IDGenerator IDGenerator
+	// PoolMonitor receives the connection pool events of clients from NewClient
+	PoolMonitor *event.PoolMonitor
+	// VersionField is the document field holding the version of a
+	// VersionedModel. Defaults to DefaultVersionField.
+	VersionField string
+	// Now gets the time set on a TimestampedModel. Defaults to time.Now.
+	Now func() time.Time
+}
+
 // MongoHelper is used for helper functions
 type MongoHelper interface {
 	NewClient(uri string) (MongoClient, error)
 	Find(ctx context.Context, coll string, filter interface{}, item interface{}, opts FindOptions) (pagination.Result, error)
 	FindOne(ctx context.Context, coll string, filter interface{}, item interface{})
-	InsertOne(ctx context.Context, coll string, item interface{}) (primitive.ObjectID, error)
-	GetIDFromInsertOneResult(*mongo.InsertOneResult) (primitive.ObjectID, error)
+	InsertOne(ctx context.Context, coll string, item interface{}) (interface{}, error)
+	GetIDFromInsertOneResult(*mongo.InsertOneResult) (interface{}, error)
 	UpdateOne(ctx context.Context, coll string, filter interface{}, item interface{}) error
+	DeleteOne(ctx context.Context, coll string, filter interface{}, item interface{}) error
+	BulkWrite(ctx context.Context, coll string, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error)
 	GetIndex(ctx context.Context, coll string, index string) (*bson.M, error)
 	HasIndex(ctx context.Context, coll string, index string) (bool, error)
 	AddIndexIfNotExists(ctx context.Context, coll string, name string, keys interface{}) error
 	Aggregate(ctx context.Context, coll string, pipeline mongo.Pipeline) ([]bson.M, error)
+	GetValidator(ctx context.Context, coll string) (*CollectionValidator, error)
+	SetValidator(ctx context.Context, coll string, v CollectionValidator) error
+	GetCollection(ctx context.Context, name string) (*CollectionInfo, error)
+	CreateCollection(ctx context.Context, name string, opts CollectionOptions) error
+	RenameCollection(ctx context.Context, from string, to string, dropTarget bool) error
+	DropCollection(ctx context.Context, name string) error
 }
 
 type helper struct {
-	db MongoDB
+	db   MongoDB
+	opts HelperOptions
 }
 
 func (h *helper) NewClient(uri string) (MongoClient, error) {
 	opt := options.Client()
 	opt.ApplyURI(uri)
-	return mongo.NewClient(opt)
+	if h.opts.PoolMonitor != nil {
+		opt.SetPoolMonitor(h.opts.PoolMonitor)
+	}
+	client, err := mongo.NewClient(opt)
+	if err != nil {
+		return nil, err
+	}
+	return WrapClient(client), nil
 }
 
 func (h *helper) FindOne(ctx context.Context, coll string, filter interface{}, item interface{}) {
 	c := h.db.Collection(coll)
 	doc := c.FindOne(ctx, filter)
-	doc.Decode(item)
+	if doc.Decode(item) == nil && afterFind(ctx, item) != nil {
+		// Leave the item empty, as when nothing is found
+		v := reflect.ValueOf(item)
+		if v.Kind() == reflect.Ptr && !v.IsNil() {
+			v.Elem().Set(reflect.Zero(v.Elem().Type()))
+		}
+	}
 }
 
 func (h *helper) Find(ctx context.Context, coll string, filter interface{}, item interface{}, opts FindOptions) (res pagination.Result, err error) {
+	defer func() {
+		err = MapError(err)
+	}()
 	c := h.db.Collection(coll)
 
 	// Get the count for the result
@@ -93,35 +132,57 @@ func (h *helper) Find(ctx context.Context, coll string, filter interface{}, item
 		vp := reflect.New(reflect.TypeOf(item))
 		newItem := vp.Interface()
 		err = cur.Decode(newItem)
+		if err == nil {
+			if err = afterFind(ctx, newItem); err != nil {
+				res.Items = nil
+				return
+			}
+		}
 		res.Items = append(res.Items, newItem)
 	}
 
 	return
 }
 
-func (h *helper) InsertOne(ctx context.Context, coll string, item interface{}) (primitive.ObjectID, error) {
-	var oid primitive.ObjectID
+func (h *helper) InsertOne(ctx context.Context, coll string, item interface{}) (interface{}, error) {
+	var id interface{}
+
+	err := h.generateID(ctx, coll, item)
+	if err != nil {
+		return nil, err
+	}
+	if err = h.prepareInsert(ctx, item); err != nil {
+		return nil, err
+	}
 
 	c := h.db.Collection(coll)
 	res, err := c.InsertOne(ctx, item)
 
 	if err == nil {
-		oid, err = h.GetIDFromInsertOneResult(res)
+		id, err = h.GetIDFromInsertOneResult(res)
+	}
+	if m, ok := item.(AfterInserter); ok && err == nil {
+		err = m.AfterInsert(ctx)
 	}
 
-	return oid, err
+	return id, MapError(err)
 }
 
-func (h *helper) GetIDFromInsertOneResult(res *mongo.InsertOneResult) (primitive.ObjectID, error) {
-	if oid, ok := res.InsertedID.(primitive.ObjectID); ok {
-		return oid, nil
-	} else if res.InsertedID != nil {
-		return primitive.NilObjectID, nil
+func (h *helper) GetIDFromInsertOneResult(res *mongo.InsertOneResult) (interface{}, error) {
+	if res != nil && res.InsertedID != nil {
+		return res.InsertedID, nil
 	}
-	return primitive.NilObjectID, ErrUnexpectedInsertResult
+	return nil, ErrUnexpectedInsertResult
 }
 
 func (h *helper) UpdateOne(ctx context.Context, coll string, filter interface{}, item interface{}) error {
+	if err := h.prepareReplace(ctx, coll, filter, item, false); err != nil {
+		return err
+	}
+	if m, ok := item.(VersionedModel); ok {
+		return h.updateVersioned(ctx, coll, filter, m)
+	}
+
 	c := h.db.Collection(coll)
 	res, err := c.ReplaceOne(ctx, filter, item)
 
@@ -129,11 +190,46 @@ func (h *helper) UpdateOne(ctx context.Context, coll string, filter interface{},
 		return ErrNoMatches
 	}
 
-	return err
+	return MapError(err)
+}
+
+// DeleteOne deletes the document matching the filter. The item is the model
+// being deleted, for its BeforeDelete hook, and may be nil.
+func (h *helper) DeleteOne(ctx context.Context, coll string, filter interface{}, item interface{}) error {
+	if m, ok := item.(BeforeDeleter); ok {
+		if err := m.BeforeDelete(ctx); err != nil {
+			return err
+		}
+	}
+
+	c := h.db.Collection(coll)
+	res, err := c.DeleteOne(ctx, filter)
+
+	if err == nil && res.DeletedCount == 0 {
+		return ErrNoMatches
+	}
+
+	return MapError(err)
+}
+
+func (h *helper) BulkWrite(ctx context.Context, coll string, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	models, versioned, err := h.prepareWrites(ctx, coll, models)
+	if err != nil {
+		return nil, err
+	}
+
+	c := h.db.Collection(coll)
+	res, err := c.BulkWrite(ctx, models, opts...)
+	if len(versioned) > 0 {
+		if verr := h.checkVersions(ctx, c, versioned, err); err == nil {
+			err = verr
+		}
+	}
+	return res, MapError(err)
 }
 
 func (h *helper) GetIndex(ctx context.Context, coll string, index string) (*bson.M, error) {
-	var cur *mongo.Cursor
+	var cur MongoCursor
 	var err error
 
 	c := h.db.Collection(coll)
@@ -143,7 +239,7 @@ func (h *helper) GetIndex(ctx context.Context, coll string, index string) (*bson
 	cur, err = iv.List(ctx)
 
 	if err != nil {
-		return nil, err
+		return nil, MapError(err)
 	}
 	defer cur.Close(ctx)
 
@@ -154,11 +250,11 @@ func (h *helper) GetIndex(ctx context.Context, coll string, index string) (*bson
 		// Check the value of the name element
 		if ni, ok := (*elem)["name"]; ok {
 			if name, ok := ni.(string); ok && name == index {
-				return elem, err
+				return elem, MapError(err)
 			}
 		}
 	}
-	return nil, err
+	return nil, MapError(err)
 }
 
 func (h *helper) HasIndex(ctx context.Context, coll string, index string) (bool, error) {
@@ -194,17 +290,32 @@ func (h *helper) Aggregate(ctx context.Context, coll string, pipeline mongo.Pipe
 	c := h.db.Collection(coll)
 	cur, err := c.Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, err
+		return nil, MapError(err)
 	}
 
 	var ret []bson.M
 	if err = cur.All(ctx, &ret); err != nil {
-		return nil, err
+		return nil, MapError(err)
 	}
 
 	return ret, nil
 }
 
+// generateID sets a generated ID on new models when the helper has a generator
+func (h *helper) generateID(ctx context.Context, coll string, item interface{}) error {
+	m, ok := item.(ModelInterface)
+	if !ok || h.opts.IDGenerator == nil || m.Exists() {
+		return nil
+	}
+
+	id, err := h.opts.IDGenerator.NewID(ctx, coll)
+	if err != nil {
+		return errors.Wrap(err, "failed generating ID on InsertOne")
+	}
+	m.SetID(id)
+	return nil
+}
+
 func (h *helper) convertFindOpts(opts FindOptions) *options.FindOptions {
 	fo := options.Find()
 	// Pagination options
@@ -223,5 +334,10 @@ func (h *helper) convertFindOpts(opts FindOptions) *options.FindOptions {
 
 // NewHelper get an implementation of a MongoHelper
 func NewHelper(db MongoDB) MongoHelper {
-	return &helper{db}
+	return NewHelperWithOptions(db, HelperOptions{})
+}
+
+// NewHelperWithOptions get an implementation of a MongoHelper using the given options
+func NewHelperWithOptions(db MongoDB, opts HelperOptions) MongoHelper {
+	return &helper{db, opts}
 }
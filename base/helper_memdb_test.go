@@ -0,0 +1,225 @@
+package base_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/mongotest"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type exampleModel struct {
+	ID  primitive.ObjectID `bson:"_id,omitempty"`
+	Str string             `bson:"str"`
+	Num int                `bson:"num"`
+}
+
+func (m exampleModel) Exists() bool {
+	return !m.ID.IsZero()
+}
+
+func (m exampleModel) GetID() interface{} {
+	return m.ID
+}
+
+func (m *exampleModel) SetID(id interface{}) {
+	m.ID = id.(primitive.ObjectID)
+}
+
+type exampleModelIntID struct {
+	ID  int64 `bson:"_id"`
+	Num int   `bson:"num"`
+}
+
+func (m exampleModelIntID) Exists() bool {
+	return m.ID != 0
+}
+
+func (m exampleModelIntID) GetID() interface{} {
+	return m.ID
+}
+
+func (m *exampleModelIntID) SetID(id interface{}) {
+	m.ID = id.(int64)
+}
+
+// setupMemDB gets a helper on the test's own in-memory database seeded with five models
+func setupMemDB(t *testing.T, ctx context.Context) (base.MongoDB, base.MongoHelper) {
+	db := mongotest.New(t)
+	for i, str := range []string{"e", "b", "d", "a", "c"} {
+		db.Helper.InsertOne(ctx, "test", &exampleModel{Str: str, Num: i + 1})
+	}
+	return db, db.Helper
+}
+
+var findMemDBTests = map[string]struct {
+	filter   interface{}
+	opts     base.FindOptions
+	expected []string
+	total    int32
+	pages    int32
+}{
+	"all":           {bson.M{}, base.FindOptions{}, []string{"e", "b", "d", "a", "c"}, 5, 0},
+	"filtered":      {bson.M{"num": bson.M{"$gt": 3}}, base.FindOptions{}, []string{"a", "c"}, 2, 0},
+	"sorted":        {bson.M{}, base.FindOptions{Sorting: map[string]interface{}{"str": 1}}, []string{"a", "b", "c", "d", "e"}, 5, 0},
+	"first page":    {bson.M{}, base.FindOptions{PageSize: 2, Page: 1, Sorting: map[string]interface{}{"str": 1}}, []string{"a", "b"}, 5, 3},
+	"last page":     {bson.M{}, base.FindOptions{PageSize: 2, Page: 3, Sorting: map[string]interface{}{"str": 1}}, []string{"e"}, 5, 3},
+	"past the end":  {bson.M{}, base.FindOptions{PageSize: 2, Page: 4}, []string{}, 5, 3},
+	"no matches":    {bson.M{"str": "z"}, base.FindOptions{}, []string{}, 0, 0},
+	"sorted desc":   {bson.M{}, base.FindOptions{Sorting: map[string]interface{}{"num": -1}}, []string{"c", "a", "d", "b", "e"}, 5, 0},
+	"pointer query": {&bson.M{"str": "d"}, base.FindOptions{}, []string{"d"}, 1, 0},
+}
+
+func Test_Find_MemDB(t *testing.T) {
+	ctx := context.Background()
+	_, h := setupMemDB(t, ctx)
+
+	for tn, tt := range findMemDBTests {
+		res, err := h.Find(ctx, "test", tt.filter, exampleModel{}, tt.opts)
+
+		assert.Nilf(t, err, "Expected nil err for Find on test '%s'", tn)
+		assert.Equalf(t, tt.total, res.Total, "Expected total to match for Find on test '%s'", tn)
+		assert.Equalf(t, tt.pages, res.NumberOfPages, "Expected number of pages to match for Find on test '%s'", tn)
+		strs := make([]string, 0, len(res.Items))
+		for _, item := range res.Items {
+			strs = append(strs, item.(*exampleModel).Str)
+		}
+		assert.Equalf(t, tt.expected, strs, "Expected items to match for Find on test '%s'", tn)
+	}
+}
+
+func Test_InsertOne_MemDB(t *testing.T) {
+	ctx := context.Background()
+	db, h := setupMemDB(t, ctx)
+
+	id, err := h.InsertOne(ctx, "test", &exampleModel{Str: "f", Num: 6})
+	assert.Nil(t, err)
+	assert.IsType(t, primitive.ObjectID{}, id)
+
+	var item exampleModel
+	h.FindOne(ctx, "test", bson.M{"_id": id}, &item)
+	assert.Equal(t, "f", item.Str)
+
+	// Sequential IDs from a counter kept in the same database
+	gen := base.NewCounterIDGenerator(base.NewCounter(db, base.CounterOptions{BlockSize: 2}))
+	h = base.NewHelperWithOptions(db, base.HelperOptions{IDGenerator: gen})
+	for i := int64(1); i <= 3; i++ {
+		id, err = h.InsertOne(ctx, "numbered", &exampleModelIntID{Num: int(i)})
+		assert.Nil(t, err)
+		assert.Equal(t, i, id)
+	}
+
+	_, err = h.InsertOne(ctx, "numbered", &exampleModelIntID{ID: 1})
+	var dupErr *base.DuplicateKeyError
+	if assert.True(t, errors.As(err, &dupErr), "Expected a duplicate key error") {
+		assert.Equal(t, "_id_", dupErr.Index)
+		assert.Equal(t, "{ _id: 1 }", dupErr.Key)
+	}
+}
+
+func Test_UpdateOne_MemDB(t *testing.T) {
+	ctx := context.Background()
+	_, h := setupMemDB(t, ctx)
+
+	var item exampleModel
+	h.FindOne(ctx, "test", bson.M{"str": "a"}, &item)
+	item.Num = 100
+
+	err := h.UpdateOne(ctx, "test", bson.M{"_id": item.ID}, &item)
+	assert.Nil(t, err)
+
+	var updated exampleModel
+	h.FindOne(ctx, "test", bson.M{"_id": item.ID}, &updated)
+	assert.Equal(t, 100, updated.Num)
+
+	err = h.UpdateOne(ctx, "test", bson.M{"str": "z"}, &item)
+	assert.Equal(t, base.ErrNoMatches, err)
+}
+
+func Test_BulkWrite_MemDB(t *testing.T) {
+	ctx := context.Background()
+	_, h := setupMemDB(t, ctx)
+
+	res, err := h.BulkWrite(ctx, "test", []mongo.WriteModel{
+		mongo.NewInsertOneModel().SetDocument(&exampleModel{Str: "f", Num: 6}),
+		mongo.NewUpdateManyModel().SetFilter(bson.M{"num": bson.M{"$lte": 2}}).SetUpdate(bson.M{"$set": bson.M{"str": "low"}}),
+		mongo.NewDeleteOneModel().SetFilter(bson.M{"str": "c"}),
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), res.InsertedCount)
+	assert.Equal(t, int64(2), res.ModifiedCount)
+	assert.Equal(t, int64(1), res.DeletedCount)
+}
+
+var bulkWriteOrderedTests = map[string]struct {
+	opts     []*options.BulkWriteOptions
+	inserted int64
+}{
+	"ordered by default": {nil, 0},
+	"ordered":            {[]*options.BulkWriteOptions{options.BulkWrite().SetOrdered(true)}, 0},
+	"unordered":          {[]*options.BulkWriteOptions{options.BulkWrite().SetOrdered(false)}, 1},
+}
+
+func Test_BulkWrite_Ordered_MemDB(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range bulkWriteOrderedTests {
+		db, h := setupMemDB(t, ctx)
+		db.Collection("ordered").InsertOne(ctx, bson.M{"_id": 1})
+
+		res, err := h.BulkWrite(ctx, "ordered", []mongo.WriteModel{
+			mongo.NewInsertOneModel().SetDocument(bson.M{"_id": 1}),
+			mongo.NewInsertOneModel().SetDocument(bson.M{"_id": 2}),
+		}, tt.opts...)
+
+		assert.Truef(t, errors.Is(err, base.ErrDuplicateKey), "Expected a duplicate key error on test '%s'", tn)
+		assert.Equalf(t, tt.inserted, res.InsertedCount, "Expected the writes after the failure to match on test '%s'", tn)
+	}
+}
+
+func Test_Indexes_MemDB(t *testing.T) {
+	ctx := context.Background()
+	db, h := setupMemDB(t, ctx)
+
+	has, err := h.HasIndex(ctx, "test", "str_index")
+	assert.Nil(t, err)
+	assert.False(t, has)
+
+	err = h.AddIndexIfNotExists(ctx, "test", "str_index", bson.M{"str": 1})
+	assert.Nil(t, err)
+	err = h.AddIndexIfNotExists(ctx, "test", "str_index", bson.M{"str": 1})
+	assert.Nil(t, err)
+
+	idx, err := h.GetIndex(ctx, "test", "str_index")
+	assert.Nil(t, err)
+	if assert.NotNil(t, idx) {
+		assert.Equal(t, bson.M{"str": int32(1)}, (*idx)["key"])
+	}
+
+	idx, err = h.GetIndex(ctx, "missing", "str_index")
+	assert.Nil(t, err)
+	assert.Nil(t, idx, "Expected no index on a missing collection")
+
+	_, err = h.GetIndex(ctx, "$bad", "str_index")
+	assert.EqualError(t, err, "(InvalidNamespace) Invalid collection name specified '"+db.Name()+".$bad'")
+}
+
+func Test_Aggregate_MemDB(t *testing.T) {
+	ctx := context.Background()
+	_, h := setupMemDB(t, ctx)
+
+	res, err := h.Aggregate(ctx, "test", mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"num": bson.M{"$gte": 2}}}},
+		{{Key: "$group", Value: bson.D{{Key: "_id", Value: nil}, {Key: "total", Value: bson.M{"$sum": "$num"}}}}},
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []bson.M{{"_id": nil, "total": int32(14)}}, res)
+}
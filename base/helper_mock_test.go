@@ -0,0 +1,216 @@
+package base_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	bMocks "github.com/archy-bold/mongo-go-helper/mocks/base"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var errMock = errors.New("mock error")
+
+// setupMocks gets a helper over a mocked database with a single mocked collection
+func setupMocks() (base.MongoHelper, *bMocks.MongoCollection) {
+	db := &bMocks.MongoDB{}
+	coll := &bMocks.MongoCollection{}
+	db.On("Collection", "test").Return(coll)
+	return base.NewHelper(db), coll
+}
+
+// cursorOver gets a mocked cursor iterating over the documents
+func cursorOver(docs ...bson.M) *bMocks.MongoCursor {
+	cur := &bMocks.MongoCursor{}
+	i := 0
+	cur.On("Next", mock.Anything).Return(func(context.Context) bool {
+		i++
+		return i <= len(docs)
+	})
+	cur.On("Decode", mock.Anything).Return(func(v interface{}) error {
+		b, _ := bson.Marshal(docs[i-1])
+		return bson.Unmarshal(b, v)
+	})
+	cur.On("Close", mock.Anything).Return(nil)
+	return cur
+}
+
+var findMockTests = map[string]struct {
+	count    int64
+	countErr error
+	docs     []bson.M
+	findErr  error
+	expected []string
+	err      string
+}{
+	"success":     {2, nil, []bson.M{{"str": "a"}, {"str": "b"}}, nil, []string{"a", "b"}, ""},
+	"no results":  {0, nil, nil, nil, []string{}, ""},
+	"count error": {0, errMock, nil, nil, nil, "failed count on Find: mock error"},
+	"find error":  {2, nil, nil, errMock, nil, "failed on Find: mock error"},
+}
+
+func Test_Find_Mocked(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range findMockTests {
+		h, coll := setupMocks()
+		coll.On("CountDocuments", ctx, bson.M{}).Return(tt.count, tt.countErr)
+		var cur base.MongoCursor
+		if tt.findErr == nil {
+			cur = cursorOver(tt.docs...)
+		}
+		coll.On("Find", ctx, bson.M{}, mock.Anything).Return(cur, tt.findErr)
+
+		res, err := h.Find(ctx, "test", bson.M{}, exampleModel{}, base.FindOptions{})
+
+		if tt.err == "" {
+			assert.Nilf(t, err, "Expected nil err for Find on test '%s'", tn)
+			assert.Equalf(t, int32(tt.count), res.Total, "Expected total to match for Find on test '%s'", tn)
+			strs := make([]string, 0, len(res.Items))
+			for _, item := range res.Items {
+				strs = append(strs, item.(*exampleModel).Str)
+			}
+			assert.Equalf(t, tt.expected, strs, "Expected items to match for Find on test '%s'", tn)
+			cur.(*bMocks.MongoCursor).AssertCalled(t, "Close", ctx)
+		} else {
+			assert.EqualErrorf(t, err, tt.err, "Expected error for Find on test '%s'", tn)
+		}
+	}
+}
+
+func Test_FindOne_Mocked(t *testing.T) {
+	ctx := context.Background()
+	h, coll := setupMocks()
+	res := &bMocks.SingleResult{}
+	res.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(0).(*exampleModel).Str = "found"
+	}).Return(nil)
+	coll.On("FindOne", ctx, bson.M{"num": 1}).Return(res)
+
+	var item exampleModel
+	h.FindOne(ctx, "test", bson.M{"num": 1}, &item)
+
+	assert.Equal(t, "found", item.Str)
+}
+
+var insertOneMockTests = map[string]struct {
+	res      *mongo.InsertOneResult
+	err      error
+	expected interface{}
+	expErr   error
+}{
+	"success":      {&mongo.InsertOneResult{InsertedID: "id"}, nil, "id", nil},
+	"error":        {nil, errMock, nil, errMock},
+	"no result id": {&mongo.InsertOneResult{}, nil, nil, base.ErrUnexpectedInsertResult},
+}
+
+func Test_InsertOne_Mocked(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range insertOneMockTests {
+		h, coll := setupMocks()
+		item := &exampleModel{Str: "new"}
+		coll.On("InsertOne", ctx, item).Return(tt.res, tt.err)
+
+		id, err := h.InsertOne(ctx, "test", item)
+
+		assert.Equalf(t, tt.expErr, err, "Expected err to match for InsertOne on test '%s'", tn)
+		assert.Equalf(t, tt.expected, id, "Expected ID to match for InsertOne on test '%s'", tn)
+	}
+}
+
+var updateOneMockTests = map[string]struct {
+	res      *mongo.UpdateResult
+	err      error
+	expected error
+}{
+	"success":    {&mongo.UpdateResult{MatchedCount: 1}, nil, nil},
+	"no matches": {&mongo.UpdateResult{}, nil, base.ErrNoMatches},
+	"error":      {nil, errMock, errMock},
+}
+
+func Test_UpdateOne_Mocked(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range updateOneMockTests {
+		h, coll := setupMocks()
+		item := &exampleModel{Str: "updated"}
+		coll.On("ReplaceOne", ctx, bson.M{"num": 1}, item).Return(tt.res, tt.err)
+
+		err := h.UpdateOne(ctx, "test", bson.M{"num": 1}, item)
+
+		assert.Equalf(t, tt.expected, err, "Expected err to match for UpdateOne on test '%s'", tn)
+	}
+}
+
+var addIndexMockTests = map[string]struct {
+	indexes []bson.M
+	listErr error
+	creates int
+	err     error
+}{
+	"missing index":  {[]bson.M{{"name": "_id_"}}, nil, 1, nil},
+	"existing index": {[]bson.M{{"name": "_id_"}, {"name": "test_index"}}, nil, 0, nil},
+	"list error":     {nil, errMock, 0, errMock},
+}
+
+func Test_AddIndexIfNotExists_Mocked(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range addIndexMockTests {
+		h, coll := setupMocks()
+		iv := &bMocks.MongoIndexView{}
+		coll.On("Indexes").Return(iv)
+		var cur base.MongoCursor
+		if tt.listErr == nil {
+			cur = cursorOver(tt.indexes...)
+		}
+		iv.On("List", ctx).Return(cur, tt.listErr)
+		iv.On("CreateOne", ctx, mock.AnythingOfType("mongo.IndexModel")).Return("test_index", nil)
+
+		err := h.AddIndexIfNotExists(ctx, "test", "test_index", bson.M{"foo": 1})
+
+		assert.Equalf(t, tt.err, err, "Expected err to match for AddIndexIfNotExists on test '%s'", tn)
+		iv.AssertNumberOfCalls(t, "CreateOne", tt.creates)
+	}
+}
+
+var aggregateMockTests = map[string]struct {
+	aggErr   error
+	allErr   error
+	expected []bson.M
+	err      error
+}{
+	"success":         {nil, nil, []bson.M{{"total": int32(3)}}, nil},
+	"aggregate error": {errMock, nil, nil, errMock},
+	"decode error":    {nil, errMock, nil, errMock},
+}
+
+func Test_Aggregate_Mocked(t *testing.T) {
+	ctx := context.Background()
+	pipeline := mongo.Pipeline{{{Key: "$count", Value: "total"}}}
+
+	for tn, tt := range aggregateMockTests {
+		h, coll := setupMocks()
+		var cur base.MongoCursor
+		if tt.aggErr == nil {
+			c := &bMocks.MongoCursor{}
+			c.On("All", ctx, mock.Anything).Run(func(args mock.Arguments) {
+				if tt.allErr == nil {
+					*args.Get(1).(*[]bson.M) = tt.expected
+				}
+			}).Return(tt.allErr)
+			cur = c
+		}
+		coll.On("Aggregate", ctx, pipeline).Return(cur, tt.aggErr)
+
+		res, err := h.Aggregate(ctx, "test", pipeline)
+
+		assert.Equalf(t, tt.err, err, "Expected err to match for Aggregate on test '%s'", tn)
+		assert.Equalf(t, tt.expected, res, "Expected results to match for Aggregate on test '%s'", tn)
+	}
+}
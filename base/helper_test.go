@@ -2,12 +2,14 @@ package base
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/archy-bold/mongo-go-helper/pagination"
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -16,6 +18,23 @@ type exampleSubStruct struct {
 	TypeID string `bson:"type_id"`
 }
 
+type exampleModelStrID struct {
+	ID  string `bson:"_id"`
+	Num int    `bson:"num"`
+}
+
+func (m exampleModelStrID) Exists() bool {
+	return m.ID != ""
+}
+
+func (m exampleModelStrID) GetID() interface{} {
+	return m.ID
+}
+
+func (m *exampleModelStrID) SetID(id interface{}) {
+	m.ID = id.(string)
+}
+
 type exampleStruct struct {
 	ID  primitive.ObjectID `bson:"_id, omitempty"`
 	Str string             `bson:"str"`
@@ -28,6 +47,7 @@ var (
 	testDB               = "db-test"
 	defaultURI           = "mongodb://localhost:27017"
 	sampleObjectIDObj, _ = primitive.ObjectIDFromHex(sampleObjectID)
+	errExample           = errors.New("error")
 )
 
 var newClientTests = map[string]struct {
@@ -40,7 +60,7 @@ var newClientTests = map[string]struct {
 }
 
 func Test_NewClient(t *testing.T) {
-	h := &helper{nil}
+	h := &helper{}
 
 	for tn, tt := range newClientTests {
 		client, err := h.NewClient(tt.uri)
@@ -57,6 +77,21 @@ func Test_NewClient(t *testing.T) {
 	}
 }
 
+func Test_NewClient_PoolMonitor(t *testing.T) {
+	var events []string
+	h := NewHelperWithOptions(nil, HelperOptions{
+		PoolMonitor: &event.PoolMonitor{Event: func(e *event.PoolEvent) {
+			events = append(events, e.Type)
+		}},
+	})
+
+	client, err := h.NewClient(defaultURI)
+	assert.Nil(t, err, "Expected nil err for NewClient with a pool monitor")
+	client.Connect(context.Background())
+
+	assert.Contains(t, events, event.PoolCreated, "Expected the pool monitor to receive events")
+}
+
 var findOneTests = map[string]struct {
 	filter  interface{}
 	table   string
@@ -74,7 +109,7 @@ func Test_FindOne(t *testing.T) {
 	defer cleanTests(ctx, db)
 	// Create the helper
 	var h MongoHelper
-	h = &helper{db}
+	h = &helper{db: db}
 	// Insert one row into test
 	oid := primitive.NewObjectID()
 	h.InsertOne(ctx, "test", exampleStruct{oid, "test string", 999, exampleSubStruct{}})
@@ -139,7 +174,7 @@ func Test_Find(t *testing.T) {
 	defer cleanTests(ctx, db)
 	// Create the helper
 	var h MongoHelper
-	h = &helper{db}
+	h = &helper{db: db}
 	// Insert two rows into test
 	item1 := exampleStruct{primitive.NewObjectID(), "test string", 999, exampleSubStruct{"type_1"}}
 	h.InsertOne(ctx, "test", item1)
@@ -200,19 +235,20 @@ func Test_InsertOne(t *testing.T) {
 	defer cleanTests(ctx, db)
 	// Create the helper
 	var h MongoHelper
-	h = &helper{db}
+	h = &helper{db: db}
 
 	for tn, tt := range insertOneTests {
 		var itemArg exampleStruct
 		var ok bool
-		var oid primitive.ObjectID
+		var id interface{}
 		var err error
 		if itemArg, ok = tt.item.(exampleStruct); ok && itemArg.ID.IsZero() {
 			itemArg.ID = primitive.NewObjectID()
-			oid, err = h.InsertOne(ctx, tt.table, itemArg)
+			id, err = h.InsertOne(ctx, tt.table, itemArg)
 		} else {
-			oid, err = h.InsertOne(ctx, tt.table, tt.item)
+			id, err = h.InsertOne(ctx, tt.table, tt.item)
 		}
+		oid, _ := id.(primitive.ObjectID)
 
 		// Try to get the inserted item for assertions
 		filter := &bson.M{"_id": oid}
@@ -239,13 +275,15 @@ func Test_InsertOne(t *testing.T) {
 }
 
 var getIDFromInsertOneResultTests = map[string]struct {
-	res interface{}
-	id  string
-	err error
+	res      interface{}
+	expected interface{}
+	err      error
 }{
-	"success":            {sampleObjectID, sampleObjectID, nil},
-	"not objectid":       {&bson.E{Key: "_id", Value: "string id"}, "", nil},
-	"error empty result": {nil, "", ErrUnexpectedInsertResult},
+	"objectid":           {sampleObjectIDObj, sampleObjectIDObj, nil},
+	"string id":          {"string id", "string id", nil},
+	"int64 id":           {int64(42), int64(42), nil},
+	"composite id":       {bson.D{{Key: "a", Value: 1}, {Key: "b", Value: "x"}}, bson.D{{Key: "a", Value: 1}, {Key: "b", Value: "x"}}, nil},
+	"error empty result": {nil, nil, ErrUnexpectedInsertResult},
 }
 
 func Test_GetIDFromInsertOneResult(t *testing.T) {
@@ -255,25 +293,60 @@ func Test_GetIDFromInsertOneResult(t *testing.T) {
 
 	for tn, tt := range getIDFromInsertOneResultTests {
 		// Set up the result
-		res := &mongo.InsertOneResult{}
-		if id, ok := tt.res.(string); ok {
-			oid, _ := primitive.ObjectIDFromHex(id)
-			res = &mongo.InsertOneResult{InsertedID: oid}
-		} else if el, ok := tt.res.(*bson.E); ok {
-			res = &mongo.InsertOneResult{InsertedID: el}
-		}
+		res := &mongo.InsertOneResult{InsertedID: tt.res}
 
 		id, err := h.GetIDFromInsertOneResult(res)
 
 		// Do the assertions
 		if tt.err == nil {
-			expected, _ := primitive.ObjectIDFromHex(tt.id)
 			assert.Nilf(t, err, "Expected nil err for GetIDFromInsertOneResult on test '%s'", tn)
-			assert.Equalf(t, expected, id, "Expected IDs to match for GetIDFromInsertOneResult on test '%s'", tn)
+			assert.Equalf(t, tt.expected, id, "Expected IDs to match for GetIDFromInsertOneResult on test '%s'", tn)
 		} else {
 			assert.NotNilf(t, err, "Expected not nil err for GetIDFromInsertOneResult on test '%s'", tn)
 			assert.Equalf(t, tt.err, err, "Expected errors to be equal for GetIDFromInsertOneResult on test '%s'", tn)
-			assert.Truef(t, id.IsZero(), "Expected id to be zero on test '%s'", tn)
+			assert.Nilf(t, id, "Expected id to be nil on test '%s'", tn)
+		}
+	}
+}
+
+var insertOneGeneratedIDTests = map[string]struct {
+	gen   IDGenerator
+	item  *exampleModelStrID
+	check func(id interface{}) bool
+	err   string
+}{
+	"generated": {
+		UUIDv4Generator, &exampleModelStrID{Num: 1},
+		func(id interface{}) bool { return len(id.(string)) == 36 }, "",
+	},
+	"keeps existing": {
+		UUIDv4Generator, &exampleModelStrID{ID: "mine", Num: 2},
+		func(id interface{}) bool { return id == "mine" }, "",
+	},
+	"generator error": {
+		IDGeneratorFunc(func(ctx context.Context, coll string) (interface{}, error) { return nil, errExample }),
+		&exampleModelStrID{Num: 3},
+		nil, "failed generating ID on InsertOne: error",
+	},
+}
+
+func Test_InsertOne_GeneratedID(t *testing.T) {
+	ctx := context.Background()
+	db, _ := setupTests(ctx)
+	defer cleanTests(ctx, db)
+
+	for tn, tt := range insertOneGeneratedIDTests {
+		h := NewHelperWithOptions(db, HelperOptions{IDGenerator: tt.gen})
+
+		id, err := h.InsertOne(ctx, "test", tt.item)
+
+		if tt.err == "" {
+			assert.Nilf(t, err, "Expected nil err for InsertOne on test '%s'", tn)
+			assert.Truef(t, tt.check(id), "Expected the ID to be as expected for InsertOne on test '%s'", tn)
+			assert.Equalf(t, tt.item.ID, id, "Expected the item ID to be set for InsertOne on test '%s'", tn)
+		} else {
+			assert.EqualErrorf(t, err, tt.err, "Expected err to match for InsertOne on test '%s'", tn)
+			assert.Nilf(t, id, "Expected nil ID for InsertOne on test '%s'", tn)
 		}
 	}
 }
@@ -296,7 +369,7 @@ func Test_UpdateOne(t *testing.T) {
 	defer cleanTests(ctx, db)
 	// Create the helper
 	var h MongoHelper
-	h = &helper{db}
+	h = &helper{db: db}
 
 	// Insert one row into test
 	oid := primitive.NewObjectID()
@@ -337,6 +410,54 @@ func Test_UpdateOne(t *testing.T) {
 	}
 }
 
+var bulkWriteTests = map[string]struct {
+	models   []mongo.WriteModel
+	inserted int64
+	matched  int64
+	err      bool
+}{
+	"insert and replace": {
+		[]mongo.WriteModel{
+			mongo.NewInsertOneModel().SetDocument(exampleStruct{primitive.NewObjectID(), "new", 1000, exampleSubStruct{}}),
+			mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": sampleObjectIDObj}).SetReplacement(exampleStruct{sampleObjectIDObj, "replaced", 999, exampleSubStruct{}}),
+		},
+		1, 1, false,
+	},
+	"duplicate continues": {
+		[]mongo.WriteModel{
+			mongo.NewInsertOneModel().SetDocument(exampleStruct{sampleObjectIDObj, "dup", 999, exampleSubStruct{}}),
+			mongo.NewInsertOneModel().SetDocument(exampleStruct{primitive.NewObjectID(), "new", 1000, exampleSubStruct{}}),
+		},
+		1, 0, true,
+	},
+}
+
+func Test_BulkWrite(t *testing.T) {
+	ctx := context.Background()
+	db, _ := setupTests(ctx)
+	defer cleanTests(ctx, db)
+	// Create the helper
+	var h MongoHelper
+	h = &helper{db: db}
+
+	for tn, tt := range bulkWriteTests {
+		// Reset to a single row in test
+		db.Collection("test").DeleteMany(ctx, &bson.M{})
+		h.InsertOne(ctx, "test", exampleStruct{sampleObjectIDObj, "test string", 999, exampleSubStruct{}})
+
+		res, err := h.BulkWrite(ctx, "test", tt.models)
+
+		if tt.err {
+			assert.Errorf(t, err, "Expected not nil err for BulkWrite on test '%s'", tn)
+			assert.IsTypef(t, mongo.BulkWriteException{}, err, "Expected a bulk write exception on test '%s'", tn)
+		} else {
+			assert.Nilf(t, err, "Expected nil err for BulkWrite on test '%s'", tn)
+		}
+		assert.Equalf(t, tt.inserted, res.InsertedCount, "Expected InsertedCount to match for BulkWrite on test '%s'", tn)
+		assert.Equalf(t, tt.matched, res.MatchedCount, "Expected MatchedCount to match for BulkWrite on test '%s'", tn)
+	}
+}
+
 var getIndexTests = map[string]struct {
 	uri         string
 	collection  string
@@ -362,12 +483,12 @@ func Test_GetIndex(t *testing.T) {
 			opt := options.Client()
 			opt.ApplyURI(tt.uri)
 			client, _ := mongo.NewClient(opt)
-			db = client.Database(testDB)
+			db = WrapDatabase(client.Database(testDB))
 		}
 		defer cleanTests(ctx, db)
 		// Create the helper
 		var h MongoHelper
-		h = &helper{db}
+		h = &helper{db: db}
 
 		res, err := h.GetIndex(ctx, tt.collection, tt.index)
 
@@ -416,12 +537,12 @@ func Test_HasIndex(t *testing.T) {
 			opt := options.Client()
 			opt.ApplyURI(tt.uri)
 			client, _ := mongo.NewClient(opt)
-			db = client.Database(testDB)
+			db = WrapDatabase(client.Database(testDB))
 		}
 		defer cleanTests(ctx, db)
 		// Create the helper
 		var h MongoHelper
-		h = &helper{db}
+		h = &helper{db: db}
 
 		res, err := h.HasIndex(ctx, tt.collection, tt.index)
 
@@ -465,12 +586,12 @@ func Test_AddIndexIfNotExists(t *testing.T) {
 			opt := options.Client()
 			opt.ApplyURI(tt.uri)
 			client, _ := mongo.NewClient(opt)
-			db = client.Database(testDB)
+			db = WrapDatabase(client.Database(testDB))
 		}
 		defer cleanTests(ctx, db)
 		// Create the helper
 		var h MongoHelper
-		h = &helper{db}
+		h = &helper{db: db}
 
 		if tt.existsAlready {
 			HasIndex, _ := h.HasIndex(ctx, "test", tt.index)
@@ -517,7 +638,7 @@ func Test_Aggregate(t *testing.T) {
 	defer cleanTests(ctx, db)
 	// Create the helper
 	var h MongoHelper
-	h = &helper{db}
+	h = &helper{db: db}
 	// Insert one row into test
 	h.InsertOne(ctx, "test", exampleStruct{sampleObjectIDObj, "test string", 999, exampleSubStruct{}})
 
@@ -535,7 +656,7 @@ func Test_Aggregate(t *testing.T) {
 }
 
 func Test_NewHelper(t *testing.T) {
-	db := &mongo.Database{}
+	db := WrapDatabase(&mongo.Database{})
 	h := NewHelper(db)
 
 	assert.NotNil(t, h)
@@ -549,7 +670,7 @@ func setupTests(ctx context.Context) (MongoDB, *mongo.Client) {
 	cop.ApplyURI(defaultURI)
 	client, _ := mongo.NewClient(cop)
 	var db MongoDB
-	db = client.Database(testDB)
+	db = WrapDatabase(client.Database(testDB))
 	client.Connect(ctx)
 	// Create an index
 	c := db.Collection("test")
@@ -0,0 +1,302 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/archy-bold/mongo-go-helper/pagination"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Operation describes a MongoHelper call for hooks
+type Operation struct {
+	// Name is the helper method, such as "Find"
+	Name       string
+	Collection string
+	// Filter is the query filter, or the pipeline for Aggregate
+	Filter interface{}
+	// Options are the FindOptions for Find, the keys for AddIndexIfNotExists,
+	// the CollectionValidator for SetValidator, the CollectionOptions for
+	// CreateCollection, or the new name for RenameCollection
+	Options interface{}
+	// Index is the index name for index operations
+	Index string
+	Start time.Time
+	// Duration and the fields below are set once the operation has finished
+	Duration time.Duration
+	// Returned is the number of documents returned by Find and Aggregate
+	Returned int64
+	Inserted int64
+	Matched  int64
+	Modified int64
+	Deleted  int64
+	Upserted int64
+	Err      error
+}
+
+// Hook is called around each MongoHelper operation. Before may return a new
+// context, which is used for the operation and passed to After.
+type Hook interface {
+	Before(ctx context.Context, op *Operation) context.Context
+	After(ctx context.Context, op *Operation)
+}
+
+// AfterHookFunc is an adapter to use a function called after each operation as a Hook
+type AfterHookFunc func(ctx context.Context, op *Operation)
+
+// Before does nothing
+func (f AfterHookFunc) Before(ctx context.Context, op *Operation) context.Context {
+	return ctx
+}
+
+// After calls f(ctx, op)
+func (f AfterHookFunc) After(ctx context.Context, op *Operation) {
+	f(ctx, op)
+}
+
+type hookedHelper struct {
+	helper MongoHelper
+	hooks  []Hook
+}
+
+// WithHooks wraps the helper so the hooks are called around each operation.
+// Before hooks are called in order and After hooks in reverse order.
+func WithHooks(h MongoHelper, hooks ...Hook) MongoHelper {
+	return &hookedHelper{h, hooks}
+}
+
+// before starts the operation, calling the Before hooks
+func (h *hookedHelper) before(ctx context.Context, op *Operation) context.Context {
+	op.Start = time.Now()
+	for _, hook := range h.hooks {
+		ctx = hook.Before(ctx, op)
+	}
+	return ctx
+}
+
+// after finishes the operation, calling the After hooks
+func (h *hookedHelper) after(ctx context.Context, op *Operation, err error) {
+	op.Duration = time.Since(op.Start)
+	op.Err = err
+	for i := len(h.hooks) - 1; i >= 0; i-- {
+		h.hooks[i].After(ctx, op)
+	}
+}
+
+func (h *hookedHelper) NewClient(uri string) (MongoClient, error) {
+	return h.helper.NewClient(uri)
+}
+
+func (h *hookedHelper) Find(ctx context.Context, coll string, filter interface{}, item interface{}, opts FindOptions) (pagination.Result, error) {
+	op := &Operation{Name: "Find", Collection: coll, Filter: filter, Options: opts}
+	ctx = h.before(ctx, op)
+	res, err := h.helper.Find(ctx, coll, filter, item, opts)
+	op.Returned = int64(len(res.Items))
+	h.after(ctx, op, err)
+	return res, err
+}
+
+func (h *hookedHelper) FindOne(ctx context.Context, coll string, filter interface{}, item interface{}) {
+	op := &Operation{Name: "FindOne", Collection: coll, Filter: filter}
+	ctx = h.before(ctx, op)
+	h.helper.FindOne(ctx, coll, filter, item)
+	h.after(ctx, op, nil)
+}
+
+func (h *hookedHelper) InsertOne(ctx context.Context, coll string, item interface{}) (interface{}, error) {
+	op := &Operation{Name: "InsertOne", Collection: coll}
+	ctx = h.before(ctx, op)
+	id, err := h.helper.InsertOne(ctx, coll, item)
+	if err == nil {
+		op.Inserted = 1
+	}
+	h.after(ctx, op, err)
+	return id, err
+}
+
+func (h *hookedHelper) GetIDFromInsertOneResult(res *mongo.InsertOneResult) (interface{}, error) {
+	return h.helper.GetIDFromInsertOneResult(res)
+}
+
+func (h *hookedHelper) UpdateOne(ctx context.Context, coll string, filter interface{}, item interface{}) error {
+	op := &Operation{Name: "UpdateOne", Collection: coll, Filter: filter}
+	ctx = h.before(ctx, op)
+	err := h.helper.UpdateOne(ctx, coll, filter, item)
+	if err == nil {
+		op.Matched = 1
+	}
+	h.after(ctx, op, err)
+	return err
+}
+
+func (h *hookedHelper) DeleteOne(ctx context.Context, coll string, filter interface{}, item interface{}) error {
+	op := &Operation{Name: "DeleteOne", Collection: coll, Filter: filter}
+	ctx = h.before(ctx, op)
+	err := h.helper.DeleteOne(ctx, coll, filter, item)
+	if err == nil {
+		op.Deleted = 1
+	}
+	h.after(ctx, op, err)
+	return err
+}
+
+func (h *hookedHelper) BulkWrite(ctx context.Context, coll string, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	op := &Operation{Name: "BulkWrite", Collection: coll}
+	ctx = h.before(ctx, op)
+	res, err := h.helper.BulkWrite(ctx, coll, models, opts...)
+	if res != nil {
+		op.Inserted = res.InsertedCount
+		op.Matched = res.MatchedCount
+		op.Modified = res.ModifiedCount
+		op.Deleted = res.DeletedCount
+		op.Upserted = res.UpsertedCount
+	}
+	h.after(ctx, op, err)
+	return res, err
+}
+
+func (h *hookedHelper) GetIndex(ctx context.Context, coll string, index string) (*bson.M, error) {
+	op := &Operation{Name: "GetIndex", Collection: coll, Index: index}
+	ctx = h.before(ctx, op)
+	res, err := h.helper.GetIndex(ctx, coll, index)
+	h.after(ctx, op, err)
+	return res, err
+}
+
+func (h *hookedHelper) HasIndex(ctx context.Context, coll string, index string) (bool, error) {
+	op := &Operation{Name: "HasIndex", Collection: coll, Index: index}
+	ctx = h.before(ctx, op)
+	res, err := h.helper.HasIndex(ctx, coll, index)
+	h.after(ctx, op, err)
+	return res, err
+}
+
+func (h *hookedHelper) AddIndexIfNotExists(ctx context.Context, coll string, name string, keys interface{}) error {
+	op := &Operation{Name: "AddIndexIfNotExists", Collection: coll, Options: keys, Index: name}
+	ctx = h.before(ctx, op)
+	err := h.helper.AddIndexIfNotExists(ctx, coll, name, keys)
+	h.after(ctx, op, err)
+	return err
+}
+
+func (h *hookedHelper) Aggregate(ctx context.Context, coll string, pipeline mongo.Pipeline) ([]bson.M, error) {
+	op := &Operation{Name: "Aggregate", Collection: coll, Filter: pipeline}
+	ctx = h.before(ctx, op)
+	res, err := h.helper.Aggregate(ctx, coll, pipeline)
+	op.Returned = int64(len(res))
+	h.after(ctx, op, err)
+	return res, err
+}
+
+func (h *hookedHelper) GetValidator(ctx context.Context, coll string) (*CollectionValidator, error) {
+	op := &Operation{Name: "GetValidator", Collection: coll}
+	ctx = h.before(ctx, op)
+	res, err := h.helper.GetValidator(ctx, coll)
+	h.after(ctx, op, err)
+	return res, err
+}
+
+func (h *hookedHelper) SetValidator(ctx context.Context, coll string, v CollectionValidator) error {
+	op := &Operation{Name: "SetValidator", Collection: coll, Options: v}
+	ctx = h.before(ctx, op)
+	err := h.helper.SetValidator(ctx, coll, v)
+	h.after(ctx, op, err)
+	return err
+}
+
+func (h *hookedHelper) GetCollection(ctx context.Context, name string) (*CollectionInfo, error) {
+	op := &Operation{Name: "GetCollection", Collection: name}
+	ctx = h.before(ctx, op)
+	res, err := h.helper.GetCollection(ctx, name)
+	h.after(ctx, op, err)
+	return res, err
+}
+
+func (h *hookedHelper) CreateCollection(ctx context.Context, name string, opts CollectionOptions) error {
+	op := &Operation{Name: "CreateCollection", Collection: name, Options: opts}
+	ctx = h.before(ctx, op)
+	err := h.helper.CreateCollection(ctx, name, opts)
+	h.after(ctx, op, err)
+	return err
+}
+
+func (h *hookedHelper) RenameCollection(ctx context.Context, from string, to string, dropTarget bool) error {
+	op := &Operation{Name: "RenameCollection", Collection: from, Options: to}
+	ctx = h.before(ctx, op)
+	err := h.helper.RenameCollection(ctx, from, to, dropTarget)
+	h.after(ctx, op, err)
+	return err
+}
+
+func (h *hookedHelper) DropCollection(ctx context.Context, name string) error {
+	op := &Operation{Name: "DropCollection", Collection: name}
+	ctx = h.before(ctx, op)
+	err := h.helper.DropCollection(ctx, name)
+	h.after(ctx, op, err)
+	return err
+}
+
+// KeepFilter gets the filter unchanged, to log filters without redacting them
+func KeepFilter(filter interface{}) interface{} {
+	return filter
+}
+
+// RedactFilter gets a copy of the filter or pipeline with every value
+// replaced by "?", keeping field names, operators and field references so
+// it can be logged without the data it was querying for
+func RedactFilter(filter interface{}) interface{} {
+	if filter == nil {
+		return nil
+	}
+
+	// Round trip through BSON to handle any document type
+	b, err := bson.Marshal(bson.D{{Key: "v", Value: filter}})
+	if err != nil {
+		return "?"
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(b, &doc); err != nil {
+		return "?"
+	}
+
+	return redactValue(doc[0].Value)
+}
+
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case bson.D:
+		d := make(bson.D, len(v))
+		for i, e := range v {
+			d[i] = bson.E{Key: e.Key, Value: redactValue(e.Value)}
+		}
+		return d
+	case bson.A:
+		a := make(bson.A, len(v))
+		for i, e := range v {
+			a[i] = redactValue(e)
+		}
+		return a
+	case string:
+		if strings.HasPrefix(v, "$") {
+			return v
+		}
+	case nil:
+		return nil
+	}
+	return "?"
+}
+
+// FormatValue gets the value, such as a redacted filter, as relaxed Extended
+// JSON for logs and traces
+func FormatValue(v interface{}) string {
+	b, err := bson.MarshalExtJSON(bson.D{{Key: "v", Value: v}}, false, false)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	// Strip the wrapping document
+	return string(b[len(`{"v":`) : len(b)-1])
+}
@@ -0,0 +1,126 @@
+package base_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ctxKey string
+
+// recordingHook records the operations and the order hooks are called in
+type recordingHook struct {
+	name  string
+	calls *[]string
+	ops   []base.Operation
+}
+
+func (h *recordingHook) Before(ctx context.Context, op *base.Operation) context.Context {
+	*h.calls = append(*h.calls, "before "+h.name)
+	return context.WithValue(ctx, ctxKey(h.name), true)
+}
+
+func (h *recordingHook) After(ctx context.Context, op *base.Operation) {
+	*h.calls = append(*h.calls, "after "+h.name)
+	if ctx.Value(ctxKey(h.name)) == nil {
+		panic("expected the context from Before")
+	}
+	h.ops = append(h.ops, *op)
+}
+
+func Test_WithHooks(t *testing.T) {
+	ctx := context.Background()
+	_, h := setupMemDB(t, ctx)
+	var calls []string
+	first := &recordingHook{name: "first", calls: &calls}
+	second := &recordingHook{name: "second", calls: &calls}
+	h = base.WithHooks(h, first, second)
+
+	h.Find(ctx, "test", bson.M{"num": bson.M{"$gt": 2}}, exampleModel{}, base.FindOptions{PageSize: 2})
+	h.FindOne(ctx, "test", bson.M{"str": "a"}, &exampleModel{})
+	h.InsertOne(ctx, "test", &exampleModel{Str: "f", Num: 6})
+	h.UpdateOne(ctx, "test", bson.M{"str": "z"}, &exampleModel{})
+	h.BulkWrite(ctx, "test", []mongo.WriteModel{
+		mongo.NewUpdateManyModel().SetFilter(bson.M{"num": bson.M{"$lte": 2}}).SetUpdate(bson.M{"$set": bson.M{"str": "low"}}),
+		mongo.NewDeleteOneModel().SetFilter(bson.M{"str": "c"}),
+	})
+	h.AddIndexIfNotExists(ctx, "test", "str_index", bson.M{"str": 1})
+	h.Aggregate(ctx, "test", mongo.Pipeline{{{Key: "$match", Value: bson.M{}}}})
+	h.DeleteOne(ctx, "test", bson.M{"str": "f"}, nil)
+
+	assert.Equal(t, []string{"before first", "before second", "after second", "after first"}, calls[:4], "Expected hooks to be called like middleware")
+	assert.Equal(t, first.ops, second.ops, "Expected each hook to see the same operations")
+
+	ops := first.ops
+	if !assert.Len(t, ops, 8) {
+		return
+	}
+	assert.Equal(t, "Find", ops[0].Name)
+	assert.Equal(t, "test", ops[0].Collection)
+	assert.Equal(t, bson.M{"num": bson.M{"$gt": 2}}, ops[0].Filter)
+	assert.Equal(t, base.FindOptions{PageSize: 2}, ops[0].Options)
+	assert.Equal(t, int64(2), ops[0].Returned)
+	assert.False(t, ops[0].Start.IsZero(), "Expected the start to be set")
+	assert.Equal(t, "FindOne", ops[1].Name)
+	assert.Equal(t, int64(1), ops[2].Inserted)
+	assert.Equal(t, base.ErrNoMatches, ops[3].Err)
+	assert.Equal(t, int64(0), ops[3].Matched)
+	assert.Equal(t, int64(2), ops[4].Matched)
+	assert.Equal(t, int64(2), ops[4].Modified)
+	assert.Equal(t, int64(1), ops[4].Deleted)
+	assert.Equal(t, "str_index", ops[5].Index)
+	assert.Equal(t, bson.M{"str": 1}, ops[5].Options)
+	assert.Equal(t, int64(5), ops[6].Returned)
+	assert.Equal(t, "DeleteOne", ops[7].Name)
+	assert.Equal(t, int64(1), ops[7].Deleted)
+}
+
+var redactFilterTests = map[string]struct {
+	filter   interface{}
+	expected interface{}
+}{
+	"nil":      {nil, nil},
+	"equality": {bson.M{"email": "a@b.com"}, bson.D{{Key: "email", Value: "?"}}},
+	"operators": {
+		bson.D{{Key: "age", Value: bson.M{"$gte": 18}}, {Key: "tags", Value: bson.M{"$in": bson.A{"a", "b"}}}},
+		bson.D{{Key: "age", Value: bson.D{{Key: "$gte", Value: "?"}}}, {Key: "tags", Value: bson.D{{Key: "$in", Value: bson.A{"?", "?"}}}}},
+	},
+	"null":    {bson.M{"deleted": nil}, bson.D{{Key: "deleted", Value: nil}}},
+	"pointer": {&bson.M{"num": 1}, bson.D{{Key: "num", Value: "?"}}},
+	"pipeline": {
+		mongo.Pipeline{{{Key: "$match", Value: bson.M{"str": "a"}}}, {{Key: "$group", Value: bson.M{"_id": "$str"}}}},
+		bson.A{bson.D{{Key: "$match", Value: bson.D{{Key: "str", Value: "?"}}}}, bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$str"}}}}},
+	},
+	"struct":         {struct{ Name string }{"secret"}, bson.D{{Key: "name", Value: "?"}}},
+	"not a document": {make(chan int), "?"},
+}
+
+func Test_RedactFilter(t *testing.T) {
+	for tn, tt := range redactFilterTests {
+		res := base.RedactFilter(tt.filter)
+
+		assert.Equalf(t, tt.expected, res, "Expected redacted filter to match on test '%s'", tn)
+	}
+}
+
+var formatValueTests = map[string]struct {
+	value    interface{}
+	expected string
+}{
+	"nil":      {nil, "null"},
+	"string":   {"a", `"a"`},
+	"document": {bson.D{{Key: "num", Value: bson.M{"$gt": 1}}}, `{"num":{"$gt":1}}`},
+	"pipeline": {bson.A{bson.D{{Key: "$match", Value: bson.D{}}}}, `[{"$match":{}}]`},
+}
+
+func Test_FormatValue(t *testing.T) {
+	for tn, tt := range formatValueTests {
+		res := base.FormatValue(tt.value)
+
+		assert.Equalf(t, tt.expected, res, "Expected formatted value to match on test '%s'", tn)
+	}
+}
@@ -0,0 +1,125 @@
+package base
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// crockford is the base32 alphabet used by ULIDs
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// IDGenerator generates the IDs of new documents in a collection
+type IDGenerator interface {
+	NewID(ctx context.Context, coll string) (interface{}, error)
+}
+
+// IDGeneratorFunc is an adapter to use a function as an IDGenerator
+type IDGeneratorFunc func(ctx context.Context, coll string) (interface{}, error)
+
+// NewID calls the function
+func (f IDGeneratorFunc) NewID(ctx context.Context, coll string) (interface{}, error) {
+	return f(ctx, coll)
+}
+
+// Counter allocates sequential values for named sequences
+type Counter interface {
+	Next(ctx context.Context, name string) (int64, error)
+}
+
+var (
+	// ObjectIDGenerator generates ObjectIDs
+	ObjectIDGenerator IDGenerator = IDGeneratorFunc(func(ctx context.Context, coll string) (interface{}, error) {
+		return primitive.NewObjectID(), nil
+	})
+
+	// UUIDv4Generator generates random UUIDs as strings
+	UUIDv4Generator IDGenerator = IDGeneratorFunc(func(ctx context.Context, coll string) (interface{}, error) {
+		return NewUUIDv4()
+	})
+
+	// UUIDv7Generator generates time-ordered UUIDs as strings
+	UUIDv7Generator IDGenerator = IDGeneratorFunc(func(ctx context.Context, coll string) (interface{}, error) {
+		return NewUUIDv7()
+	})
+
+	// ULIDGenerator generates ULIDs as strings
+	ULIDGenerator IDGenerator = IDGeneratorFunc(func(ctx context.Context, coll string) (interface{}, error) {
+		return NewULID()
+	})
+)
+
+// NewCounterIDGenerator gets a generator allocating int64 IDs from a sequence named after the collection
+func NewCounterIDGenerator(counter Counter) IDGenerator {
+	return IDGeneratorFunc(func(ctx context.Context, coll string) (interface{}, error) {
+		return counter.Next(ctx, coll)
+	})
+}
+
+// NewUUIDv4 gets a random (version 4) UUID
+func NewUUIDv4() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", err
+	}
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return formatUUID(u), nil
+}
+
+// NewUUIDv7 gets a time-ordered (version 7) UUID
+func NewUUIDv7() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[6:]); err != nil {
+		return "", err
+	}
+	putMillis(u[:6], time.Now())
+	u[6] = (u[6] & 0x0f) | 0x70
+	u[8] = (u[8] & 0x3f) | 0x80
+	return formatUUID(u), nil
+}
+
+// NewULID gets a lexicographically sortable ULID
+func NewULID() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[6:]); err != nil {
+		return "", err
+	}
+	putMillis(u[:6], time.Now())
+
+	// Encode the 128 bits as 26 base32 characters, 5 bits at a time from the end
+	hi := binary.BigEndian.Uint64(u[:8])
+	lo := binary.BigEndian.Uint64(u[8:])
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out), nil
+}
+
+// putMillis writes the time as big-endian unix milliseconds into 6 bytes
+func putMillis(b []byte, t time.Time) {
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(t.UnixNano()/int64(time.Millisecond)))
+	copy(b, ms[2:])
+}
+
+func formatUUID(u [16]byte) string {
+	buf := make([]byte, 36)
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf)
+}
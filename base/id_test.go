@@ -0,0 +1,75 @@
+package base
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type exampleCounter struct {
+	values map[string]int64
+}
+
+func (c *exampleCounter) Next(ctx context.Context, name string) (int64, error) {
+	c.values[name]++
+	return c.values[name], nil
+}
+
+var idGeneratorTests = map[string]struct {
+	gen     IDGenerator
+	pattern *regexp.Regexp
+}{
+	"uuid v4": {UUIDv4Generator, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)},
+	"uuid v7": {UUIDv7Generator, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)},
+	"ulid":    {ULIDGenerator, regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)},
+}
+
+func Test_IDGenerators(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range idGeneratorTests {
+		a, errA := tt.gen.NewID(ctx, "test")
+		b, errB := tt.gen.NewID(ctx, "test")
+
+		assert.Nilf(t, errA, "Expected nil err for NewID on test '%s'", tn)
+		assert.Nilf(t, errB, "Expected nil err for NewID on test '%s'", tn)
+		assert.Regexpf(t, tt.pattern, a, "Expected ID to match the format on test '%s'", tn)
+		assert.NotEqualf(t, a, b, "Expected IDs to be unique on test '%s'", tn)
+	}
+}
+
+func Test_IDGenerators_TimeOrdered(t *testing.T) {
+	u1, _ := NewUUIDv7()
+	l1, _ := NewULID()
+	time.Sleep(2 * time.Millisecond)
+	u2, _ := NewUUIDv7()
+	l2, _ := NewULID()
+
+	assert.True(t, u1 < u2, "Expected UUIDv7s to sort by time")
+	assert.True(t, l1 < l2, "Expected ULIDs to sort by time")
+}
+
+func Test_ObjectIDGenerator(t *testing.T) {
+	id, err := ObjectIDGenerator.NewID(context.Background(), "test")
+
+	assert.Nil(t, err)
+	assert.IsType(t, primitive.ObjectID{}, id)
+	assert.False(t, id.(primitive.ObjectID).IsZero())
+}
+
+func Test_CounterIDGenerator(t *testing.T) {
+	ctx := context.Background()
+	gen := NewCounterIDGenerator(&exampleCounter{map[string]int64{}})
+
+	a, _ := gen.NewID(ctx, "orders")
+	b, _ := gen.NewID(ctx, "orders")
+	c, _ := gen.NewID(ctx, "invoices")
+
+	assert.Equal(t, int64(1), a)
+	assert.Equal(t, int64(2), b)
+	assert.Equal(t, int64(1), c, "Expected each collection to have its own sequence")
+}
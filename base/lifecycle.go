@@ -0,0 +1,143 @@
+package base
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Models may implement the lifecycle interfaces below to have the helper
+// call them around their operations. An error from a Before hook or Validate
+// aborts the operation and is returned. An error from an After hook is
+// returned once the operation has run.
+
+// BeforeInserter is called by InsertOne and bulk inserts before writing
+type BeforeInserter interface {
+	BeforeInsert(ctx context.Context) error
+}
+
+// AfterInserter is called by InsertOne after writing
+type AfterInserter interface {
+	AfterInsert(ctx context.Context) error
+}
+
+// BeforeUpdater is called by UpdateOne and bulk replacements before writing
+type BeforeUpdater interface {
+	BeforeUpdate(ctx context.Context) error
+}
+
+// AfterFinder is called on each model read by Find and FindOne. FindOne
+// has no error to return, so it empties the item as if nothing was found.
+type AfterFinder interface {
+	AfterFind(ctx context.Context) error
+}
+
+// BeforeDeleter is called by DeleteOne before deleting
+type BeforeDeleter interface {
+	BeforeDelete(ctx context.Context) error
+}
+
+// Validator is called before the model is inserted or replaced, after its
+// Before hook and the rules in its validate tags. Its error is an ErrValidation.
+type Validator interface {
+	Validate() error
+}
+
+// beforeInsert calls the item's BeforeInsert hook
+func beforeInsert(ctx context.Context, item interface{}) error {
+	if m, ok := item.(BeforeInserter); ok {
+		return m.BeforeInsert(ctx)
+	}
+	return nil
+}
+
+// beforeUpdate calls the item's BeforeUpdate hook
+func beforeUpdate(ctx context.Context, item interface{}) error {
+	if m, ok := item.(BeforeUpdater); ok {
+		return m.BeforeUpdate(ctx)
+	}
+	return nil
+}
+
+// afterFind calls the item's AfterFind hook
+func afterFind(ctx context.Context, item interface{}) error {
+	if m, ok := item.(AfterFinder); ok {
+		return m.AfterFind(ctx)
+	}
+	return nil
+}
+
+// validate validates the item with its validate tags then its Validate
+// method, marking errors as validation errors
+func validate(item interface{}) error {
+	if err := ValidateStruct(item); err != nil {
+		return err
+	}
+
+	m, ok := item.(Validator)
+	if !ok {
+		return nil
+	}
+	if err := m.Validate(); err != nil {
+		if errors.Is(err, ErrValidation) {
+			return err
+		}
+		return &kindError{ErrValidation, err}
+	}
+	return nil
+}
+
+// prepareInsert calls the hooks and sets the fields of an item before it's inserted
+func (h *helper) prepareInsert(ctx context.Context, item interface{}) error {
+	if err := beforeInsert(ctx, item); err != nil {
+		return err
+	}
+	if m, ok := item.(VersionedModel); ok && m.GetVersion() == 0 {
+		m.SetVersion(1)
+	}
+	h.stamp(ctx, item, true)
+	return validate(item)
+}
+
+// prepareReplace calls the hooks and sets the fields of an item before it
+// replaces the document matching the filter, which may be inserted if upserting
+func (h *helper) prepareReplace(ctx context.Context, coll string, filter interface{}, item interface{}, upsert bool) error {
+	if err := beforeUpdate(ctx, item); err != nil {
+		return err
+	}
+	if err := h.keepCreated(ctx, coll, filter, item); err != nil {
+		return err
+	}
+	h.stamp(ctx, item, upsert)
+	return validate(item)
+}
+
+// prepareWrites prepares the documents inserted or replaced by the write
+// models. Replacements of versioned models are copied to only match the
+// model's version, so the models returned are the ones to write.
+func (h *helper) prepareWrites(ctx context.Context, coll string, models []mongo.WriteModel) ([]mongo.WriteModel, []versionedWrite, error) {
+	prepared := make([]mongo.WriteModel, len(models))
+	var versioned []versionedWrite
+	for i, model := range models {
+		prepared[i] = model
+		var err error
+		switch m := model.(type) {
+		case *mongo.InsertOneModel:
+			err = h.prepareInsert(ctx, m.Document)
+		case *mongo.ReplaceOneModel:
+			err = h.prepareReplace(ctx, coll, m.Filter, m.Replacement, m.Upsert != nil && *m.Upsert)
+			if vm, ok := m.Replacement.(VersionedModel); ok && err == nil {
+				prepared[i] = h.versionReplace(m, vm)
+				versioned = append(versioned, versionedWrite{i, m.Filter, vm})
+			}
+		}
+		if err != nil {
+			for _, w := range versioned {
+				w.model.SetVersion(w.model.GetVersion() - 1)
+			}
+			return nil, nil, err
+		}
+	}
+	return prepared, versioned, nil
+}
@@ -0,0 +1,180 @@
+package base_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/mongotest"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var errHook = errors.New("hook error")
+
+// hookedModel records its lifecycle hooks and fails the ones named in failOn
+type hookedModel struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty"`
+	Email  string             `bson:"email"`
+	calls  []string
+	failOn string
+}
+
+func (m *hookedModel) hook(name string) error {
+	m.calls = append(m.calls, name)
+	if m.failOn == name {
+		return errHook
+	}
+	return nil
+}
+
+func (m *hookedModel) BeforeInsert(ctx context.Context) error {
+	m.Email = strings.ToLower(m.Email)
+	return m.hook("BeforeInsert")
+}
+
+func (m *hookedModel) AfterInsert(ctx context.Context) error { return m.hook("AfterInsert") }
+
+func (m *hookedModel) BeforeUpdate(ctx context.Context) error {
+	m.Email = strings.ToLower(m.Email)
+	return m.hook("BeforeUpdate")
+}
+
+func (m *hookedModel) AfterFind(ctx context.Context) error    { return m.hook("AfterFind") }
+func (m *hookedModel) BeforeDelete(ctx context.Context) error { return m.hook("BeforeDelete") }
+
+func (m *hookedModel) Validate() error {
+	if err := m.hook("Validate"); err != nil {
+		return err
+	}
+	if !strings.Contains(m.Email, "@") {
+		return errors.New("invalid email")
+	}
+	return nil
+}
+
+// failingFindModel fails its AfterFind hook
+type failingFindModel struct {
+	Email string `bson:"email"`
+}
+
+func (m *failingFindModel) AfterFind(ctx context.Context) error { return errHook }
+
+var insertHooksTests = map[string]struct {
+	item     *hookedModel
+	calls    []string
+	inserted bool
+	err      error
+}{
+	"success":        {&hookedModel{Email: "A@example.com"}, []string{"BeforeInsert", "Validate", "AfterInsert"}, true, nil},
+	"before insert":  {&hookedModel{Email: "a@example.com", failOn: "BeforeInsert"}, []string{"BeforeInsert"}, false, errHook},
+	"invalid":        {&hookedModel{Email: "a"}, []string{"BeforeInsert", "Validate"}, false, base.ErrValidation},
+	"after insert":   {&hookedModel{Email: "a@example.com", failOn: "AfterInsert"}, []string{"BeforeInsert", "Validate", "AfterInsert"}, true, errHook},
+	"validate error": {&hookedModel{Email: "a@example.com", failOn: "Validate"}, []string{"BeforeInsert", "Validate"}, false, base.ErrValidation},
+}
+
+func Test_InsertOne_Hooks(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range insertHooksTests {
+		db := mongotest.New(t)
+
+		_, err := db.Helper.InsertOne(ctx, "test", tt.item)
+
+		assert.Equalf(t, tt.calls, tt.item.calls, "Expected the hooks to match on test '%s'", tn)
+		if tt.err == nil {
+			assert.Nilf(t, err, "Expected nil err on test '%s'", tn)
+		} else {
+			assert.Truef(t, errors.Is(err, tt.err), "Expected err '%v' to be '%v' on test '%s'", err, tt.err, tn)
+		}
+		count, _ := db.Collection("test").CountDocuments(ctx, bson.M{"email": "a@example.com"})
+		assert.Equalf(t, tt.inserted, count == 1, "Expected the normalised item to be inserted on test '%s'", tn)
+	}
+}
+
+func Test_UpdateOne_Hooks(t *testing.T) {
+	ctx := context.Background()
+	db := mongotest.New(t)
+	db.Collection("test").InsertOne(ctx, bson.M{"_id": 1, "email": "a@example.com"})
+
+	item := &hookedModel{Email: "B@example.com"}
+	err := db.Helper.UpdateOne(ctx, "test", bson.M{"_id": 1}, item)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"BeforeUpdate", "Validate"}, item.calls)
+	mongotest.AssertDocumentExists(t, db, "test", bson.M{"_id": 1, "email": "b@example.com"})
+
+	err = db.Helper.UpdateOne(ctx, "test", bson.M{"_id": 1}, &hookedModel{Email: "c@example.com", failOn: "BeforeUpdate"})
+	assert.Equal(t, errHook, err)
+	err = db.Helper.UpdateOne(ctx, "test", bson.M{"_id": 1}, &hookedModel{Email: "c"})
+	assert.True(t, errors.Is(err, base.ErrValidation), "Expected a validation error")
+	assert.EqualError(t, err, "invalid email")
+	mongotest.AssertDocumentExists(t, db, "test", bson.M{"_id": 1, "email": "b@example.com"}, "Expected failed hooks to abort the update")
+}
+
+func Test_Find_Hooks(t *testing.T) {
+	ctx := context.Background()
+	db := mongotest.New(t)
+	db.Collection("test").InsertOne(ctx, bson.M{"email": "a@example.com"})
+	db.Collection("test").InsertOne(ctx, bson.M{"email": "b@example.com"})
+
+	res, err := db.Helper.Find(ctx, "test", bson.M{}, hookedModel{}, base.FindOptions{})
+	assert.Nil(t, err)
+	if assert.Len(t, res.Items, 2) {
+		for _, item := range res.Items {
+			assert.Equal(t, []string{"AfterFind"}, item.(*hookedModel).calls)
+		}
+	}
+
+	res, err = db.Helper.Find(ctx, "test", bson.M{}, failingFindModel{}, base.FindOptions{})
+	assert.Equal(t, errHook, err)
+	assert.Nil(t, res.Items, "Expected no items when a hook fails")
+
+	var item hookedModel
+	db.Helper.FindOne(ctx, "test", bson.M{"email": "a@example.com"}, &item)
+	assert.Equal(t, []string{"AfterFind"}, item.calls)
+	item = hookedModel{}
+	db.Helper.FindOne(ctx, "test", bson.M{"email": "c@example.com"}, &item)
+	assert.Empty(t, item.calls, "Expected no hook when nothing is found")
+
+	item = hookedModel{failOn: "AfterFind"}
+	db.Helper.FindOne(ctx, "test", bson.M{"email": "a@example.com"}, &item)
+	assert.Equal(t, hookedModel{}, item, "Expected the item to be emptied when a hook fails")
+}
+
+func Test_DeleteOne(t *testing.T) {
+	ctx := context.Background()
+	db := mongotest.New(t)
+	db.Collection("test").InsertOne(ctx, bson.M{"_id": 1, "email": "a@example.com"})
+
+	err := db.Helper.DeleteOne(ctx, "test", bson.M{"_id": 1}, &hookedModel{failOn: "BeforeDelete"})
+	assert.Equal(t, errHook, err)
+	mongotest.AssertDocumentExists(t, db, "test", bson.M{"_id": 1}, "Expected the hook error to abort the delete")
+
+	item := &hookedModel{}
+	err = db.Helper.DeleteOne(ctx, "test", bson.M{"_id": 1}, item)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"BeforeDelete"}, item.calls)
+	mongotest.AssertNoDocument(t, db, "test", bson.M{"_id": 1})
+
+	err = db.Helper.DeleteOne(ctx, "test", bson.M{"_id": 1}, nil)
+	assert.Equal(t, base.ErrNoMatches, err)
+}
+
+func Test_BulkWrite_Hooks(t *testing.T) {
+	ctx := context.Background()
+	db := mongotest.New(t)
+
+	valid := &hookedModel{Email: "A@example.com"}
+	_, err := db.Helper.BulkWrite(ctx, "test", []mongo.WriteModel{
+		mongo.NewInsertOneModel().SetDocument(valid),
+		mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": 1}).SetReplacement(&hookedModel{Email: "b"}),
+	})
+
+	assert.True(t, errors.Is(err, base.ErrValidation), "Expected a validation error")
+	assert.Equal(t, []string{"BeforeInsert", "Validate"}, valid.calls)
+	mongotest.AssertCount(t, db, "test", bson.M{}, 0, "Expected nothing to be written")
+}
@@ -3,19 +3,72 @@ package base
 import (
 	"context"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 // MongoClient encapsulates the MongoDB Client struct
 type MongoClient interface {
 	Connect(context.Context) error
-	Database(string, ...*options.DatabaseOptions) *mongo.Database
+	Database(string, ...*options.DatabaseOptions) MongoDB
+	Disconnect(context.Context) error
+	ListDatabaseNames(context.Context, interface{}, ...*options.ListDatabasesOptions) ([]string, error)
+	Ping(context.Context, *readpref.ReadPref) error
 }
 
 // MongoDB encapsulates the MongoDB DB struct
 type MongoDB interface {
-	Collection(string, ...*options.CollectionOptions) *mongo.Collection
+	Name() string
+	Client() MongoClient
+	Collection(string, ...*options.CollectionOptions) MongoCollection
+	RunCommand(context.Context, interface{}, ...*options.RunCmdOptions) SingleResult
+	ListCollectionNames(context.Context, interface{}, ...*options.ListCollectionsOptions) ([]string, error)
+	Drop(context.Context) error
+}
+
+// MongoCollection encapsulates the MongoDB Collection struct
+type MongoCollection interface {
+	Name() string
+	Aggregate(context.Context, interface{}, ...*options.AggregateOptions) (MongoCursor, error)
+	BulkWrite(context.Context, []mongo.WriteModel, ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error)
+	CountDocuments(context.Context, interface{}, ...*options.CountOptions) (int64, error)
+	DeleteMany(context.Context, interface{}, ...*options.DeleteOptions) (*mongo.DeleteResult, error)
+	DeleteOne(context.Context, interface{}, ...*options.DeleteOptions) (*mongo.DeleteResult, error)
+	Drop(context.Context) error
+	Find(context.Context, interface{}, ...*options.FindOptions) (MongoCursor, error)
+	FindOne(context.Context, interface{}, ...*options.FindOneOptions) SingleResult
+	FindOneAndUpdate(context.Context, interface{}, interface{}, ...*options.FindOneAndUpdateOptions) SingleResult
+	Indexes() MongoIndexView
+	InsertMany(context.Context, []interface{}, ...*options.InsertManyOptions) (*mongo.InsertManyResult, error)
+	InsertOne(context.Context, interface{}, ...*options.InsertOneOptions) (*mongo.InsertOneResult, error)
+	ReplaceOne(context.Context, interface{}, interface{}, ...*options.ReplaceOptions) (*mongo.UpdateResult, error)
+	UpdateMany(context.Context, interface{}, interface{}, ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	UpdateOne(context.Context, interface{}, interface{}, ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+}
+
+// MongoCursor encapsulates the MongoDB Cursor struct
+type MongoCursor interface {
+	Next(context.Context) bool
+	Decode(interface{}) error
+	All(context.Context, interface{}) error
+	Err() error
+	Close(context.Context) error
+}
+
+// MongoIndexView encapsulates the MongoDB IndexView struct
+type MongoIndexView interface {
+	List(context.Context, ...*options.ListIndexesOptions) (MongoCursor, error)
+	CreateOne(context.Context, mongo.IndexModel, ...*options.CreateIndexesOptions) (string, error)
+	DropOne(context.Context, string, ...*options.DropIndexesOptions) (bson.Raw, error)
+	DropAll(context.Context, ...*options.DropIndexesOptions) (bson.Raw, error)
+}
+
+// SingleResult encapsulates the MongoDB SingleResult struct
+type SingleResult interface {
+	Decode(interface{}) error
+	Err() error
 }
 
 // ModelInterface defines common mongo models
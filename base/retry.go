@@ -0,0 +1,376 @@
+package base
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/archy-bold/mongo-go-helper/pagination"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+// RetryPolicy configures how WithRetry retries failed operations
+type RetryPolicy struct {
+	// MaxAttempts is the number of times an operation is tried. Defaults to 3.
+	MaxAttempts int
+	// InitialBackoff is the wait after the first failed attempt, doubling
+	// after each attempt up to MaxBackoff. Defaults to 50ms and 2s.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Jitter is the fraction of each wait that's randomised, from 0 to 1, so
+	// clients don't retry in step. Defaults to 0.5, set it below 0 for none.
+	Jitter float64
+	// Retryable decides whether an error is transient. Defaults to IsRetryableError.
+	Retryable func(err error) bool
+	// RetryNonIdempotent retries writes that may apply twice if the first
+	// attempt succeeded without its response arriving, such as InsertOne and
+	// BulkWrite with inserts or $inc updates, or that would conflict with
+	// themselves, such as updates of versioned models
+	RetryNonIdempotent bool
+}
+
+// retryableCodes are the server error codes of transient errors, such as
+// elections, shutdowns and network timeouts
+var retryableCodes = map[int32]bool{
+	6:     true, // HostUnreachable
+	7:     true, // HostNotFound
+	89:    true, // NetworkTimeout
+	91:    true, // ShutdownInProgress
+	189:   true, // PrimarySteppedDown
+	262:   true, // ExceededTimeLimit
+	9001:  true, // SocketException
+	10107: true, // NotMaster
+	11600: true, // InterruptedAtShutdown
+	11602: true, // InterruptedDueToReplStateChange
+	13435: true, // NotMasterNoSlaveOk
+	13436: true, // NotMasterOrSecondary
+}
+
+// retryableLabels are the error labels the server and driver add to transient errors
+var retryableLabels = []string{"NetworkError", "RetryableWriteError", "TransientTransactionError"}
+
+// IsRetryableError gets whether the error is transient, such as a network
+// error or a primary stepping down, so the operation may succeed if retried
+func IsRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var ce mongo.CommandError
+	if errors.As(err, &ce) {
+		for _, label := range retryableLabels {
+			if ce.HasErrorLabel(label) {
+				return true
+			}
+		}
+		return retryableCodes[ce.Code]
+	}
+	var we mongo.WriteException
+	if errors.As(err, &we) {
+		return hasRetryableWriteCode(we.WriteConcernError, we.WriteErrors)
+	}
+	var bwe mongo.BulkWriteException
+	if errors.As(err, &bwe) {
+		writeErrors := make([]mongo.WriteError, len(bwe.WriteErrors))
+		for i, e := range bwe.WriteErrors {
+			writeErrors[i] = e.WriteError
+		}
+		return hasRetryableWriteCode(bwe.WriteConcernError, writeErrors)
+	}
+
+	var connErr topology.ConnectionError
+	if errors.As(err, &connErr) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout()
+	}
+	cause := errors.Cause(err)
+	return cause == io.EOF || cause == io.ErrUnexpectedEOF
+}
+
+func hasRetryableWriteCode(wce *mongo.WriteConcernError, writeErrors []mongo.WriteError) bool {
+	if wce != nil && retryableCodes[int32(wce.Code)] {
+		return true
+	}
+	for _, e := range writeErrors {
+		if retryableCodes[int32(e.Code)] {
+			return true
+		}
+	}
+	return false
+}
+
+// idempotentUpdateOperators are the update operators that give the same
+// result when applied twice
+var idempotentUpdateOperators = map[string]bool{
+	"$set":         true,
+	"$unset":       true,
+	"$setOnInsert": true,
+	"$rename":      true,
+	"$min":         true,
+	"$max":         true,
+	"$addToSet":    true,
+	"$pull":        true,
+	"$pullAll":     true,
+}
+
+// isIdempotentWrite gets whether applying the models twice has the same
+// result as applying them once
+func isIdempotentWrite(models []mongo.WriteModel) bool {
+	for _, model := range models {
+		switch m := model.(type) {
+		case *mongo.ReplaceOneModel:
+			// Versioned replacements conflict with themselves when applied twice
+			if _, ok := m.Replacement.(VersionedModel); ok {
+				return false
+			}
+		case *mongo.DeleteManyModel:
+		case *mongo.UpdateOneModel:
+			if !isIdempotentUpdate(m.Update) {
+				return false
+			}
+		case *mongo.UpdateManyModel:
+			if !isIdempotentUpdate(m.Update) {
+				return false
+			}
+		default:
+			// Inserts may duplicate and DeleteOne may delete another match
+			return false
+		}
+	}
+	return true
+}
+
+// isIdempotentUpdate gets whether the update document only uses idempotent operators
+func isIdempotentUpdate(update interface{}) bool {
+	b, err := bson.Marshal(update)
+	if err != nil {
+		return false
+	}
+	elems, err := bson.Raw(b).Elements()
+	if err != nil {
+		return false
+	}
+	for _, e := range elems {
+		if !idempotentUpdateOperators[e.Key()] {
+			return false
+		}
+	}
+	return true
+}
+
+type retryHelper struct {
+	helper MongoHelper
+	policy RetryPolicy
+}
+
+// WithRetry wraps the helper so operations failing with transient errors are
+// retried with exponential backoff. Reads, UpdateOne, DropCollection, and
+// index and validator operations are always retried. InsertOne, DeleteOne,
+// CreateCollection, RenameCollection and non-idempotent bulk writes are only
+// retried when the policy allows it. FindOne doesn't return errors so is
+// never retried.
+func WithRetry(h MongoHelper, policy RetryPolicy) MongoHelper {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 3
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = 50 * time.Millisecond
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = 2 * time.Second
+	}
+	if policy.Jitter == 0 {
+		policy.Jitter = 0.5
+	} else if policy.Jitter < 0 {
+		policy.Jitter = 0
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = IsRetryableError
+	}
+	return &retryHelper{h, policy}
+}
+
+// sleepContext waits for the duration or until the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// do calls fn until it succeeds, fails with an error that isn't retryable or
+// runs out of attempts
+func (h *retryHelper) do(ctx context.Context, idempotent bool, fn func() error) error {
+	if !idempotent && !h.policy.RetryNonIdempotent {
+		return fn()
+	}
+
+	backoff := h.policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= h.policy.MaxAttempts || !h.policy.Retryable(err) {
+			return err
+		}
+
+		wait := backoff - time.Duration(rand.Float64()*h.policy.Jitter*float64(backoff))
+		if sleepContext(ctx, wait) != nil {
+			// Return the operation's error rather than the context's
+			return err
+		}
+		backoff *= 2
+		if backoff > h.policy.MaxBackoff {
+			backoff = h.policy.MaxBackoff
+		}
+	}
+}
+
+func (h *retryHelper) NewClient(uri string) (MongoClient, error) {
+	return h.helper.NewClient(uri)
+}
+
+func (h *retryHelper) Find(ctx context.Context, coll string, filter interface{}, item interface{}, opts FindOptions) (res pagination.Result, err error) {
+	err = h.do(ctx, true, func() error {
+		res, err = h.helper.Find(ctx, coll, filter, item, opts)
+		return err
+	})
+	return
+}
+
+func (h *retryHelper) FindOne(ctx context.Context, coll string, filter interface{}, item interface{}) {
+	h.helper.FindOne(ctx, coll, filter, item)
+}
+
+func (h *retryHelper) InsertOne(ctx context.Context, coll string, item interface{}) (id interface{}, err error) {
+	err = h.do(ctx, false, func() error {
+		id, err = h.helper.InsertOne(ctx, coll, item)
+		return err
+	})
+	return
+}
+
+func (h *retryHelper) GetIDFromInsertOneResult(res *mongo.InsertOneResult) (interface{}, error) {
+	return h.helper.GetIDFromInsertOneResult(res)
+}
+
+func (h *retryHelper) UpdateOne(ctx context.Context, coll string, filter interface{}, item interface{}) error {
+	// The item replaces the match so applying it twice is safe, unless it's
+	// versioned as the second attempt would conflict with the first
+	_, versioned := item.(VersionedModel)
+	return h.do(ctx, !versioned, func() error {
+		return h.helper.UpdateOne(ctx, coll, filter, item)
+	})
+}
+
+func (h *retryHelper) DeleteOne(ctx context.Context, coll string, filter interface{}, item interface{}) error {
+	// A retry after a lost response may delete another match
+	return h.do(ctx, false, func() error {
+		return h.helper.DeleteOne(ctx, coll, filter, item)
+	})
+}
+
+func (h *retryHelper) BulkWrite(ctx context.Context, coll string, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (res *mongo.BulkWriteResult, err error) {
+	err = h.do(ctx, isIdempotentWrite(models), func() error {
+		res, err = h.helper.BulkWrite(ctx, coll, models, opts...)
+		return err
+	})
+	return
+}
+
+func (h *retryHelper) GetIndex(ctx context.Context, coll string, index string) (res *bson.M, err error) {
+	err = h.do(ctx, true, func() error {
+		res, err = h.helper.GetIndex(ctx, coll, index)
+		return err
+	})
+	return
+}
+
+func (h *retryHelper) HasIndex(ctx context.Context, coll string, index string) (res bool, err error) {
+	err = h.do(ctx, true, func() error {
+		res, err = h.helper.HasIndex(ctx, coll, index)
+		return err
+	})
+	return
+}
+
+func (h *retryHelper) AddIndexIfNotExists(ctx context.Context, coll string, name string, keys interface{}) error {
+	return h.do(ctx, true, func() error {
+		return h.helper.AddIndexIfNotExists(ctx, coll, name, keys)
+	})
+}
+
+func (h *retryHelper) Aggregate(ctx context.Context, coll string, pipeline mongo.Pipeline) (res []bson.M, err error) {
+	err = h.do(ctx, isIdempotentPipeline(pipeline), func() error {
+		res, err = h.helper.Aggregate(ctx, coll, pipeline)
+		return err
+	})
+	return
+}
+
+func (h *retryHelper) GetValidator(ctx context.Context, coll string) (res *CollectionValidator, err error) {
+	err = h.do(ctx, true, func() error {
+		res, err = h.helper.GetValidator(ctx, coll)
+		return err
+	})
+	return
+}
+
+func (h *retryHelper) SetValidator(ctx context.Context, coll string, v CollectionValidator) error {
+	return h.do(ctx, true, func() error {
+		return h.helper.SetValidator(ctx, coll, v)
+	})
+}
+
+func (h *retryHelper) GetCollection(ctx context.Context, name string) (res *CollectionInfo, err error) {
+	err = h.do(ctx, true, func() error {
+		res, err = h.helper.GetCollection(ctx, name)
+		return err
+	})
+	return
+}
+
+func (h *retryHelper) CreateCollection(ctx context.Context, name string, opts CollectionOptions) error {
+	// A retry after a lost response fails as the collection exists
+	return h.do(ctx, false, func() error {
+		return h.helper.CreateCollection(ctx, name, opts)
+	})
+}
+
+func (h *retryHelper) RenameCollection(ctx context.Context, from string, to string, dropTarget bool) error {
+	// A retry after a lost response fails as the collection was renamed
+	return h.do(ctx, false, func() error {
+		return h.helper.RenameCollection(ctx, from, to, dropTarget)
+	})
+}
+
+func (h *retryHelper) DropCollection(ctx context.Context, name string) error {
+	return h.do(ctx, true, func() error {
+		return h.helper.DropCollection(ctx, name)
+	})
+}
+
+// isIdempotentPipeline gets whether running the pipeline twice has the same
+// result as running it once. $out replaces its collection so is safe to
+// repeat but $merge may not be.
+func isIdempotentPipeline(pipeline mongo.Pipeline) bool {
+	for _, stage := range pipeline {
+		for _, e := range stage {
+			if e.Key == "$merge" {
+				return false
+			}
+		}
+	}
+	return true
+}
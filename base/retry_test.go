@@ -0,0 +1,280 @@
+package base_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	bMocks "github.com/archy-bold/mongo-go-helper/mocks/base"
+	"github.com/archy-bold/mongo-go-helper/pagination"
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	errStepDown = mongo.CommandError{Code: 189, Name: "PrimarySteppedDown", Message: "primary stepped down"}
+	errNetwork  = mongo.CommandError{Labels: []string{"NetworkError"}, Message: "connection reset"}
+)
+
+// timeoutError is a net.Error
+type timeoutError struct {
+	timeout bool
+}
+
+func (e timeoutError) Error() string   { return "i/o timeout" }
+func (e timeoutError) Timeout() bool   { return e.timeout }
+func (e timeoutError) Temporary() bool { return false }
+
+var _ net.Error = timeoutError{}
+
+var isRetryableErrorTests = map[string]struct {
+	err      error
+	expected bool
+}{
+	"nil":                   {nil, false},
+	"other":                 {errMock, false},
+	"no documents":          {mongo.ErrNoDocuments, false},
+	"no matches":            {base.ErrNoMatches, false},
+	"context cancelled":     {context.Canceled, false},
+	"step down":             {errStepDown, true},
+	"network label":         {errNetwork, true},
+	"retryable write label": {mongo.CommandError{Labels: []string{"RetryableWriteError"}}, true},
+	"wrapped":               {pkgerrors.Wrap(errStepDown, "failed count on Find"), true},
+	"other command":         {mongo.CommandError{Code: 73, Name: "InvalidNamespace"}, false},
+	"duplicate key": {
+		mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000, Message: "duplicate key"}}},
+		false,
+	},
+	"write concern not master": {
+		mongo.WriteException{WriteConcernError: &mongo.WriteConcernError{Code: 10107, Message: "not master"}},
+		true,
+	},
+	"bulk write shutdown": {
+		mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{{WriteError: mongo.WriteError{Code: 91}}}},
+		true,
+	},
+	"net timeout":    {timeoutError{true}, true},
+	"net other":      {timeoutError{false}, false},
+	"unexpected eof": {pkgerrors.Wrap(io.ErrUnexpectedEOF, "read"), true},
+}
+
+func Test_IsRetryableError(t *testing.T) {
+	for tn, tt := range isRetryableErrorTests {
+		assert.Equalf(t, tt.expected, base.IsRetryableError(tt.err), "Expected retryable to match on test '%s'", tn)
+	}
+}
+
+// fastPolicy gets a policy retrying without waiting
+func fastPolicy() base.RetryPolicy {
+	return base.RetryPolicy{InitialBackoff: time.Nanosecond, Jitter: -1}
+}
+
+var withRetryTests = map[string]struct {
+	errs     []error
+	attempts int
+	expected error
+}{
+	"success":         {[]error{nil}, 1, nil},
+	"transient":       {[]error{errStepDown, errNetwork, nil}, 3, nil},
+	"out of attempts": {[]error{errStepDown, errStepDown, errStepDown}, 3, errStepDown},
+	"not retryable":   {[]error{errMock}, 1, errMock},
+	"then permanent":  {[]error{errNetwork, base.ErrNoMatches}, 2, base.ErrNoMatches},
+}
+
+func Test_WithRetry(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range withRetryTests {
+		helper := &bMocks.MongoHelper{}
+		for _, err := range tt.errs {
+			helper.On("UpdateOne", ctx, "test", bson.M{"_id": 1}, mock.Anything).Return(err).Once()
+		}
+		h := base.WithRetry(helper, fastPolicy())
+
+		err := h.UpdateOne(ctx, "test", bson.M{"_id": 1}, &exampleModel{})
+
+		assert.Equalf(t, tt.expected, err, "Expected err to match on test '%s'", tn)
+		helper.AssertNumberOfCalls(t, "UpdateOne", tt.attempts)
+	}
+}
+
+func Test_WithRetry_Results(t *testing.T) {
+	ctx := context.Background()
+	helper := &bMocks.MongoHelper{}
+	expected := pagination.Result{Total: 1, Items: []interface{}{exampleModel{Str: "a"}}}
+	helper.On("Find", ctx, "test", bson.M{}, exampleModel{}, base.FindOptions{}).Return(pagination.Result{}, errNetwork).Once()
+	helper.On("Find", ctx, "test", bson.M{}, exampleModel{}, base.FindOptions{}).Return(expected, nil).Once()
+	h := base.WithRetry(helper, fastPolicy())
+
+	res, err := h.Find(ctx, "test", bson.M{}, exampleModel{}, base.FindOptions{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, res, "Expected the result of the successful attempt")
+}
+
+func Test_WithRetry_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	helper := &bMocks.MongoHelper{}
+	helper.On("HasIndex", ctx, "test", "index").Return(false, errStepDown).Run(func(mock.Arguments) {
+		cancel()
+	})
+	h := base.WithRetry(helper, base.RetryPolicy{InitialBackoff: time.Hour})
+
+	_, err := h.HasIndex(ctx, "test", "index")
+
+	assert.Equal(t, errStepDown, err, "Expected the operation's error when cancelled while waiting")
+	helper.AssertNumberOfCalls(t, "HasIndex", 1)
+}
+
+var idempotencyTests = map[string]struct {
+	call        func(ctx context.Context, h base.MongoHelper)
+	method      string
+	allowAll    bool
+	numAttempts int
+}{
+	"insert": {
+		func(ctx context.Context, h base.MongoHelper) { h.InsertOne(ctx, "test", &exampleModel{}) },
+		"InsertOne", false, 1,
+	},
+	"insert allowed": {
+		func(ctx context.Context, h base.MongoHelper) { h.InsertOne(ctx, "test", &exampleModel{}) },
+		"InsertOne", true, 3,
+	},
+	"update": {
+		func(ctx context.Context, h base.MongoHelper) {
+			h.UpdateOne(ctx, "test", bson.M{"_id": 1}, &exampleModel{})
+		},
+		"UpdateOne", false, 3,
+	},
+	"update versioned": {
+		func(ctx context.Context, h base.MongoHelper) {
+			h.UpdateOne(ctx, "test", bson.M{"_id": 1}, &versionedModel{Version: 1})
+		},
+		"UpdateOne", false, 1,
+	},
+	"bulk replace versioned": {
+		func(ctx context.Context, h base.MongoHelper) {
+			h.BulkWrite(ctx, "test", []mongo.WriteModel{
+				mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": 1}).SetReplacement(&versionedModel{Version: 1}),
+			})
+		},
+		"BulkWrite", false, 1,
+	},
+	"bulk set": {
+		func(ctx context.Context, h base.MongoHelper) {
+			h.BulkWrite(ctx, "test", []mongo.WriteModel{
+				mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": 1}).SetUpdate(bson.M{"$set": bson.M{"a": 1}}),
+				mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": 2}).SetReplacement(bson.M{"a": 1}),
+				mongo.NewDeleteManyModel().SetFilter(bson.M{"a": 2}),
+			})
+		},
+		"BulkWrite", false, 3,
+	},
+	"bulk inc": {
+		func(ctx context.Context, h base.MongoHelper) {
+			h.BulkWrite(ctx, "test", []mongo.WriteModel{
+				mongo.NewUpdateManyModel().SetFilter(bson.M{}).SetUpdate(bson.D{{Key: "$set", Value: bson.M{"a": 1}}, {Key: "$inc", Value: bson.M{"n": 1}}}),
+			})
+		},
+		"BulkWrite", false, 1,
+	},
+	"bulk insert": {
+		func(ctx context.Context, h base.MongoHelper) {
+			h.BulkWrite(ctx, "test", []mongo.WriteModel{mongo.NewInsertOneModel().SetDocument(bson.M{"a": 1})})
+		},
+		"BulkWrite", false, 1,
+	},
+	"bulk delete one": {
+		func(ctx context.Context, h base.MongoHelper) {
+			h.BulkWrite(ctx, "test", []mongo.WriteModel{mongo.NewDeleteOneModel().SetFilter(bson.M{"a": 1})})
+		},
+		"BulkWrite", false, 1,
+	},
+	"aggregate": {
+		func(ctx context.Context, h base.MongoHelper) {
+			h.Aggregate(ctx, "test", mongo.Pipeline{{{Key: "$match", Value: bson.M{}}}, {{Key: "$out", Value: "copy"}}})
+		},
+		"Aggregate", false, 3,
+	},
+	"aggregate merge": {
+		func(ctx context.Context, h base.MongoHelper) {
+			h.Aggregate(ctx, "test", mongo.Pipeline{{{Key: "$merge", Value: bson.M{"into": "totals"}}}})
+		},
+		"Aggregate", false, 1,
+	},
+	"add index": {
+		func(ctx context.Context, h base.MongoHelper) {
+			h.AddIndexIfNotExists(ctx, "test", "index", bson.M{"a": 1})
+		},
+		"AddIndexIfNotExists", false, 3,
+	},
+	"delete": {
+		func(ctx context.Context, h base.MongoHelper) { h.DeleteOne(ctx, "test", bson.M{"_id": 1}, nil) },
+		"DeleteOne", false, 1,
+	},
+	"get index": {
+		func(ctx context.Context, h base.MongoHelper) { h.GetIndex(ctx, "test", "index") },
+		"GetIndex", false, 3,
+	},
+	"create collection": {
+		func(ctx context.Context, h base.MongoHelper) {
+			h.CreateCollection(ctx, "test", base.CollectionOptions{})
+		},
+		"CreateCollection", false, 1,
+	},
+	"drop collection": {
+		func(ctx context.Context, h base.MongoHelper) { h.DropCollection(ctx, "test") },
+		"DropCollection", false, 3,
+	},
+	"set validator": {
+		func(ctx context.Context, h base.MongoHelper) { h.SetValidator(ctx, "test", base.CollectionValidator{}) },
+		"SetValidator", false, 3,
+	},
+}
+
+func Test_WithRetry_Idempotency(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range idempotencyTests {
+		helper := &bMocks.MongoHelper{}
+		helper.On("InsertOne", mock.Anything, mock.Anything, mock.Anything).Return(nil, errNetwork)
+		helper.On("BulkWrite", mock.Anything, mock.Anything, mock.Anything).Return(nil, errNetwork)
+		helper.On("Aggregate", mock.Anything, mock.Anything, mock.Anything).Return(nil, errNetwork)
+		helper.On("AddIndexIfNotExists", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errNetwork)
+		helper.On("GetIndex", mock.Anything, mock.Anything, mock.Anything).Return(nil, errNetwork)
+		helper.On("UpdateOne", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errNetwork)
+		helper.On("DeleteOne", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errNetwork)
+		helper.On("SetValidator", mock.Anything, mock.Anything, mock.Anything).Return(errNetwork)
+		helper.On("CreateCollection", mock.Anything, mock.Anything, mock.Anything).Return(errNetwork)
+		helper.On("DropCollection", mock.Anything, mock.Anything).Return(errNetwork)
+		policy := fastPolicy()
+		policy.RetryNonIdempotent = tt.allowAll
+
+		tt.call(ctx, base.WithRetry(helper, policy))
+
+		assert.Equalf(t, tt.numAttempts, len(helper.Calls), "Expected the number of %s attempts to match on test '%s'", tt.method, tn)
+	}
+}
+
+func Test_WithRetry_CustomClassifier(t *testing.T) {
+	ctx := context.Background()
+	helper := &bMocks.MongoHelper{}
+	helper.On("AddIndexIfNotExists", ctx, "test", "index", mock.Anything).Return(errMock)
+	policy := fastPolicy()
+	policy.MaxAttempts = 5
+	policy.Retryable = func(err error) bool {
+		return errors.Is(err, errMock)
+	}
+
+	err := base.WithRetry(helper, policy).AddIndexIfNotExists(ctx, "test", "index", bson.M{"a": 1})
+
+	assert.Equal(t, errMock, err)
+	helper.AssertNumberOfCalls(t, "AddIndexIfNotExists", 5)
+}
@@ -0,0 +1,374 @@
+package base
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// codeNamespaceNotFound is the server's error code for a missing collection
+const codeNamespaceNotFound = 26
+
+// Validation levels, which choose the documents a collection's validator
+// applies to
+const (
+	ValidationLevelOff      = "off"
+	ValidationLevelStrict   = "strict"
+	ValidationLevelModerate = "moderate"
+)
+
+// Validation actions, which choose what happens to invalid writes
+const (
+	ValidationActionError = "error"
+	ValidationActionWarn  = "warn"
+)
+
+// CollectionValidator is the document validation of a collection
+type CollectionValidator struct {
+	// Validator is the query documents must match, such as a $jsonSchema
+	Validator bson.D `bson:"validator,omitempty"`
+	// Level defaults to ValidationLevelStrict
+	Level string `bson:"validationLevel,omitempty"`
+	// Action defaults to ValidationActionError
+	Action string `bson:"validationAction,omitempty"`
+}
+
+// Equal gets whether the validators are the same, treating empty levels and
+// actions as their defaults
+func (v CollectionValidator) Equal(other CollectionValidator) bool {
+	a, errA := normaliseDoc(v.Validator)
+	b, errB := normaliseDoc(other.Validator)
+	return errA == nil && errB == nil && reflect.DeepEqual(a, b) &&
+		orDefault(v.Level, ValidationLevelStrict) == orDefault(other.Level, ValidationLevelStrict) &&
+		orDefault(v.Action, ValidationActionError) == orDefault(other.Action, ValidationActionError)
+}
+
+func orDefault(s string, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// normaliseDoc round trips the document through BSON so documents built
+// from Go values compare equal to those read from the server
+func normaliseDoc(doc bson.D) (bson.D, error) {
+	norm := bson.D{}
+	if len(doc) == 0 {
+		return norm, nil
+	}
+	b, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	err = bson.Unmarshal(b, &norm)
+	return norm, err
+}
+
+// GetValidator gets the validator of the collection, or nil if the
+// collection doesn't exist
+func (h *helper) GetValidator(ctx context.Context, coll string) (*CollectionValidator, error) {
+	info, err := h.GetCollection(ctx, coll)
+	if info == nil {
+		return nil, err
+	}
+	return &info.Options.CollectionValidator, nil
+}
+
+// SetValidator sets the validator of the collection, creating it if it
+// doesn't exist
+func (h *helper) SetValidator(ctx context.Context, coll string, v CollectionValidator) error {
+	err := h.db.RunCommand(ctx, validatorCommand("collMod", coll, v)).Err()
+	var ce mongo.CommandError
+	if errors.As(err, &ce) && ce.Code == codeNamespaceNotFound {
+		err = h.db.RunCommand(ctx, validatorCommand("create", coll, v)).Err()
+	}
+	return MapError(err)
+}
+
+// validatorCommand gets the create or collMod command setting the validator
+func validatorCommand(name string, coll string, v CollectionValidator) bson.D {
+	validator := v.Validator
+	if validator == nil {
+		validator = bson.D{}
+	}
+	return bson.D{
+		{Key: name, Value: coll},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: orDefault(v.Level, ValidationLevelStrict)},
+		{Key: "validationAction", Value: orDefault(v.Action, ValidationActionError)},
+	}
+}
+
+var (
+	objectIDType   = reflect.TypeOf(primitive.ObjectID{})
+	dateTimeType   = reflect.TypeOf(primitive.DateTime(0))
+	decimalType    = reflect.TypeOf(primitive.Decimal128{})
+	binaryType     = reflect.TypeOf(primitive.Binary{})
+	bsonDocTypes   = []reflect.Type{reflect.TypeOf(bson.D{}), reflect.TypeOf(bson.M{}), reflect.TypeOf(bson.Raw{})}
+	bsonArrayTypes = []reflect.Type{reflect.TypeOf(bson.A{})}
+)
+
+// JSONSchema generates a $jsonSchema for documents of the model, a struct or
+// pointer to one, from its fields' bson names and types and validate tags.
+// The rules of validate tags are converted as:
+//
+//	required  the field is in the schema's required list, so must be present
+//	min, max  minimum and maximum for numbers, and lengths for strings, arrays and maps
+//	enum      the values the field may be, converted to the field's type
+//	regex     a pattern strings must match
+//
+// Pointers, slices and maps may also be null, and fields with
+// `validate:"-"` may hold anything. Fields the model doesn't have are
+// allowed, so documents written by older models stay valid.
+func JSONSchema(model interface{}) (bson.D, error) {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, errors.Errorf("could not generate JSON schema: %T is not a struct", model)
+	}
+	return structSchema(t, map[reflect.Type]bool{})
+}
+
+// structSchema gets the schema of a struct. Structs it's already generating
+// are allowed to be any object, so recursive types end.
+func structSchema(t reflect.Type, seen map[reflect.Type]bool) (bson.D, error) {
+	schema := bson.D{{Key: "bsonType", Value: "object"}}
+	if seen[t] {
+		return schema, nil
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	props := bson.D{}
+	required := bson.A{}
+	if err := addProperties(t, seen, &props, &required); err != nil {
+		return nil, err
+	}
+	if len(required) > 0 {
+		schema = append(schema, bson.E{Key: "required", Value: required})
+	}
+	if len(props) > 0 {
+		schema = append(schema, bson.E{Key: "properties", Value: props})
+	}
+	return schema, nil
+}
+
+// addProperties adds the struct's fields to the properties, and those
+// required to the list. Inlined structs add their fields to the parent's.
+func addProperties(t reflect.Type, seen map[reflect.Type]bool, props *bson.D, required *bson.A) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get(ValidateTag)
+		if sf.PkgPath != "" || sf.Tag.Get("bson") == "-" || tag == "-" {
+			continue
+		}
+		rules, err := parseRules(tag)
+		if err != nil {
+			return errors.Wrapf(err, "invalid validate tag on %s.%s", t.Name(), sf.Name)
+		}
+
+		name := bsonName(sf)
+		if name == "" {
+			// Inlined maps hold any other fields, so only structs add properties
+			ft := sf.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := addProperties(ft, seen, props, required); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		schema, err := fieldSchema(sf.Type, seen)
+		if err != nil {
+			return err
+		}
+		schema, err = applyRules(schema, sf.Type, rules)
+		if err != nil {
+			return errors.Wrapf(err, "invalid validate tag on %s.%s", t.Name(), sf.Name)
+		}
+		for _, r := range rules {
+			if r.name == "required" {
+				*required = append(*required, name)
+			}
+		}
+		*props = append(*props, bson.E{Key: name, Value: schema})
+	}
+	return nil
+}
+
+// fieldSchema gets the schema of values of the type
+func fieldSchema(t reflect.Type, seen map[reflect.Type]bool) (bson.D, error) {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		nullable = true
+		t = t.Elem()
+	}
+
+	var schema bson.D
+	switch {
+	case t == timeType || t == dateTimeType:
+		schema = bson.D{{Key: "bsonType", Value: "date"}}
+	case t == objectIDType:
+		schema = bson.D{{Key: "bsonType", Value: "objectId"}}
+	case t == decimalType:
+		schema = bson.D{{Key: "bsonType", Value: "decimal"}}
+	case t == binaryType || (t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8):
+		schema = bson.D{{Key: "bsonType", Value: "binData"}}
+	case isOneOf(t, bsonDocTypes):
+		schema = bson.D{{Key: "bsonType", Value: "object"}}
+	case isOneOf(t, bsonArrayTypes):
+		schema = bson.D{{Key: "bsonType", Value: "array"}}
+	default:
+		switch t.Kind() {
+		case reflect.Struct:
+			s, err := structSchema(t, seen)
+			if err != nil {
+				return nil, err
+			}
+			schema = s
+		case reflect.Slice, reflect.Array:
+			items, err := fieldSchema(t.Elem(), seen)
+			if err != nil {
+				return nil, err
+			}
+			schema = bson.D{{Key: "bsonType", Value: "array"}}
+			if len(items) > 0 {
+				schema = append(schema, bson.E{Key: "items", Value: items})
+			}
+			nullable = nullable || t.Kind() == reflect.Slice
+		case reflect.Map:
+			values, err := fieldSchema(t.Elem(), seen)
+			if err != nil {
+				return nil, err
+			}
+			schema = bson.D{{Key: "bsonType", Value: "object"}}
+			if len(values) > 0 {
+				schema = append(schema, bson.E{Key: "additionalProperties", Value: values})
+			}
+			nullable = true
+		case reflect.String:
+			schema = bson.D{{Key: "bsonType", Value: "string"}}
+		case reflect.Bool:
+			schema = bson.D{{Key: "bsonType", Value: "bool"}}
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+			schema = bson.D{{Key: "bsonType", Value: "int"}}
+		case reflect.Int64, reflect.Uint32, reflect.Uint64:
+			schema = bson.D{{Key: "bsonType", Value: "long"}}
+		case reflect.Int, reflect.Uint:
+			// Encoded as an int when it fits, otherwise a long
+			schema = bson.D{{Key: "bsonType", Value: bson.A{"int", "long"}}}
+		case reflect.Float32, reflect.Float64:
+			schema = bson.D{{Key: "bsonType", Value: "double"}}
+		default:
+			// Interfaces may hold anything
+			return bson.D{}, nil
+		}
+	}
+
+	if nullable {
+		schema[0].Value = appendType(schema[0].Value, "null")
+	}
+	return schema, nil
+}
+
+// applyRules adds the validate rules to the field's schema
+func applyRules(schema bson.D, t reflect.Type, rules []rule) (bson.D, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for _, r := range rules {
+		switch r.name {
+		case "min", "max":
+			schema = append(schema, bson.E{Key: boundKeyword(r.name, t), Value: boundValue(r.num, t)})
+		case "enum":
+			values := bson.A{}
+			for _, e := range r.enum {
+				v, err := enumValue(e, t)
+				if err != nil {
+					return nil, err
+				}
+				values = append(values, v)
+			}
+			schema = append(schema, bson.E{Key: "enum", Value: values})
+		case "regex":
+			schema = append(schema, bson.E{Key: "pattern", Value: r.regex.String()})
+		}
+	}
+	return schema, nil
+}
+
+// boundKeyword gets the keyword limiting values of the type for a min or max rule
+func boundKeyword(name string, t reflect.Type) string {
+	var suffix string
+	switch t.Kind() {
+	case reflect.String:
+		suffix = "Length"
+	case reflect.Slice, reflect.Array:
+		suffix = "Items"
+	case reflect.Map:
+		suffix = "Properties"
+	default:
+		if name == "min" {
+			return "minimum"
+		}
+		return "maximum"
+	}
+	return name + suffix
+}
+
+// boundValue gets the number in a bound of the type, as lengths must be integers
+func boundValue(n float64, t reflect.Type) interface{} {
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return int64(n)
+	case reflect.Float32, reflect.Float64:
+		return n
+	}
+	if n == float64(int64(n)) {
+		return int64(n)
+	}
+	return n
+}
+
+// enumValue converts an enum value to the type of the field
+func enumValue(s string, t reflect.Type) (interface{}, error) {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseInt(s, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(s, 64)
+	case reflect.Bool:
+		return strconv.ParseBool(s)
+	}
+	return s, nil
+}
+
+// appendType adds a BSON type to a bsonType value
+func appendType(v interface{}, typ string) interface{} {
+	if types, ok := v.(bson.A); ok {
+		return append(types, typ)
+	}
+	return bson.A{v, typ}
+}
+
+func isOneOf(t reflect.Type, types []reflect.Type) bool {
+	for _, other := range types {
+		if t == other {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+package base_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/mongotest"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	addressSchema = bson.D{
+		{Key: "bsonType", Value: "object"},
+		{Key: "required", Value: bson.A{"city"}},
+		{Key: "properties", Value: bson.D{
+			{Key: "city", Value: bson.D{{Key: "bsonType", Value: "string"}}},
+			{Key: "postcode", Value: bson.D{{Key: "bsonType", Value: "string"}, {Key: "pattern", Value: "^[A-Z0-9]{2,4} [0-9][A-Z]{2}$"}}},
+		}},
+	}
+	lineItemProperties = bson.D{
+		{Key: "sku", Value: bson.D{{Key: "bsonType", Value: "string"}}},
+		{Key: "qty", Value: bson.D{{Key: "bsonType", Value: bson.A{"int", "long"}}, {Key: "minimum", Value: int64(1)}, {Key: "maximum", Value: int64(100)}}},
+	}
+)
+
+type EmbeddedNote struct {
+	Note string `bson:"note" validate:"required"`
+}
+
+type schemaModel struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	EmbeddedNote `bson:",inline"`
+	Raw          []byte       `bson:"raw"`
+	Any          interface{}  `bson:"any"`
+	Parent       *schemaModel `bson:"parent"`
+	Count        int64        `bson:"count" validate:"enum=1|2"`
+	Active       bool         `bson:"active"`
+	Ignored      string       `bson:"-"`
+	private      string
+}
+
+type badTagModel struct {
+	Str string `bson:"str" validate:"foo"`
+}
+
+type badEnumModel struct {
+	Num int `bson:"num" validate:"enum=a"`
+}
+
+var jsonSchemaTests = map[string]struct {
+	model    interface{}
+	expected bson.D
+	err      string
+}{
+	"nested rules": {
+		&order{},
+		bson.D{
+			{Key: "bsonType", Value: "object"},
+			{Key: "required", Value: bson.A{"name", "items"}},
+			{Key: "properties", Value: bson.D{
+				{Key: "name", Value: bson.D{{Key: "bsonType", Value: "string"}, {Key: "maxLength", Value: int64(10)}}},
+				{Key: "status", Value: bson.D{{Key: "bsonType", Value: "string"}, {Key: "enum", Value: bson.A{"new", "paid", "shipped"}}}},
+				{Key: "priority", Value: bson.D{{Key: "bsonType", Value: bson.A{"int", "long"}}, {Key: "enum", Value: bson.A{int64(1), int64(2), int64(3)}}}},
+				{Key: "discount", Value: bson.D{{Key: "bsonType", Value: bson.A{"double", "null"}}, {Key: "minimum", Value: 0.0}, {Key: "maximum", Value: 0.5}}},
+				{Key: "tags", Value: bson.D{{Key: "bsonType", Value: bson.A{"array", "null"}}, {Key: "items", Value: bson.D{{Key: "bsonType", Value: "string"}}}, {Key: "maxItems", Value: int64(2)}}},
+				{Key: "address", Value: addressSchema},
+				{Key: "billing", Value: bson.D{
+					{Key: "bsonType", Value: bson.A{"object", "null"}},
+					addressSchema[1],
+					addressSchema[2],
+				}},
+				{Key: "items", Value: bson.D{
+					{Key: "bsonType", Value: bson.A{"array", "null"}},
+					{Key: "items", Value: bson.D{
+						{Key: "bsonType", Value: "object"},
+						{Key: "required", Value: bson.A{"sku"}},
+						{Key: "properties", Value: lineItemProperties},
+					}},
+				}},
+				{Key: "extra", Value: bson.D{
+					{Key: "bsonType", Value: bson.A{"object", "null"}},
+					{Key: "additionalProperties", Value: bson.D{
+						{Key: "bsonType", Value: bson.A{"object", "null"}},
+						{Key: "required", Value: bson.A{"sku"}},
+						{Key: "properties", Value: lineItemProperties},
+					}},
+				}},
+				{Key: "createdAt", Value: bson.D{{Key: "bsonType", Value: "date"}}},
+			}},
+		},
+		"",
+	},
+	"types": {
+		schemaModel{},
+		bson.D{
+			{Key: "bsonType", Value: "object"},
+			{Key: "required", Value: bson.A{"note"}},
+			{Key: "properties", Value: bson.D{
+				{Key: "_id", Value: bson.D{{Key: "bsonType", Value: "objectId"}}},
+				{Key: "note", Value: bson.D{{Key: "bsonType", Value: "string"}}},
+				{Key: "raw", Value: bson.D{{Key: "bsonType", Value: "binData"}}},
+				{Key: "any", Value: bson.D{}},
+				{Key: "parent", Value: bson.D{{Key: "bsonType", Value: bson.A{"object", "null"}}}},
+				{Key: "count", Value: bson.D{{Key: "bsonType", Value: "long"}, {Key: "enum", Value: bson.A{int64(1), int64(2)}}}},
+				{Key: "active", Value: bson.D{{Key: "bsonType", Value: "bool"}}},
+			}},
+		},
+		"",
+	},
+	"not a struct": {"str", nil, "could not generate JSON schema: string is not a struct"},
+	"nil":          {nil, nil, "could not generate JSON schema: <nil> is not a struct"},
+	"invalid tag":  {&badTagModel{}, nil, "invalid validate tag on badTagModel.Str: unknown rule 'foo'"},
+	"invalid enum": {&badEnumModel{}, nil, "invalid validate tag on badEnumModel.Num: strconv.ParseInt: parsing \"a\": invalid syntax"},
+}
+
+func Test_JSONSchema(t *testing.T) {
+	for tn, tt := range jsonSchemaTests {
+		schema, err := base.JSONSchema(tt.model)
+
+		assert.Equalf(t, tt.expected, schema, "Expected schema to match on test '%s'", tn)
+		if tt.err == "" {
+			assert.Nilf(t, err, "Expected nil err on test '%s'", tn)
+		} else {
+			assert.EqualErrorf(t, err, tt.err, "Expected error on test '%s'", tn)
+		}
+	}
+}
+
+var collectionValidatorEqualTests = map[string]struct {
+	a        base.CollectionValidator
+	b        base.CollectionValidator
+	expected bool
+}{
+	"same": {
+		base.CollectionValidator{Validator: bson.D{{Key: "a", Value: 1}}, Level: "moderate"},
+		base.CollectionValidator{Validator: bson.D{{Key: "a", Value: 1}}, Level: "moderate"},
+		true,
+	},
+	"decoded": {
+		base.CollectionValidator{Validator: bson.D{{Key: "a", Value: bson.A{"b"}}}},
+		base.CollectionValidator{Validator: bson.D{{Key: "a", Value: []string{"b"}}}},
+		true,
+	},
+	"defaults": {
+		base.CollectionValidator{},
+		base.CollectionValidator{Level: base.ValidationLevelStrict, Action: base.ValidationActionError},
+		true,
+	},
+	"different validator": {
+		base.CollectionValidator{Validator: bson.D{{Key: "a", Value: 1}}},
+		base.CollectionValidator{Validator: bson.D{{Key: "a", Value: 2}}},
+		false,
+	},
+	"different order": {
+		base.CollectionValidator{Validator: bson.D{{Key: "a", Value: 1}, {Key: "b", Value: 1}}},
+		base.CollectionValidator{Validator: bson.D{{Key: "b", Value: 1}, {Key: "a", Value: 1}}},
+		false,
+	},
+	"different action": {
+		base.CollectionValidator{},
+		base.CollectionValidator{Action: base.ValidationActionWarn},
+		false,
+	},
+}
+
+func Test_CollectionValidator_Equal(t *testing.T) {
+	for tn, tt := range collectionValidatorEqualTests {
+		assert.Equalf(t, tt.expected, tt.a.Equal(tt.b), "Expected Equal to match on test '%s'", tn)
+	}
+}
+
+func Test_SetValidator(t *testing.T) {
+	ctx := context.Background()
+	db := mongotest.New(t)
+	h := db.Helper
+
+	v, err := h.GetValidator(ctx, "test")
+	assert.Nil(t, err)
+	assert.Nil(t, v, "Expected no validator for a missing collection")
+
+	db.Collection("plain").InsertOne(ctx, bson.M{"a": 1})
+	v, err = h.GetValidator(ctx, "plain")
+	assert.Nil(t, err)
+	assert.Equal(t, &base.CollectionValidator{}, v, "Expected an empty validator for a collection without one")
+
+	validator := base.CollectionValidator{Validator: bson.D{{Key: "$jsonSchema", Value: bson.D{{Key: "required", Value: bson.A{"a"}}}}}}
+	err = h.SetValidator(ctx, "test", validator)
+	assert.Nil(t, err, "Expected the collection to be created")
+	v, err = h.GetValidator(ctx, "test")
+	assert.Nil(t, err)
+	if assert.NotNil(t, v) {
+		assert.True(t, v.Equal(validator), "Expected the validator to be set")
+		assert.Equal(t, base.ValidationLevelStrict, v.Level)
+		assert.Equal(t, base.ValidationActionError, v.Action)
+	}
+
+	validator.Action = base.ValidationActionWarn
+	err = h.SetValidator(ctx, "test", validator)
+	assert.Nil(t, err, "Expected the collection to be modified")
+	v, _ = h.GetValidator(ctx, "test")
+	if assert.NotNil(t, v) {
+		assert.Equal(t, base.ValidationActionWarn, v.Action)
+	}
+
+	err = h.SetValidator(ctx, "$bad", validator)
+	assert.EqualError(t, err, "(InvalidNamespace) Invalid collection name specified '"+db.Name()+".$bad'")
+}
@@ -0,0 +1,81 @@
+//go:build go1.21
+// +build go1.21
+
+package base
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// SlogOptions configures the hook logging operations with log/slog
+type SlogOptions struct {
+	// Level is the level operations are logged at unless they're slow or
+	// fail. Defaults to debug.
+	Level slog.Leveler
+	// SlowThreshold logs operations taking at least this long at warn
+	// level. Zero turns it off.
+	SlowThreshold time.Duration
+	// Redact is applied to filters before they're logged. Defaults to
+	// RedactFilter, use KeepFilter to log their values.
+	Redact func(filter interface{}) interface{}
+}
+
+// NewSlogHook gets a hook logging each operation to the logger. Failed
+// operations are logged at error level.
+func NewSlogHook(logger *slog.Logger, opts SlogOptions) Hook {
+	if opts.Level == nil {
+		opts.Level = slog.LevelDebug
+	}
+	if opts.Redact == nil {
+		opts.Redact = RedactFilter
+	}
+
+	return AfterHookFunc(func(ctx context.Context, op *Operation) {
+		level, msg := opts.Level.Level(), "mongo operation"
+		if op.Err != nil {
+			level, msg = slog.LevelError, "mongo operation failed"
+		} else if opts.SlowThreshold > 0 && op.Duration >= opts.SlowThreshold {
+			level, msg = slog.LevelWarn, "slow mongo operation"
+		}
+		if !logger.Enabled(ctx, level) {
+			return
+		}
+
+		attrs := []slog.Attr{
+			slog.String("op", op.Name),
+			slog.String("collection", op.Collection),
+			slog.Duration("duration", op.Duration),
+		}
+		if op.Index != "" {
+			attrs = append(attrs, slog.String("index", op.Index))
+		}
+		if op.Filter != nil {
+			attrs = append(attrs, slog.String("filter", FormatValue(opts.Redact(op.Filter))))
+		}
+		if op.Options != nil {
+			attrs = append(attrs, slog.String("options", FormatValue(op.Options)))
+		}
+		for _, c := range []struct {
+			key string
+			n   int64
+		}{
+			{"returned", op.Returned},
+			{"inserted", op.Inserted},
+			{"matched", op.Matched},
+			{"modified", op.Modified},
+			{"deleted", op.Deleted},
+			{"upserted", op.Upserted},
+		} {
+			if c.n > 0 {
+				attrs = append(attrs, slog.Int64(c.key, c.n))
+			}
+		}
+		if op.Err != nil {
+			attrs = append(attrs, slog.String("error", op.Err.Error()))
+		}
+
+		logger.LogAttrs(ctx, level, msg, attrs...)
+	})
+}
@@ -0,0 +1,84 @@
+//go:build go1.21
+// +build go1.21
+
+package base_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var slogHookTests = map[string]struct {
+	opts     base.SlogOptions
+	op       base.Operation
+	expected string
+}{
+	"debug": {
+		base.SlogOptions{},
+		base.Operation{Name: "Find", Collection: "users", Filter: bson.M{"email": "a@b.com"}, Options: base.FindOptions{PageSize: 10}, Duration: time.Millisecond, Returned: 3},
+		`level=DEBUG msg="mongo operation" op=Find collection=users duration=1ms filter="{\"email\":\"?\"}" options="{\"pagesize\":10,\"page\":0,\"sorting\":null}" returned=3` + "\n",
+	},
+	"slow": {
+		base.SlogOptions{SlowThreshold: time.Second},
+		base.Operation{Name: "UpdateOne", Collection: "users", Filter: bson.M{"_id": 1}, Duration: 2 * time.Second, Matched: 1},
+		`level=WARN msg="slow mongo operation" op=UpdateOne collection=users duration=2s filter="{\"_id\":\"?\"}" matched=1` + "\n",
+	},
+	"not slow": {
+		base.SlogOptions{SlowThreshold: time.Second, Level: slog.LevelInfo},
+		base.Operation{Name: "HasIndex", Collection: "users", Index: "email_1", Duration: time.Millisecond},
+		`level=INFO msg="mongo operation" op=HasIndex collection=users duration=1ms index=email_1` + "\n",
+	},
+	"failed": {
+		base.SlogOptions{SlowThreshold: time.Second},
+		base.Operation{Name: "InsertOne", Collection: "users", Duration: 2 * time.Second, Err: errors.New("duplicate")},
+		`level=ERROR msg="mongo operation failed" op=InsertOne collection=users duration=2s error=duplicate` + "\n",
+	},
+	"kept filter": {
+		base.SlogOptions{Redact: base.KeepFilter},
+		base.Operation{Name: "FindOne", Collection: "users", Filter: bson.M{"email": "a@b.com"}},
+		`level=DEBUG msg="mongo operation" op=FindOne collection=users duration=0s filter="{\"email\":\"a@b.com\"}"` + "\n",
+	},
+}
+
+func Test_SlogHook(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range slogHookTests {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+			Level: slog.LevelDebug,
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}))
+		hook := base.NewSlogHook(logger, tt.opts)
+
+		op := tt.op
+		hook.After(hook.Before(ctx, &op), &op)
+
+		assert.Equalf(t, tt.expected, buf.String(), "Expected the log line to match on test '%s'", tn)
+	}
+}
+
+func Test_SlogHook_Level(t *testing.T) {
+	ctx := context.Background()
+	_, h := setupMemDB(t, ctx)
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	h = base.WithHooks(h, base.NewSlogHook(logger, base.SlogOptions{}))
+
+	h.HasIndex(ctx, "test", "missing")
+
+	assert.Empty(t, buf.String(), "Expected debug operations not to be logged at info level")
+}
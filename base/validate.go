@@ -0,0 +1,335 @@
+package base
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ValidateTag is the struct tag holding a field's validation rules, such as
+// `validate:"required,max=50"`. The rules are:
+//
+//	required  the field isn't its zero value, or empty for slices and maps
+//	min=N     numbers are at least N, strings, slices and maps have at least N elements
+//	max=N     numbers are at most N, strings, slices and maps have at most N elements
+//	enum=a|b  the field is one of the values
+//	regex=RE  strings match the regular expression, which takes the rest of the tag
+//
+// Nested structs, and structs in slices and maps, are validated too. Use
+// `validate:"-"` to skip a field.
+const ValidateTag = "validate"
+
+// FieldError is a field that failed a validation rule
+type FieldError struct {
+	// Field is the path to the field using its BSON names, such as "items[0].qty"
+	Field string
+	// Rule is the rule that failed, such as "required"
+	Rule    string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// ValidationError holds every field that failed validation. It's an ErrValidation.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Error()
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Is matches ErrValidation
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// Add adds the field errors of err, prefixing their fields, such as with the
+// index of an item. Other errors are added as a failed rule of the prefix.
+func (e *ValidationError) Add(prefix string, err error) {
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		e.Errors = append(e.Errors, FieldError{Field: prefix, Rule: "invalid", Message: err.Error()})
+		return
+	}
+	for _, fe := range ve.Errors {
+		fe.Field = joinField(prefix, fe.Field)
+		e.Errors = append(e.Errors, fe)
+	}
+}
+
+// ErrorOrNil gets the error if any fields failed validation, otherwise nil
+func (e *ValidationError) ErrorOrNil() error {
+	if e == nil || len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// rule is a parsed validation rule
+type rule struct {
+	name  string
+	num   float64
+	enum  []string
+	regex *regexp.Regexp
+}
+
+// field is a struct field with its validation rules
+type field struct {
+	index int
+	name  string
+	rules []rule
+}
+
+var (
+	structFields sync.Map // reflect.Type to []field
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// ValidateStruct validates the struct, or pointer to one, using the rules in
+// its fields' validate tags. It returns a *ValidationError listing every
+// failed field, or an error if a tag is invalid.
+func ValidateStruct(v interface{}) error {
+	errs := &ValidationError{}
+	if err := validateValue(reflect.ValueOf(v), "", errs); err != nil {
+		return err
+	}
+	return errs.ErrorOrNil()
+}
+
+func validateValue(v reflect.Value, path string, errs *ValidationError) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return validateValue(v.Elem(), path, errs)
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return nil
+		}
+		fields, err := getFields(v.Type())
+		if err != nil {
+			return err
+		}
+		for _, f := range fields {
+			fv := v.Field(f.index)
+			fpath := joinField(path, f.name)
+			for _, r := range f.rules {
+				if msg := r.check(fv); msg != "" {
+					errs.Errors = append(errs.Errors, FieldError{Field: fpath, Rule: r.name, Message: msg})
+				}
+			}
+			if err := validateValue(fv, fpath, errs); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if !hasStructs(v.Type().Elem()) {
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if !hasStructs(v.Type().Elem()) {
+			return nil
+		}
+		for _, key := range v.MapKeys() {
+			if err := validateValue(v.MapIndex(key), joinField(path, fmt.Sprint(key.Interface())), errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hasStructs gets whether values of the type may be structs to validate
+func hasStructs(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return (t.Kind() == reflect.Struct && t != timeType) || t.Kind() == reflect.Interface ||
+		t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map
+}
+
+// joinField joins a field to its parent's path
+func joinField(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	if name == "" || strings.HasPrefix(name, "[") {
+		return path + name
+	}
+	return path + "." + name
+}
+
+// getFields gets the exported fields of the struct type with their rules
+func getFields(t reflect.Type) ([]field, error) {
+	if fields, ok := structFields.Load(t); ok {
+		return fields.([]field), nil
+	}
+
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get(ValidateTag)
+		if sf.PkgPath != "" || tag == "-" {
+			continue
+		}
+		rules, err := parseRules(tag)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid validate tag on %s.%s", t.Name(), sf.Name)
+		}
+		fields = append(fields, field{i, bsonName(sf), rules})
+	}
+	structFields.Store(t, fields)
+	return fields, nil
+}
+
+// bsonName gets the name the field is stored as, or an empty string for
+// inlined structs
+func bsonName(sf reflect.StructField) string {
+	parts := strings.Split(sf.Tag.Get("bson"), ",")
+	for _, opt := range parts[1:] {
+		if opt == "inline" {
+			return ""
+		}
+	}
+	name := parts[0]
+	if name == "" || name == "-" {
+		return strings.ToLower(sf.Name)
+	}
+	return name
+}
+
+// parseRules parses the rules of a validate tag
+func parseRules(tag string) ([]rule, error) {
+	var rules []rule
+	for tag != "" {
+		var part string
+		if strings.HasPrefix(tag, "regex=") {
+			part, tag = tag, ""
+		} else if i := strings.Index(tag, ","); i >= 0 {
+			part, tag = tag[:i], tag[i+1:]
+		} else {
+			part, tag = tag, ""
+		}
+
+		r := rule{name: part}
+		var arg string
+		if i := strings.Index(part, "="); i >= 0 {
+			r.name, arg = part[:i], part[i+1:]
+		}
+		var err error
+		switch r.name {
+		case "required":
+		case "min", "max":
+			r.num, err = strconv.ParseFloat(arg, 64)
+		case "enum":
+			r.enum = strings.Split(arg, "|")
+		case "regex":
+			r.regex, err = regexp.Compile(arg)
+		default:
+			err = errors.Errorf("unknown rule '%s'", r.name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// check gets why the value fails the rule, or an empty string if it passes
+func (r rule) check(v reflect.Value) string {
+	if r.name == "required" {
+		if isEmpty(v) {
+			return "is required"
+		}
+		return ""
+	}
+
+	// Other rules don't apply to missing values
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return ""
+	}
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	switch r.name {
+	case "min", "max":
+		n, isLen := size(v)
+		if r.name == "min" && n < r.num {
+			return fmt.Sprintf("must be at least %s%s", formatNum(r.num), lengthUnit(isLen))
+		}
+		if r.name == "max" && n > r.num {
+			return fmt.Sprintf("must be at most %s%s", formatNum(r.num), lengthUnit(isLen))
+		}
+	case "enum":
+		s := fmt.Sprint(v.Interface())
+		for _, e := range r.enum {
+			if s == e {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(r.enum, ", ")
+	case "regex":
+		if v.Kind() == reflect.String && !r.regex.MatchString(v.String()) {
+			return "must match " + r.regex.String()
+		}
+	}
+	return ""
+}
+
+// isEmpty gets whether the value is zero, or an empty slice or map
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// size gets the value of a number, or the length of anything else
+func size(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false
+	case reflect.String:
+		return float64(len([]rune(v.String()))), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	}
+	return 0, false
+}
+
+func lengthUnit(isLen bool) string {
+	if isLen {
+		return " long"
+	}
+	return ""
+}
+
+func formatNum(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
@@ -0,0 +1,179 @@
+package base_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/mongotest"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type address struct {
+	City     string `bson:"city" validate:"required"`
+	Postcode string `bson:"postcode" validate:"regex=^[A-Z0-9]{2,4} [0-9][A-Z]{2}$"`
+}
+
+type lineItem struct {
+	SKU string `bson:"sku" validate:"required"`
+	Qty int    `bson:"qty" validate:"min=1,max=100"`
+}
+
+type order struct {
+	Name      string               `bson:"name" validate:"required,max=10"`
+	Status    string               `bson:"status" validate:"enum=new|paid|shipped"`
+	Priority  int                  `bson:"priority" validate:"enum=1|2|3"`
+	Discount  *float64             `bson:"discount,omitempty" validate:"min=0,max=0.5"`
+	Tags      []string             `bson:"tags" validate:"max=2"`
+	Address   address              `bson:"address"`
+	Billing   *address             `bson:"billing,omitempty"`
+	Items     []lineItem           `bson:"items" validate:"required"`
+	Extra     map[string]*lineItem `bson:"extra"`
+	CreatedAt time.Time            `bson:"createdAt"`
+	Skipped   lineItem             `bson:"skipped" validate:"-"`
+}
+
+func validOrder() order {
+	return order{
+		Name:     "order",
+		Status:   "new",
+		Priority: 1,
+		Address:  address{City: "London", Postcode: "SW1A 1AA"},
+		Items:    []lineItem{{SKU: "a", Qty: 1}},
+	}
+}
+
+func discount(f float64) *float64 {
+	return &f
+}
+
+var validateStructTests = map[string]struct {
+	modify   func(o *order)
+	expected []base.FieldError
+}{
+	"valid": {func(o *order) {}, nil},
+	"required": {
+		func(o *order) { o.Name = ""; o.Items = nil },
+		[]base.FieldError{
+			{Field: "name", Rule: "required", Message: "is required"},
+			{Field: "items", Rule: "required", Message: "is required"},
+		},
+	},
+	"min and max": {
+		func(o *order) {
+			o.Name = "a long order name"
+			o.Tags = []string{"a", "b", "c"}
+			o.Discount = discount(0.75)
+		},
+		[]base.FieldError{
+			{Field: "name", Rule: "max", Message: "must be at most 10 long"},
+			{Field: "discount", Rule: "max", Message: "must be at most 0.5"},
+			{Field: "tags", Rule: "max", Message: "must be at most 2 long"},
+		},
+	},
+	"enum": {
+		func(o *order) { o.Status = "lost"; o.Priority = 4 },
+		[]base.FieldError{
+			{Field: "status", Rule: "enum", Message: "must be one of new, paid, shipped"},
+			{Field: "priority", Rule: "enum", Message: "must be one of 1, 2, 3"},
+		},
+	},
+	"nested": {
+		func(o *order) {
+			o.Address.City = ""
+			o.Billing = &address{City: "Leeds", Postcode: "nope"}
+			o.Items = append(o.Items, lineItem{SKU: "b", Qty: 0})
+			o.Extra = map[string]*lineItem{"gift": {Qty: 1}}
+		},
+		[]base.FieldError{
+			{Field: "address.city", Rule: "required", Message: "is required"},
+			{Field: "billing.postcode", Rule: "regex", Message: "must match ^[A-Z0-9]{2,4} [0-9][A-Z]{2}$"},
+			{Field: "items[1].qty", Rule: "min", Message: "must be at least 1"},
+			{Field: "extra.gift.sku", Rule: "required", Message: "is required"},
+		},
+	},
+	"skipped": {func(o *order) { o.Skipped = lineItem{Qty: -1} }, nil},
+}
+
+func Test_ValidateStruct(t *testing.T) {
+	for tn, tt := range validateStructTests {
+		o := validOrder()
+		tt.modify(&o)
+
+		err := base.ValidateStruct(&o)
+
+		if tt.expected == nil {
+			assert.Nilf(t, err, "Expected nil err on test '%s'", tn)
+			continue
+		}
+		var ve *base.ValidationError
+		if assert.Truef(t, errors.As(err, &ve), "Expected a validation error on test '%s'", tn) {
+			assert.Equalf(t, tt.expected, ve.Errors, "Expected the field errors to match on test '%s'", tn)
+		}
+		assert.Truef(t, errors.Is(err, base.ErrValidation), "Expected an ErrValidation on test '%s'", tn)
+	}
+}
+
+func Test_ValidateStruct_Message(t *testing.T) {
+	o := validOrder()
+	o.Name = ""
+	o.Status = "lost"
+
+	err := base.ValidateStruct(o)
+
+	assert.EqualError(t, err, "validation failed: name is required; status must be one of new, paid, shipped")
+}
+
+func Test_ValidateStruct_InvalidTag(t *testing.T) {
+	err := base.ValidateStruct(struct {
+		Num int `validate:"min=one"`
+	}{})
+	assert.EqualError(t, err, `invalid validate tag on .Num: strconv.ParseFloat: parsing "one": invalid syntax`)
+	assert.False(t, errors.Is(err, base.ErrValidation), "Expected invalid tags not to be validation errors")
+
+	err = base.ValidateStruct(struct {
+		Num int `validate:"positive"`
+	}{})
+	assert.EqualError(t, err, "invalid validate tag on .Num: unknown rule 'positive'")
+
+	assert.Nil(t, base.ValidateStruct(bson.M{"name": ""}), "Expected documents without tags to be valid")
+}
+
+func Test_ValidationError_Add(t *testing.T) {
+	errs := &base.ValidationError{}
+	assert.Nil(t, errs.ErrorOrNil())
+
+	o := validOrder()
+	o.Name = ""
+	errs.Add("items[2]", base.ValidateStruct(o))
+	errs.Add("items[3]", errors.New("bad item"))
+
+	assert.Equal(t, []base.FieldError{
+		{Field: "items[2].name", Rule: "required", Message: "is required"},
+		{Field: "items[3]", Rule: "invalid", Message: "bad item"},
+	}, errs.Errors)
+	assert.Equal(t, errs, errs.ErrorOrNil())
+}
+
+func Test_InsertOne_Validation(t *testing.T) {
+	ctx := context.Background()
+	db := mongotest.New(t)
+
+	o := validOrder()
+	o.Items[0].Qty = 0
+	_, err := db.Helper.InsertOne(ctx, "orders", &o)
+	assert.EqualError(t, err, "validation failed: items[0].qty must be at least 1")
+	assert.True(t, errors.Is(err, base.ErrValidation))
+	mongotest.AssertCount(t, db, "orders", bson.M{}, 0, "Expected invalid documents not to be written")
+
+	o.Items[0].Qty = 1
+	_, err = db.Helper.InsertOne(ctx, "orders", &o)
+	assert.Nil(t, err)
+
+	o.Status = "lost"
+	err = db.Helper.UpdateOne(ctx, "orders", bson.M{"name": "order"}, &o)
+	assert.True(t, errors.Is(err, base.ErrValidation), "Expected UpdateOne to validate")
+}
@@ -0,0 +1,136 @@
+package base
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DefaultVersionField is the document field holding the version of versioned models
+const DefaultVersionField = "version"
+
+// ErrVersionConflict indicates that a versioned model was changed by someone
+// else since it was read. It's an ErrConflict.
+var ErrVersionConflict error = &kindError{ErrConflict, errors.New("document version has changed")}
+
+// VersionedModel is a model using optimistic locking. InsertOne sets the
+// version of new models to 1, and UpdateOne and BulkWrite replacements only
+// replace the document if its version is unchanged, incrementing it. The
+// version must be stored in the helper's VersionField.
+type VersionedModel interface {
+	GetVersion() int64
+	SetVersion(int64)
+}
+
+// versionField gets the field holding the version of versioned models
+func (h *helper) versionField() string {
+	if h.opts.VersionField != "" {
+		return h.opts.VersionField
+	}
+	return DefaultVersionField
+}
+
+// versionFilter adds the expected version to the filter. Documents written
+// before the model was versioned have no version, so match version 0.
+func (h *helper) versionFilter(filter interface{}, version int64) interface{} {
+	var match interface{} = version
+	if version == 0 {
+		match = bson.M{"$in": bson.A{0, nil}}
+	}
+	return bson.M{"$and": bson.A{filter, bson.M{h.versionField(): match}}}
+}
+
+// updateVersioned replaces the document matching the filter and the model's
+// version, incrementing the version
+func (h *helper) updateVersioned(ctx context.Context, coll string, filter interface{}, m VersionedModel) error {
+	c := h.db.Collection(coll)
+	version := m.GetVersion()
+	m.SetVersion(version + 1)
+
+	res, err := c.ReplaceOne(ctx, h.versionFilter(filter, version), m)
+	if err == nil && res.MatchedCount == 0 {
+		// Tell a changed version from a missing document
+		var n int64
+		n, err = c.CountDocuments(ctx, filter)
+		if err == nil {
+			err = ErrNoMatches
+			if n > 0 {
+				err = ErrVersionConflict
+			}
+		}
+	}
+	if err != nil {
+		m.SetVersion(version)
+	}
+	return MapError(err)
+}
+
+// versionedWrite is a replacement of a versioned model in a bulk write
+type versionedWrite struct {
+	index  int
+	filter interface{}
+	model  VersionedModel
+}
+
+// versionReplace copies a replacement of a versioned model to only match the
+// document at the model's version, incrementing it. A stale replacement
+// matches nothing, or fails as a duplicate if upserting.
+func (h *helper) versionReplace(m *mongo.ReplaceOneModel, vm VersionedModel) *mongo.ReplaceOneModel {
+	version := vm.GetVersion()
+	vm.SetVersion(version + 1)
+
+	replace := *m
+	replace.Filter = h.versionFilter(m.Filter, version)
+	return &replace
+}
+
+// checkVersions resets the versions of the bulk replacements that weren't
+// written, and gets ErrVersionConflict if any matched nothing as their
+// document's version had changed, or ErrNoMatches if it's gone. Unless the
+// error is a bulk write exception, none were written.
+func (h *helper) checkVersions(ctx context.Context, c MongoCollection, writes []versionedWrite, err error) error {
+	var bwe mongo.BulkWriteException
+	failed := make(map[int]bool)
+	partial := errors.As(err, &bwe)
+	if partial {
+		for _, we := range bwe.WriteErrors {
+			failed[we.Index] = true
+		}
+	}
+
+	var mismatch error
+	for _, w := range writes {
+		if err != nil && (!partial || failed[w.index]) {
+			w.model.SetVersion(w.model.GetVersion() - 1)
+			continue
+		}
+
+		// Replacements that matched left their document equal to the model
+		var doc bson.D
+		b, cerr := bson.Marshal(w.model)
+		if cerr == nil {
+			cerr = bson.Unmarshal(b, &doc)
+		}
+		var n int64
+		if cerr == nil {
+			n, cerr = c.CountDocuments(ctx, bson.D{{Key: "$and", Value: bson.A{w.filter, doc}}})
+		}
+		if cerr != nil {
+			return cerr
+		} else if n > 0 {
+			continue
+		}
+		w.model.SetVersion(w.model.GetVersion() - 1)
+		if mismatch != ErrVersionConflict {
+			mismatch = ErrNoMatches
+			if n, cerr = c.CountDocuments(ctx, w.filter); cerr != nil {
+				return cerr
+			} else if n > 0 {
+				mismatch = ErrVersionConflict
+			}
+		}
+	}
+	return mismatch
+}
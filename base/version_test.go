@@ -0,0 +1,143 @@
+package base_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/mongotest"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type versionedModel struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty"`
+	Str     string             `bson:"str"`
+	Version int64              `bson:"version"`
+}
+
+func (m *versionedModel) GetVersion() int64 {
+	return m.Version
+}
+
+func (m *versionedModel) SetVersion(v int64) {
+	m.Version = v
+}
+
+func Test_UpdateOne_Versioned(t *testing.T) {
+	ctx := context.Background()
+	db := mongotest.New(t)
+	h := db.Helper
+
+	item := &versionedModel{Str: "a"}
+	id, err := h.InsertOne(ctx, "test", item)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), item.Version, "Expected new models to start at version 1")
+	item.ID = id.(primitive.ObjectID)
+
+	// Two editors read the same version
+	var first, second versionedModel
+	h.FindOne(ctx, "test", bson.M{"_id": item.ID}, &first)
+	h.FindOne(ctx, "test", bson.M{"_id": item.ID}, &second)
+
+	first.Str = "b"
+	err = h.UpdateOne(ctx, "test", bson.M{"_id": item.ID}, &first)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), first.Version, "Expected the version to be incremented")
+	mongotest.AssertDocumentExists(t, db, "test", bson.M{"_id": item.ID, "str": "b", "version": 2})
+
+	second.Str = "c"
+	err = h.UpdateOne(ctx, "test", bson.M{"_id": item.ID}, &second)
+	assert.Equal(t, base.ErrVersionConflict, err)
+	assert.True(t, errors.Is(err, base.ErrConflict), "Expected a conflict error")
+	assert.Equal(t, int64(1), second.Version, "Expected the version to be kept on conflict")
+	mongotest.AssertDocumentExists(t, db, "test", bson.M{"_id": item.ID, "str": "b", "version": 2})
+
+	// Missing documents aren't conflicts
+	err = h.UpdateOne(ctx, "test", bson.M{"_id": primitive.NewObjectID()}, &versionedModel{Version: 1})
+	assert.Equal(t, base.ErrNoMatches, err)
+}
+
+func Test_BulkWrite_Versioned(t *testing.T) {
+	ctx := context.Background()
+	db := mongotest.New(t)
+	h := db.Helper
+
+	item := &versionedModel{Str: "a"}
+	id, err := h.InsertOne(ctx, "test", item)
+	assert.Nil(t, err)
+	filter := bson.M{"_id": id}
+
+	var first, second versionedModel
+	h.FindOne(ctx, "test", filter, &first)
+	h.FindOne(ctx, "test", filter, &second)
+
+	first.Str = "b"
+	model := mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(&first)
+	res, err := h.BulkWrite(ctx, "test", []mongo.WriteModel{model})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), res.ModifiedCount)
+	assert.Equal(t, int64(2), first.Version, "Expected the version to be incremented")
+	assert.Equal(t, filter, model.Filter, "Expected the model's filter to be kept")
+	mongotest.AssertDocumentExists(t, db, "test", bson.M{"_id": id, "str": "b", "version": 2})
+
+	// A stale replacement matches nothing and conflicts
+	second.Str = "c"
+	other := &versionedModel{Str: "d"}
+	res, err = h.BulkWrite(ctx, "test", []mongo.WriteModel{
+		mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(&second),
+		mongo.NewReplaceOneModel().SetFilter(bson.M{"str": "d"}).SetReplacement(other).SetUpsert(true),
+	})
+	assert.Equal(t, base.ErrVersionConflict, err)
+	assert.Equal(t, int64(1), res.UpsertedCount, "Expected the other writes to be made")
+	assert.Equal(t, int64(1), second.Version, "Expected the version to be kept on conflict")
+	assert.Equal(t, int64(1), other.Version, "Expected the upserted model's version to be incremented")
+	mongotest.AssertDocumentExists(t, db, "test", bson.M{"_id": id, "str": "b", "version": 2})
+
+	// Missing documents aren't conflicts
+	_, err = h.BulkWrite(ctx, "test", []mongo.WriteModel{
+		mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": primitive.NewObjectID()}).SetReplacement(&versionedModel{Version: 1}),
+	})
+	assert.Equal(t, base.ErrNoMatches, err)
+
+	// A stale upsert would insert a duplicate
+	_, err = h.BulkWrite(ctx, "test", []mongo.WriteModel{
+		mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(&second).SetUpsert(true),
+	})
+	assert.True(t, errors.Is(err, base.ErrDuplicateKey), "Expected a duplicate key error")
+	assert.Equal(t, int64(1), second.Version, "Expected the version to be kept when the write fails")
+	mongotest.AssertDocumentExists(t, db, "test", bson.M{"_id": id, "str": "b", "version": 2})
+}
+
+func Test_UpdateOne_VersionedLegacy(t *testing.T) {
+	ctx := context.Background()
+	db := mongotest.New(t)
+	h := base.NewHelperWithOptions(db, base.HelperOptions{VersionField: "rev"})
+	db.Collection("test").InsertOne(ctx, bson.M{"_id": 1, "str": "a"})
+
+	// Documents written before versioning have no version
+	err := h.UpdateOne(ctx, "test", bson.M{"_id": 1}, &legacyModel{ID: 1, Str: "b"})
+	assert.Nil(t, err)
+	mongotest.AssertDocumentExists(t, db, "test", bson.M{"_id": 1, "str": "b", "rev": 1})
+
+	err = h.UpdateOne(ctx, "test", bson.M{"_id": 1}, &legacyModel{ID: 1, Str: "c"})
+	assert.Equal(t, base.ErrVersionConflict, err, "Expected the stale version 0 to conflict")
+}
+
+// legacyModel stores its version in the rev field
+type legacyModel struct {
+	ID  int    `bson:"_id"`
+	Str string `bson:"str"`
+	Rev int64  `bson:"rev"`
+}
+
+func (m *legacyModel) GetVersion() int64 {
+	return m.Rev
+}
+
+func (m *legacyModel) SetVersion(v int64) {
+	m.Rev = v
+}
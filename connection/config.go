@@ -0,0 +1,303 @@
+package connection
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+)
+
+// Config represents the settings of a connection. Zero values are left to the
+// URI or the driver's defaults.
+type Config struct {
+	URI string `yaml:"uri"`
+	// URIFile is read for the URI when URI isn't set
+	URIFile string `yaml:"uriFile"`
+	// Database is the name of the database the manager gets
+	Database string `yaml:"database"`
+	// Username and Password are used instead of the URI's credentials. They
+	// can be read from files, such as Docker and Kubernetes secrets.
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	UsernameFile string `yaml:"usernameFile"`
+	PasswordFile string `yaml:"passwordFile"`
+	AuthSource   string `yaml:"authSource"`
+	AppName      string `yaml:"appName"`
+	MinPoolSize  uint64 `yaml:"minPoolSize"`
+	MaxPoolSize  uint64 `yaml:"maxPoolSize"`
+	// ConnectTimeout is the timeout of opening a connection to a server
+	ConnectTimeout time.Duration `yaml:"connectTimeout"`
+	// ServerSelectionTimeout is how long an operation waits for a suitable server
+	ServerSelectionTimeout time.Duration `yaml:"serverSelectionTimeout"`
+	// SocketTimeout is the timeout of reads and writes on a connection
+	SocketTimeout time.Duration `yaml:"socketTimeout"`
+	// ReadConcern is the read concern level, such as "majority"
+	ReadConcern string `yaml:"readConcern"`
+	// ReadPreference is the read preference mode, such as "secondaryPreferred"
+	ReadPreference string             `yaml:"readPreference"`
+	WriteConcern   WriteConcernConfig `yaml:"writeConcern"`
+	TLS            TLSConfig          `yaml:"tls"`
+}
+
+// WriteConcernConfig represents the settings of the write concern
+type WriteConcernConfig struct {
+	// W is "majority", a tag set name or the number of members to acknowledge writes
+	W       string        `yaml:"w"`
+	Journal bool          `yaml:"journal"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// TLSConfig represents the TLS settings. TLS is used when it's enabled or any
+// of the files are set.
+type TLSConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// CAFile is the PEM encoded certificate authorities to verify the server with
+	CAFile string `yaml:"caFile"`
+	// CertFile and KeyFile are the PEM encoded client certificate and key
+	CertFile           string `yaml:"certFile"`
+	KeyFile            string `yaml:"keyFile"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
+}
+
+func (c TLSConfig) enabled() bool {
+	return c.Enabled || c.CAFile != "" || c.CertFile != "" || c.KeyFile != ""
+}
+
+// ClientOptions gets the driver's client options for the config
+func (c Config) ClientOptions() (*options.ClientOptions, error) {
+	uri, err := c.uri()
+	if err != nil {
+		return nil, err
+	}
+	opts := options.Client().ApplyURI(uri)
+
+	if c.Username != "" || c.UsernameFile != "" {
+		cred, err := c.credential(opts.Auth)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetAuth(cred)
+	}
+	if c.AppName != "" {
+		opts.SetAppName(c.AppName)
+	}
+	if c.MinPoolSize > 0 {
+		opts.SetMinPoolSize(c.MinPoolSize)
+	}
+	if c.MaxPoolSize > 0 {
+		opts.SetMaxPoolSize(c.MaxPoolSize)
+	}
+	if c.ConnectTimeout > 0 {
+		opts.SetConnectTimeout(c.ConnectTimeout)
+	}
+	if c.ServerSelectionTimeout > 0 {
+		opts.SetServerSelectionTimeout(c.ServerSelectionTimeout)
+	}
+	if c.SocketTimeout > 0 {
+		opts.SetSocketTimeout(c.SocketTimeout)
+	}
+	if c.ReadConcern != "" {
+		opts.SetReadConcern(readconcern.New(readconcern.Level(c.ReadConcern)))
+	}
+	if c.ReadPreference != "" {
+		mode, err := readpref.ModeFromString(c.ReadPreference)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid read preference")
+		}
+		rp, err := readpref.New(mode)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid read preference")
+		}
+		opts.SetReadPreference(rp)
+	}
+	if wc := c.WriteConcern.writeConcern(); wc != nil {
+		opts.SetWriteConcern(wc)
+	}
+	if c.TLS.enabled() {
+		tlsConfig, err := c.TLS.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// uri gets the URI, reading it from URIFile if it isn't set
+func (c Config) uri() (string, error) {
+	if c.URI != "" {
+		return c.URI, nil
+	}
+	if c.URIFile != "" {
+		return readSecret(c.URIFile, "URI")
+	}
+	return "", errors.New("no URI in connection config")
+}
+
+// credential gets the configured credentials, keeping the other auth
+// settings from the URI
+func (c Config) credential(auth *options.Credential) (options.Credential, error) {
+	var cred options.Credential
+	if auth != nil {
+		cred = *auth
+	}
+
+	var err error
+	cred.Username = c.Username
+	if c.UsernameFile != "" {
+		if cred.Username, err = readSecret(c.UsernameFile, "username"); err != nil {
+			return cred, err
+		}
+	}
+	cred.Password = c.Password
+	if c.PasswordFile != "" {
+		if cred.Password, err = readSecret(c.PasswordFile, "password"); err != nil {
+			return cred, err
+		}
+	}
+	cred.PasswordSet = cred.Password != ""
+	if c.AuthSource != "" {
+		cred.AuthSource = c.AuthSource
+	}
+	return cred, nil
+}
+
+// readSecret reads a secret from a file, without any trailing newline
+func readSecret(path string, name string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read %s file", name)
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// Validate checks the config, returning every problem found
+func (c Config) Validate() error {
+	var errs *multierror.Error
+
+	if c.URI == "" && c.URIFile == "" {
+		errs = multierror.Append(errs, errors.New("no URI in connection config"))
+	} else if c.URI != "" {
+		if _, err := connstring.Parse(c.URI); err != nil {
+			errs = multierror.Append(errs, errors.New(redactURI(err.Error())))
+		}
+	}
+	if (c.Password != "" || c.PasswordFile != "") && c.Username == "" && c.UsernameFile == "" {
+		errs = multierror.Append(errs, errors.New("password set without a username"))
+	}
+	if c.MinPoolSize > 0 && c.MaxPoolSize > 0 && c.MinPoolSize > c.MaxPoolSize {
+		errs = multierror.Append(errs, errors.Errorf("min pool size %d is greater than max pool size %d", c.MinPoolSize, c.MaxPoolSize))
+	}
+	if c.ReadPreference != "" {
+		if _, err := readpref.ModeFromString(c.ReadPreference); err != nil {
+			errs = multierror.Append(errs, errors.Wrap(err, "invalid read preference"))
+		}
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		errs = multierror.Append(errs, errors.New("TLS cert file and key file must be set together"))
+	}
+	for _, f := range []struct{ name, path string }{
+		{"URI", c.URIFile},
+		{"username", c.UsernameFile},
+		{"password", c.PasswordFile},
+		{"TLS CA", c.TLS.CAFile},
+		{"TLS cert", c.TLS.CertFile},
+		{"TLS key", c.TLS.KeyFile},
+	} {
+		if f.path == "" {
+			continue
+		}
+		if _, err := os.Stat(f.path); err != nil {
+			errs = multierror.Append(errs, errors.Wrapf(err, "invalid %s file", f.name))
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// plainConfig is a Config without the String method
+type plainConfig Config
+
+// String gets the config with the password and the URI's credentials redacted
+func (c Config) String() string {
+	c.URI = redactURI(c.URI)
+	if c.Password != "" {
+		c.Password = redacted
+	}
+	return fmt.Sprintf("%+v", plainConfig(c))
+}
+
+// redacted replaces secrets in strings
+const redacted = "xxxxx"
+
+var uriPasswordPattern = regexp.MustCompile(`([a-z+]+://[^:/@\s]*:)[^@\s]*@`)
+
+// redactURI replaces the password of any URIs in the string
+func redactURI(s string) string {
+	return uriPasswordPattern.ReplaceAllString(s, "${1}"+redacted+"@")
+}
+
+// writeConcern gets the driver's write concern, or nil if none is configured
+func (c WriteConcernConfig) writeConcern() *writeconcern.WriteConcern {
+	var opts []writeconcern.Option
+	if c.W == "majority" {
+		opts = append(opts, writeconcern.WMajority())
+	} else if w, err := strconv.Atoi(c.W); err == nil {
+		opts = append(opts, writeconcern.W(w))
+	} else if c.W != "" {
+		opts = append(opts, writeconcern.WTagSet(c.W))
+	}
+	if c.Journal {
+		opts = append(opts, writeconcern.J(true))
+	}
+	if c.Timeout > 0 {
+		opts = append(opts, writeconcern.WTimeout(c.Timeout))
+	}
+	if len(opts) == 0 {
+		return nil
+	}
+	return writeconcern.New(opts...)
+}
+
+// tlsConfig loads the certificates into a TLS config
+func (c TLSConfig) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify}
+
+	if c.CAFile != "" {
+		pem, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read TLS CA file")
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificates found in TLS CA file '%s'", c.CAFile)
+		}
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load TLS client certificate")
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
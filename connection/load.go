@@ -0,0 +1,156 @@
+package connection
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultEnvPrefix is the prefix of the environment variables read by FromEnv
+const DefaultEnvPrefix = "MONGO"
+
+// FromEnv gets the config from environment variables named with the prefix,
+// or DefaultEnvPrefix if it's empty, such as MONGO_URI, MONGO_DB,
+// MONGO_PASSWORD_FILE and MONGO_MAX_POOL_SIZE
+func FromEnv(prefix string) (Config, error) {
+	return fromLookup(prefix, os.LookupEnv)
+}
+
+// fromLookup gets the config from the variables found by lookup
+func fromLookup(prefix string, lookup func(string) (string, bool)) (Config, error) {
+	if prefix == "" {
+		prefix = DefaultEnvPrefix
+	}
+
+	var c Config
+	vars := []struct {
+		name  string
+		parse func(string) error
+	}{
+		{"URI", stringVar(&c.URI)},
+		{"URI_FILE", stringVar(&c.URIFile)},
+		{"DB", stringVar(&c.Database)},
+		{"USERNAME", stringVar(&c.Username)},
+		{"USERNAME_FILE", stringVar(&c.UsernameFile)},
+		{"PASSWORD", stringVar(&c.Password)},
+		{"PASSWORD_FILE", stringVar(&c.PasswordFile)},
+		{"AUTH_SOURCE", stringVar(&c.AuthSource)},
+		{"APP_NAME", stringVar(&c.AppName)},
+		{"MIN_POOL_SIZE", uintVar(&c.MinPoolSize)},
+		{"MAX_POOL_SIZE", uintVar(&c.MaxPoolSize)},
+		{"CONNECT_TIMEOUT", durationVar(&c.ConnectTimeout)},
+		{"SERVER_SELECTION_TIMEOUT", durationVar(&c.ServerSelectionTimeout)},
+		{"SOCKET_TIMEOUT", durationVar(&c.SocketTimeout)},
+		{"READ_CONCERN", stringVar(&c.ReadConcern)},
+		{"READ_PREFERENCE", stringVar(&c.ReadPreference)},
+		{"WRITE_CONCERN", stringVar(&c.WriteConcern.W)},
+		{"WRITE_JOURNAL", boolVar(&c.WriteConcern.Journal)},
+		{"WRITE_TIMEOUT", durationVar(&c.WriteConcern.Timeout)},
+		{"TLS", boolVar(&c.TLS.Enabled)},
+		{"TLS_CA_FILE", stringVar(&c.TLS.CAFile)},
+		{"TLS_CERT_FILE", stringVar(&c.TLS.CertFile)},
+		{"TLS_KEY_FILE", stringVar(&c.TLS.KeyFile)},
+		{"TLS_INSECURE", boolVar(&c.TLS.InsecureSkipVerify)},
+	}
+
+	var errs *multierror.Error
+	for _, v := range vars {
+		name := prefix + "_" + v.name
+		value, ok := lookup(name)
+		if !ok || value == "" {
+			continue
+		}
+		if err := v.parse(value); err != nil {
+			errs = multierror.Append(errs, errors.Wrapf(err, "invalid %s", name))
+		}
+	}
+	if err := c.Validate(); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	return c, errs.ErrorOrNil()
+}
+
+func stringVar(p *string) func(string) error {
+	return func(v string) error {
+		*p = v
+		return nil
+	}
+}
+
+func uintVar(p *uint64) func(string) error {
+	return func(v string) (err error) {
+		*p, err = strconv.ParseUint(v, 10, 64)
+		return
+	}
+}
+
+func durationVar(p *time.Duration) func(string) error {
+	return func(v string) (err error) {
+		*p, err = time.ParseDuration(v)
+		return
+	}
+}
+
+func boolVar(p *bool) func(string) error {
+	return func(v string) (err error) {
+		*p, err = strconv.ParseBool(v)
+		return
+	}
+}
+
+// LoadFile gets the config from a YAML or JSON file. Durations are written
+// as strings such as "5s".
+func LoadFile(path string) (Config, error) {
+	var c Config
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return c, errors.Wrap(err, "failed to read connection config")
+	}
+	// YAML is a superset of JSON so it reads either
+	if err := yaml.UnmarshalStrict(b, &c); err != nil {
+		return c, errors.Wrapf(err, "failed to parse connection config '%s'", path)
+	}
+	return c, c.Validate()
+}
+
+// FromURI gets the config for the URI, with the database from its path
+func FromURI(uri string) (Config, error) {
+	c := Config{URI: uri}
+	if err := c.Validate(); err != nil {
+		return c, err
+	}
+	cs, _ := connstring.Parse(uri)
+	c.Database = cs.Database
+	return c, nil
+}
+
+// Open connects with the config, getting the client and the configured database
+func Open(ctx context.Context, cfg Config) (base.MongoClient, base.MongoDB, error) {
+	return OpenWithOptions(ctx, cfg, ManagerOptions{})
+}
+
+// OpenWithOptions connects with the config using the given manager options,
+// getting the client and the configured database
+func OpenWithOptions(ctx context.Context, cfg Config, opts ManagerOptions) (base.MongoClient, base.MongoDB, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if cfg.Database == "" {
+		return nil, nil, errors.New("no database in connection config")
+	}
+
+	m := NewManagerWithOptions(cfg, opts)
+	if err := m.Connect(ctx); err != nil {
+		return nil, nil, err
+	}
+	return m.Client(), m.Database(), nil
+}
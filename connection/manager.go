@@ -0,0 +1,197 @@
+// Package connection manages the lifecycle of a MongoDB client: connecting
+// with retries, health checks for readiness probes and disconnecting on
+// shutdown.
+package connection
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrNotConnected indicates that the manager has no connected client
+var ErrNotConnected = errors.New("not connected to MongoDB")
+
+// ManagerOptions represents options for the manager
+type ManagerOptions struct {
+	// Attempts is the number of times to try connecting. Defaults to 5.
+	Attempts int
+	// InitialBackoff is the wait after the first failed attempt, doubling
+	// after each attempt up to MaxBackoff. Defaults to 100ms and 10s.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// HealthTimeout is the timeout of the ping made by HealthHandler. Defaults to 2s.
+	HealthTimeout time.Duration
+	// PoolMonitor receives the connection pool events of the client
+	PoolMonitor *event.PoolMonitor
+	// NewClient creates the client from the options. Defaults to the driver's client.
+	NewClient func(opts *options.ClientOptions) (base.MongoClient, error)
+}
+
+// Manager connects to MongoDB and keeps the client for the life of the service
+type Manager interface {
+	// Connect connects and pings the deployment, retrying with backoff
+	Connect(ctx context.Context) error
+	// Client gets the connected client, or nil if not connected
+	Client() base.MongoClient
+	// Database gets the configured database, or nil if not connected
+	Database() base.MongoDB
+	// Health pings the deployment
+	Health(ctx context.Context) error
+	// HealthHandler gets an HTTP handler for readiness probes, responding
+	// 200 when the ping succeeds and 503 when it fails
+	HealthHandler() http.Handler
+	// Disconnect closes the client's connections
+	Disconnect(ctx context.Context) error
+}
+
+type manager struct {
+	cfg  Config
+	opts ManagerOptions
+
+	// connectMu serialises Connect and Disconnect, mu guards the client
+	connectMu sync.Mutex
+	mu        sync.RWMutex
+	client    base.MongoClient
+}
+
+func (m *manager) Connect(ctx context.Context) error {
+	m.connectMu.Lock()
+	defer m.connectMu.Unlock()
+	if m.Client() != nil {
+		return nil
+	}
+
+	clientOpts, err := m.cfg.ClientOptions()
+	if err != nil {
+		return err
+	}
+	if m.opts.PoolMonitor != nil {
+		clientOpts.SetPoolMonitor(m.opts.PoolMonitor)
+	}
+
+	backoff := m.opts.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		var client base.MongoClient
+		client, err = m.opts.NewClient(clientOpts)
+		if err != nil {
+			// The options are invalid so retrying won't help
+			return err
+		}
+		if err = client.Connect(ctx); err == nil {
+			if err = client.Ping(ctx, nil); err == nil {
+				m.mu.Lock()
+				m.client = client
+				m.mu.Unlock()
+				return nil
+			}
+			client.Disconnect(ctx)
+		}
+		if attempt >= m.opts.Attempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(err, "connection cancelled after %d attempts", attempt)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > m.opts.MaxBackoff {
+			backoff = m.opts.MaxBackoff
+		}
+	}
+
+	return errors.Wrapf(err, "failed to connect after %d attempts", m.opts.Attempts)
+}
+
+func (m *manager) Client() base.MongoClient {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.client
+}
+
+func (m *manager) Database() base.MongoDB {
+	client := m.Client()
+	if client == nil {
+		return nil
+	}
+	return client.Database(m.cfg.Database)
+}
+
+func (m *manager) Health(ctx context.Context) error {
+	client := m.Client()
+	if client == nil {
+		return ErrNotConnected
+	}
+	return client.Ping(ctx, nil)
+}
+
+func (m *manager) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), m.opts.HealthTimeout)
+		defer cancel()
+
+		if err := m.Health(ctx); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+}
+
+func (m *manager) Disconnect(ctx context.Context) error {
+	m.connectMu.Lock()
+	defer m.connectMu.Unlock()
+
+	m.mu.Lock()
+	client := m.client
+	m.client = nil
+	m.mu.Unlock()
+
+	if client == nil {
+		return nil
+	}
+	return client.Disconnect(ctx)
+}
+
+// newDriverClient creates a driver client
+func newDriverClient(opts *options.ClientOptions) (base.MongoClient, error) {
+	client, err := mongo.NewClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	return base.WrapClient(client), nil
+}
+
+// NewManager gets a new manager for the config
+func NewManager(cfg Config) Manager {
+	return NewManagerWithOptions(cfg, ManagerOptions{})
+}
+
+// NewManagerWithOptions gets a new manager for the config using the given options
+func NewManagerWithOptions(cfg Config, opts ManagerOptions) Manager {
+	if opts.Attempts <= 0 {
+		opts.Attempts = 5
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = 100 * time.Millisecond
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 10 * time.Second
+	}
+	if opts.HealthTimeout <= 0 {
+		opts.HealthTimeout = 2 * time.Second
+	}
+	if opts.NewClient == nil {
+		opts.NewClient = newDriverClient
+	}
+	return &manager{cfg: cfg, opts: opts}
+}
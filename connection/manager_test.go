@@ -0,0 +1,157 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/memdb"
+	bMocks "github.com/archy-bold/mongo-go-helper/mocks/base"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var errExample = errors.New("error")
+
+// clientFactory gets a NewClient option returning the clients in order
+func clientFactory(clients ...base.MongoClient) func(*options.ClientOptions) (base.MongoClient, error) {
+	i := 0
+	return func(*options.ClientOptions) (base.MongoClient, error) {
+		client := clients[i]
+		i++
+		return client, nil
+	}
+}
+
+// failingClient gets a mock client failing to connect or ping. Clients that
+// connect but fail the ping are expected to be disconnected.
+func failingClient(connectErr, pingErr error) *bMocks.MongoClient {
+	client := &bMocks.MongoClient{}
+	client.On("Connect", mock.Anything).Return(connectErr)
+	if connectErr == nil {
+		client.On("Ping", mock.Anything, mock.Anything).Return(pingErr)
+		client.On("Disconnect", mock.Anything).Return(nil)
+	}
+	return client
+}
+
+var connectTests = map[string]struct {
+	clients func() []base.MongoClient
+	err     string
+}{
+	"first attempt": {
+		func() []base.MongoClient { return []base.MongoClient{memdb.NewClient()} },
+		"",
+	},
+	"after retries": {
+		func() []base.MongoClient {
+			return []base.MongoClient{failingClient(errExample, nil), failingClient(nil, errExample), memdb.NewClient()}
+		},
+		"",
+	},
+	"all attempts fail": {
+		func() []base.MongoClient {
+			return []base.MongoClient{failingClient(errExample, nil), failingClient(nil, errExample), failingClient(nil, errExample)}
+		},
+		"failed to connect after 3 attempts: error",
+	},
+}
+
+func Test_Connect(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range connectTests {
+		clients := tt.clients()
+		m := NewManagerWithOptions(Config{URI: defaultURI, Database: "test"}, ManagerOptions{
+			Attempts:       3,
+			InitialBackoff: time.Millisecond,
+			NewClient:      clientFactory(clients...),
+		})
+
+		err := m.Connect(ctx)
+
+		if tt.err == "" {
+			assert.Nilf(t, err, "Expected nil err on test '%s'", tn)
+			assert.Equalf(t, clients[len(clients)-1], m.Client(), "Expected the connected client on test '%s'", tn)
+			assert.Equalf(t, "test", m.Database().Name(), "Expected the configured database on test '%s'", tn)
+		} else {
+			assert.EqualErrorf(t, err, tt.err, "Expected err to match on test '%s'", tn)
+			assert.Nilf(t, m.Client(), "Expected no client on test '%s'", tn)
+			assert.Nilf(t, m.Database(), "Expected no database on test '%s'", tn)
+		}
+		for _, client := range clients {
+			if mc, ok := client.(*bMocks.MongoClient); ok {
+				mc.AssertExpectations(t)
+			}
+		}
+	}
+}
+
+func Test_Connect_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	// Invalid config isn't retried
+	m := NewManager(Config{})
+	assert.EqualError(t, m.Connect(ctx), "no URI in connection config")
+
+	// Cancelled while waiting to retry
+	ctx, cancel := context.WithCancel(ctx)
+	m = NewManagerWithOptions(Config{URI: defaultURI}, ManagerOptions{
+		InitialBackoff: time.Hour,
+		NewClient: func(*options.ClientOptions) (base.MongoClient, error) {
+			cancel()
+			return failingClient(errExample, nil), nil
+		},
+	})
+	assert.EqualError(t, m.Connect(ctx), "connection cancelled after 1 attempts: error")
+}
+
+func Test_HealthAndDisconnect(t *testing.T) {
+	ctx := context.Background()
+	client := memdb.NewClient()
+	m := NewManagerWithOptions(Config{URI: defaultURI}, ManagerOptions{NewClient: clientFactory(client)})
+
+	assert.Equal(t, ErrNotConnected, m.Health(ctx), "Expected not connected before Connect")
+	m.Connect(ctx)
+	assert.Nil(t, m.Health(ctx), "Expected healthy once connected")
+	assert.Nil(t, m.Connect(ctx), "Expected connecting again to do nothing")
+
+	assert.Nil(t, m.Disconnect(ctx))
+	assert.Equal(t, ErrNotConnected, m.Health(ctx), "Expected not connected after Disconnect")
+	assert.Equal(t, mongo.ErrClientDisconnected, client.Ping(ctx, nil), "Expected the client to be disconnected")
+	assert.Nil(t, m.Disconnect(ctx), "Expected disconnecting again to do nothing")
+}
+
+var healthHandlerTests = map[string]struct {
+	pingErr error
+	status  int
+	body    string
+}{
+	"healthy":   {nil, http.StatusOK, "ok"},
+	"unhealthy": {errExample, http.StatusServiceUnavailable, "error\n"},
+}
+
+func Test_HealthHandler(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range healthHandlerTests {
+		client := &bMocks.MongoClient{}
+		client.On("Connect", mock.Anything).Return(nil)
+		client.On("Ping", mock.Anything, mock.Anything).Return(nil).Once()
+		client.On("Ping", mock.Anything, mock.Anything).Return(tt.pingErr)
+		m := NewManagerWithOptions(Config{URI: defaultURI}, ManagerOptions{NewClient: clientFactory(client)})
+		m.Connect(ctx)
+
+		rec := httptest.NewRecorder()
+		m.HealthHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+
+		assert.Equalf(t, tt.status, rec.Code, "Expected status to match on test '%s'", tn)
+		assert.Equalf(t, tt.body, rec.Body.String(), "Expected body to match on test '%s'", tn)
+	}
+}
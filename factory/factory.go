@@ -0,0 +1,197 @@
+package factory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrNotStructPointer error indicating the factory model isn't a pointer to a struct
+var ErrNotStructPointer = errors.New("the factory model must be a pointer to a struct")
+
+// Generator represents a function generating a field value for the nth item built by a factory
+type Generator func(n int) interface{}
+
+// Attrs represents field values overriding the generated ones, by struct or bson field name.
+// Values may be Generators or plain func(int) interface{} functions.
+type Attrs map[string]interface{}
+
+type field struct {
+	name string
+	gen  Generator
+}
+
+// Factory builds instances of a model with generated field values
+type Factory struct {
+	model  base.ModelInterface
+	fields []field
+	mu     sync.Mutex
+	seq    int
+}
+
+// New gets a factory building copies of the given model
+func New(model base.ModelInterface) *Factory {
+	return &Factory{model: model}
+}
+
+// Field sets the default generator for the field with the given struct or bson name
+func (f *Factory) Field(name string, gen Generator) *Factory {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := range f.fields {
+		if f.fields[i].name == name {
+			f.fields[i].gen = gen
+			return f
+		}
+	}
+	f.fields = append(f.fields, field{name, gen})
+	return f
+}
+
+// Make builds a single instance, applying any overrides on top of the generated fields
+func (f *Factory) Make(overrides ...Attrs) (base.ModelInterface, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t := reflect.TypeOf(f.model)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil, ErrNotStructPointer
+	}
+
+	// Copy the template model and fill in the fields
+	f.seq++
+	v := reflect.New(t.Elem())
+	base.DeepCopy(v.Elem(), reflect.ValueOf(f.model).Elem())
+
+	for _, fd := range f.fields {
+		if err := setField(v.Elem(), fd.name, fd.gen(f.seq)); err != nil {
+			return nil, err
+		}
+	}
+	for _, attrs := range overrides {
+		for name, val := range attrs {
+			switch gen := val.(type) {
+			case Generator:
+				val = gen(f.seq)
+			case func(int) interface{}:
+				val = gen(f.seq)
+			}
+			if err := setField(v.Elem(), name, val); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return v.Interface().(base.ModelInterface), nil
+}
+
+// MakeMany builds n instances, applying any overrides to each of them
+func (f *Factory) MakeMany(n int, overrides ...Attrs) ([]base.ModelInterface, error) {
+	items := make([]base.ModelInterface, 0, n)
+	for i := 0; i < n; i++ {
+		item, err := f.Make(overrides...)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Create builds a single instance and inserts it into the collection
+func (f *Factory) Create(ctx context.Context, h base.MongoHelper, coll string, overrides ...Attrs) (base.ModelInterface, error) {
+	item, err := f.Make(overrides...)
+	if err != nil {
+		return nil, err
+	}
+
+	setObjectID(item)
+	if _, err = h.InsertOne(ctx, coll, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// CreateMany builds n instances and inserts them into the collection with a single bulk write
+func (f *Factory) CreateMany(ctx context.Context, h base.MongoHelper, coll string, n int, overrides ...Attrs) ([]base.ModelInterface, error) {
+	items, err := f.MakeMany(n, overrides...)
+	if err != nil || n == 0 {
+		return items, err
+	}
+
+	models := make([]mongo.WriteModel, 0, n)
+	for _, item := range items {
+		setObjectID(item)
+		models = append(models, mongo.NewInsertOneModel().SetDocument(item))
+	}
+	if _, err = h.BulkWrite(ctx, coll, models); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// setObjectID gives new items with ObjectIDs an ID so it's known after a bulk insert
+func setObjectID(item base.ModelInterface) {
+	if _, ok := item.GetID().(primitive.ObjectID); ok && !item.Exists() {
+		item.SetID(primitive.NewObjectID())
+	}
+}
+
+// setField sets the field with the given struct or bson name on the struct value
+func setField(v reflect.Value, name string, val interface{}) error {
+	f, ok := findField(v, name)
+	if !ok {
+		return fmt.Errorf("factory: unknown field '%s' on %s", name, v.Type())
+	}
+
+	if val == nil {
+		f.Set(reflect.Zero(f.Type()))
+		return nil
+	}
+
+	rv := reflect.ValueOf(val)
+	switch {
+	case rv.Type().AssignableTo(f.Type()):
+		f.Set(rv)
+	case isNumber(rv.Kind()) && isNumber(f.Kind()):
+		f.Set(rv.Convert(f.Type()))
+	case rv.Kind() == reflect.String && f.Kind() == reflect.String:
+		f.SetString(rv.String())
+	default:
+		return fmt.Errorf("factory: cannot use %s as %s for field '%s'", rv.Type(), f.Type(), name)
+	}
+	return nil
+}
+
+func findField(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		tag := strings.Split(sf.Tag.Get("bson"), ",")[0]
+		if sf.Name == name || (tag != "" && tag == name) {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func isNumber(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,228 @@
+package factory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/archy-bold/mongo-go-helper/migration/schema"
+	bMocks "github.com/archy-bold/mongo-go-helper/mocks/base"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	errExample = errors.New("error")
+	sampleTime = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+type exampleUser struct {
+	ID      primitive.ObjectID `bson:"_id"`
+	Name    string             `bson:"name"`
+	Email   string             `bson:"email"`
+	Age     int64              `bson:"age"`
+	Role    string             `bson:"role"`
+	Created time.Time          `bson:"created_at"`
+}
+
+func (m exampleUser) Exists() bool {
+	return !m.ID.IsZero()
+}
+
+func (m exampleUser) GetID() interface{} {
+	return m.ID
+}
+
+func (m *exampleUser) SetID(id interface{}) {
+	m.ID = id.(primitive.ObjectID)
+}
+
+func newUserFactory() *Factory {
+	return New(&exampleUser{Role: "member"}).
+		Field("Name", Name()).
+		Field("email", Email("example.com")).
+		Field("Age", SequenceInt(18)).
+		Field("created_at", Timestamp(sampleTime, time.Hour))
+}
+
+var makeTests = map[string]struct {
+	factory   *Factory
+	overrides []Attrs
+	expected  []exampleUser
+	err       string
+}{
+	"defaults": {
+		newUserFactory(), nil,
+		[]exampleUser{
+			{Name: "Alex Smith", Email: "alex.smith1@example.com", Age: 18, Role: "member", Created: sampleTime},
+			{Name: "Sam Smith", Email: "sam.smith2@example.com", Age: 19, Role: "member", Created: sampleTime.Add(time.Hour)},
+		},
+		"",
+	},
+	"overrides": {
+		newUserFactory(),
+		[]Attrs{{"role": "admin", "Age": 40}, {"Name": Sequence("user-%d")}},
+		[]exampleUser{
+			{Name: "user-1", Email: "alex.smith1@example.com", Age: 40, Role: "admin", Created: sampleTime},
+			{Name: "user-2", Email: "sam.smith2@example.com", Age: 40, Role: "admin", Created: sampleTime.Add(time.Hour)},
+		},
+		"",
+	},
+	"function overrides": {
+		newUserFactory(),
+		[]Attrs{{"Name": func(n int) interface{} { return fmt.Sprintf("fn-%d", n) }}},
+		[]exampleUser{
+			{Name: "fn-1", Email: "alex.smith1@example.com", Age: 18, Role: "member", Created: sampleTime},
+			{Name: "fn-2", Email: "sam.smith2@example.com", Age: 19, Role: "member", Created: sampleTime.Add(time.Hour)},
+		},
+		"",
+	},
+	"unknown field": {
+		newUserFactory(), []Attrs{{"nope": 1}}, nil,
+		"factory: unknown field 'nope' on factory.exampleUser",
+	},
+	"bad type": {
+		newUserFactory(), []Attrs{{"Name": 1}}, nil,
+		"factory: cannot use int as string for field 'Name'",
+	},
+	"not a pointer": {
+		New(nil), nil, nil,
+		ErrNotStructPointer.Error(),
+	},
+}
+
+func Test_MakeMany(t *testing.T) {
+	for tn, tt := range makeTests {
+		items, err := tt.factory.MakeMany(2, tt.overrides...)
+
+		if tt.err == "" {
+			assert.Nilf(t, err, "Expected nil err for MakeMany on test '%s'", tn)
+			assert.Lenf(t, items, len(tt.expected), "Expected items to match the expected len for MakeMany on test '%s'", tn)
+			for i, item := range items {
+				assert.Equalf(t, &tt.expected[i], item, "Expected item %d to match for MakeMany on test '%s'", i, tn)
+			}
+		} else {
+			assert.NotNilf(t, err, "Expected not nil err for MakeMany on test '%s'", tn)
+			assert.EqualErrorf(t, err, tt.err, "Expected err to match for MakeMany on test '%s'", tn)
+			assert.Nilf(t, items, "Expected nil items for MakeMany on test '%s'", tn)
+		}
+	}
+}
+
+type exampleTagged struct {
+	ID      primitive.ObjectID `bson:"_id"`
+	Tags    []string           `bson:"tags"`
+	Meta    map[string]string  `bson:"meta"`
+	Profile *exampleProfile    `bson:"profile"`
+}
+
+type exampleProfile struct {
+	Links []string `bson:"links"`
+}
+
+func (m exampleTagged) Exists() bool {
+	return !m.ID.IsZero()
+}
+
+func (m exampleTagged) GetID() interface{} {
+	return m.ID
+}
+
+func (m *exampleTagged) SetID(id interface{}) {
+	m.ID = id.(primitive.ObjectID)
+}
+
+func Test_Make_DeepCopy(t *testing.T) {
+	template := &exampleTagged{
+		Tags:    []string{"a"},
+		Meta:    map[string]string{"k": "v"},
+		Profile: &exampleProfile{Links: []string{"x"}},
+	}
+	f := New(template)
+
+	first, _ := f.Make()
+	second, _ := f.Make()
+	item := first.(*exampleTagged)
+	item.Tags[0] = "changed"
+	item.Meta["k"] = "changed"
+	item.Profile.Links[0] = "changed"
+
+	assert.Equal(t, &exampleTagged{
+		Tags:    []string{"a"},
+		Meta:    map[string]string{"k": "v"},
+		Profile: &exampleProfile{Links: []string{"x"}},
+	}, template, "Expected the template not to change")
+	assert.Equal(t, template, second, "Expected items not to share the template's slices, maps and pointers")
+}
+
+func Test_Make_SeedItems(t *testing.T) {
+	// Factories can supply the items of a seed task
+	items, err := newUserFactory().MakeMany(3)
+	task := &schema.SeedTableTask{Task: schema.Task{Collection: "users"}, Items: items}
+
+	assert.Nil(t, err)
+	assert.Len(t, task.Items, 3)
+	assert.IsType(t, &exampleUser{}, task.Items[0])
+}
+
+var createTests = map[string]struct {
+	err error
+}{
+	"success": {nil},
+	"error":   {errExample},
+}
+
+func Test_Create(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range createTests {
+		helper := &bMocks.MongoHelper{}
+		helper.On("InsertOne", ctx, "users", mock.AnythingOfType("*factory.exampleUser")).
+			Return(primitive.NewObjectID(), tt.err)
+
+		item, err := newUserFactory().Create(ctx, helper, "users")
+
+		helper.AssertNumberOfCalls(t, "InsertOne", 1)
+		if tt.err == nil {
+			assert.Nilf(t, err, "Expected nil err for Create on test '%s'", tn)
+			assert.Truef(t, item.Exists(), "Expected item to have an ID for Create on test '%s'", tn)
+		} else {
+			assert.Equalf(t, tt.err, err, "Expected err to match for Create on test '%s'", tn)
+			assert.Nilf(t, item, "Expected nil item for Create on test '%s'", tn)
+		}
+	}
+}
+
+func Test_CreateMany(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range createTests {
+		helper := &bMocks.MongoHelper{}
+		var models []mongo.WriteModel
+		helper.On("BulkWrite", ctx, "users", mock.Anything).
+			Run(func(args mock.Arguments) {
+				models = args.Get(2).([]mongo.WriteModel)
+			}).
+			Return(&mongo.BulkWriteResult{InsertedCount: 3}, tt.err)
+
+		items, err := newUserFactory().CreateMany(ctx, helper, "users", 3)
+
+		helper.AssertNumberOfCalls(t, "BulkWrite", 1)
+		assert.Lenf(t, models, 3, "Expected a write model per item for CreateMany on test '%s'", tn)
+		if tt.err == nil {
+			assert.Nilf(t, err, "Expected nil err for CreateMany on test '%s'", tn)
+			assert.Lenf(t, items, 3, "Expected items for CreateMany on test '%s'", tn)
+			for i, item := range items {
+				assert.Truef(t, item.Exists(), "Expected item %d to have an ID for CreateMany on test '%s'", i, tn)
+				assert.Equalf(t, item, models[i].(*mongo.InsertOneModel).Document, "Expected item %d to be written for CreateMany on test '%s'", i, tn)
+			}
+		} else {
+			assert.Equalf(t, tt.err, err, "Expected err to match for CreateMany on test '%s'", tn)
+			assert.Nilf(t, items, "Expected nil items for CreateMany on test '%s'", tn)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package factory
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	firstNames = []string{"Alex", "Sam", "Jordan", "Taylor", "Morgan", "Casey", "Jamie", "Robin", "Charlie", "Drew"}
+	lastNames  = []string{"Smith", "Jones", "Garcia", "Chen", "Patel", "Okafor", "Novak", "Silva", "Kim", "Murphy"}
+)
+
+// Sequence generates strings by formatting the item's sequence number, eg "user-%d"
+func Sequence(format string) Generator {
+	return func(n int) interface{} {
+		return fmt.Sprintf(format, n)
+	}
+}
+
+// SequenceInt generates integers counting up from start
+func SequenceInt(start int) Generator {
+	return func(n int) interface{} {
+		return start + n - 1
+	}
+}
+
+// ObjectID generates new ObjectIDs
+func ObjectID() Generator {
+	return func(n int) interface{} {
+		return primitive.NewObjectID()
+	}
+}
+
+// Name generates full names, unique for the first hundred items
+func Name() Generator {
+	return func(n int) interface{} {
+		first, last := name(n)
+		return first + " " + last
+	}
+}
+
+// Email generates unique email addresses at the given domain
+func Email(domain string) Generator {
+	return func(n int) interface{} {
+		first, last := name(n)
+		return strings.ToLower(fmt.Sprintf("%s.%s%d@%s", first, last, n, domain))
+	}
+}
+
+// Now generates the current time, truncated to the millisecond precision mongo stores
+func Now() Generator {
+	return func(n int) interface{} {
+		return time.Now().Truncate(time.Millisecond)
+	}
+}
+
+// Timestamp generates times starting at start, separated by step
+func Timestamp(start time.Time, step time.Duration) Generator {
+	return func(n int) interface{} {
+		return start.Add(time.Duration(n-1) * step)
+	}
+}
+
+// OneOf cycles through the given values. It panics if there are none.
+func OneOf(values ...interface{}) Generator {
+	if len(values) == 0 {
+		panic("factory: OneOf needs at least one value")
+	}
+	return func(n int) interface{} {
+		return values[(n-1)%len(values)]
+	}
+}
+
+func name(n int) (string, string) {
+	i := n - 1
+	return firstNames[i%len(firstNames)], lastNames[(i/len(firstNames))%len(lastNames)]
+}
@@ -0,0 +1,43 @@
+package factory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var generatorTests = map[string]struct {
+	gen      Generator
+	expected []interface{}
+}{
+	"sequence":     {Sequence("item-%03d"), []interface{}{"item-001", "item-002", "item-003"}},
+	"sequence int": {SequenceInt(10), []interface{}{10, 11, 12}},
+	"name":         {Name(), []interface{}{"Alex Smith", "Sam Smith", "Jordan Smith"}},
+	"email":        {Email("test.io"), []interface{}{"alex.smith1@test.io", "sam.smith2@test.io", "jordan.smith3@test.io"}},
+	"timestamp":    {Timestamp(sampleTime, time.Minute), []interface{}{sampleTime, sampleTime.Add(time.Minute), sampleTime.Add(2 * time.Minute)}},
+	"one of":       {OneOf("a", "b"), []interface{}{"a", "b", "a"}},
+}
+
+func Test_Generators(t *testing.T) {
+	for tn, tt := range generatorTests {
+		for i, expected := range tt.expected {
+			assert.Equalf(t, expected, tt.gen(i+1), "Expected value %d to match for generator test '%s'", i, tn)
+		}
+	}
+}
+
+func Test_Generators_Unique(t *testing.T) {
+	oid := ObjectID()
+	assert.NotEqual(t, oid(1), oid(2), "Expected ObjectIDs to be unique")
+	assert.IsType(t, primitive.ObjectID{}, oid(1))
+
+	now := Now()(1).(time.Time)
+	assert.Equal(t, now, now.Truncate(time.Millisecond), "Expected Now to be truncated to milliseconds")
+	assert.Equal(t, "Alex Jones", Name()(11), "Expected names to move on to the next last name")
+}
+
+func Test_OneOf_Empty(t *testing.T) {
+	assert.PanicsWithValue(t, "factory: OneOf needs at least one value", func() { OneOf() }, "Expected OneOf to reject no values when it's built")
+}
@@ -1,6 +1,6 @@
 module github.com/archy-bold/mongo-go-helper
 
-go 1.12
+go 1.14
 
 require (
 	github.com/hashicorp/go-multierror v1.0.0
@@ -8,4 +8,5 @@ require (
 	github.com/pkg/errors v0.9.1
 	github.com/stretchr/testify v1.5.1
 	go.mongodb.org/mongo-driver v1.3.0
+	gopkg.in/yaml.v2 v2.2.2
 )
@@ -0,0 +1,499 @@
+package memdb
+
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// aggregate runs the pipeline stages over the documents
+func aggregate(docs []bson.D, pipeline []bson.D) ([]bson.D, error) {
+	for _, stage := range pipeline {
+		if len(stage) != 1 {
+			return nil, badValue("A pipeline stage specification object must contain exactly one field.")
+		}
+		var err error
+		docs, err = runStage(docs, stage[0])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return docs, nil
+}
+
+func runStage(docs []bson.D, stage bson.E) ([]bson.D, error) {
+	switch stage.Key {
+	case "$match":
+		filter, ok := stage.Value.(primitive.D)
+		if !ok {
+			return nil, badValue("the match filter must be an expression in an object")
+		}
+		out := make([]bson.D, 0, len(docs))
+		for _, d := range docs {
+			m, err := matches(d, filter)
+			if err != nil {
+				return nil, err
+			}
+			if m {
+				out = append(out, d)
+			}
+		}
+		return out, nil
+	case "$sort":
+		spec, ok := stage.Value.(primitive.D)
+		if !ok || len(spec) == 0 {
+			return nil, badValue("the $sort key specification must be an object")
+		}
+		out := append([]bson.D{}, docs...)
+		return out, sortDocs(out, spec)
+	case "$skip", "$limit":
+		n, ok := toInt64(stage.Value)
+		if !ok || n < 0 || (stage.Key == "$limit" && n == 0) {
+			return nil, badValue("invalid argument to %s stage: %s", stage.Key, formatValue(stage.Value))
+		}
+		if stage.Key == "$skip" {
+			return window(docs, &n, nil), nil
+		}
+		return window(docs, nil, &n), nil
+	case "$project":
+		spec, ok := stage.Value.(primitive.D)
+		if !ok || len(spec) == 0 {
+			return nil, badValue("$project specification must be an object")
+		}
+		out := make([]bson.D, 0, len(docs))
+		for _, d := range docs {
+			p, err := project(d, spec)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, p)
+		}
+		return out, nil
+	case "$addFields", "$set":
+		spec, ok := stage.Value.(primitive.D)
+		if !ok {
+			return nil, badValue("%s specification stage must be an object", stage.Key)
+		}
+		out := make([]bson.D, 0, len(docs))
+		for _, d := range docs {
+			var nd interface{} = cloneDoc(d)
+			for _, f := range flattenSpec(spec, "") {
+				v, err := evalExpr(d, f.Value)
+				if err != nil {
+					return nil, err
+				}
+				if nd, err = setPath(nd, splitPath(f.Key), v); err != nil {
+					return nil, err
+				}
+			}
+			out = append(out, nd.(primitive.D))
+		}
+		return out, nil
+	case "$unset":
+		var fields primitive.A
+		switch t := stage.Value.(type) {
+		case string:
+			fields = primitive.A{t}
+		case primitive.A:
+			fields = t
+		}
+		out := make([]bson.D, 0, len(docs))
+		for _, d := range docs {
+			var nd interface{} = cloneDoc(d)
+			for _, f := range fields {
+				s, _ := f.(string)
+				nd = unsetPath(nd, splitPath(s))
+			}
+			out = append(out, nd.(primitive.D))
+		}
+		return out, nil
+	case "$count":
+		name, ok := stage.Value.(string)
+		if !ok || name == "" || strings.HasPrefix(name, "$") || strings.Contains(name, ".") {
+			return nil, badValue("the count field must be a non-empty string, not start with '$' and not contain '.'")
+		}
+		if len(docs) == 0 {
+			return nil, nil
+		}
+		return []bson.D{{{Key: name, Value: int32(len(docs))}}}, nil
+	case "$unwind":
+		return unwind(docs, stage.Value)
+	case "$group":
+		spec, ok := stage.Value.(primitive.D)
+		if !ok {
+			return nil, badValue("a group's fields must be specified in an object")
+		}
+		return group(docs, spec)
+	case "$replaceRoot", "$replaceWith":
+		expr := stage.Value
+		if spec, ok := expr.(primitive.D); ok && stage.Key == "$replaceRoot" {
+			expr, _ = get(spec, "newRoot")
+		}
+		out := make([]bson.D, 0, len(docs))
+		for _, d := range docs {
+			v, err := evalExpr(d, expr)
+			if err != nil {
+				return nil, err
+			}
+			root, ok := v.(primitive.D)
+			if !ok {
+				return nil, badValue("'newRoot' expression must evaluate to an object, but resulting value was: %s", formatValue(v))
+			}
+			out = append(out, root)
+		}
+		return out, nil
+	}
+	return nil, newError(codeUnrecognizedPipelineStep, "Location40324", "Unrecognized pipeline stage name: '%s'", stage.Key)
+}
+
+func unwind(docs []bson.D, arg interface{}) ([]bson.D, error) {
+	path, preserve := "", false
+	switch t := arg.(type) {
+	case string:
+		path = t
+	case primitive.D:
+		p, _ := get(t, "path")
+		path, _ = p.(string)
+		pv, _ := get(t, "preserveNullAndEmptyArrays")
+		preserve = truthy(pv)
+	}
+	if !strings.HasPrefix(path, "$") {
+		return nil, badValue("path option to $unwind stage should be prefixed with a '$': %s", path)
+	}
+	parts := splitPath(path[1:])
+
+	var out []bson.D
+	for _, d := range docs {
+		v, ok := getPath(d, parts)
+		arr, isArr := v.(primitive.A)
+		switch {
+		case isArr && len(arr) > 0:
+			for _, e := range arr {
+				nd, err := setPath(cloneDoc(d), parts, e)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, nd.(primitive.D))
+			}
+		case isArr || !ok || v == nil:
+			if preserve {
+				out = append(out, d)
+			}
+		default:
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// groupState accumulates the documents in a group
+type groupState struct {
+	id   interface{}
+	docs []bson.D
+}
+
+func group(docs []bson.D, spec bson.D) ([]bson.D, error) {
+	idExpr, ok := get(spec, "_id")
+	if !ok {
+		return nil, badValue("a group specification must include an _id")
+	}
+
+	var groups []*groupState
+	for _, d := range docs {
+		id, err := evalExpr(d, idExpr)
+		if err != nil {
+			return nil, err
+		}
+		var g *groupState
+		for _, existing := range groups {
+			if compareValues(existing.id, id) == 0 {
+				g = existing
+				break
+			}
+		}
+		if g == nil {
+			g = &groupState{id: id}
+			groups = append(groups, g)
+		}
+		g.docs = append(g.docs, d)
+	}
+
+	out := make([]bson.D, 0, len(groups))
+	for _, g := range groups {
+		res := bson.D{{Key: "_id", Value: g.id}}
+		for _, f := range spec {
+			if f.Key == "_id" {
+				continue
+			}
+			acc, ok := f.Value.(primitive.D)
+			if !ok || len(acc) != 1 {
+				return nil, badValue("The field '%s' must be an accumulator object", f.Key)
+			}
+			v, err := accumulate(g.docs, acc[0])
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, bson.E{Key: f.Key, Value: v})
+		}
+		out = append(out, res)
+	}
+	return out, nil
+}
+
+// accumulate applies a $group accumulator over the documents in a group
+func accumulate(docs []bson.D, acc bson.E) (interface{}, error) {
+	vals := make([]interface{}, 0, len(docs))
+	for _, d := range docs {
+		v, err := evalExpr(d, acc.Value)
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, v)
+	}
+
+	switch acc.Key {
+	case "$sum", "$avg":
+		var sum interface{} = int32(0)
+		n := 0
+		for _, v := range vals {
+			if isNumber(v) {
+				sum = addNumbers(sum, v)
+				n++
+			}
+		}
+		if acc.Key == "$sum" {
+			return sum, nil
+		}
+		if n == 0 {
+			return nil, nil
+		}
+		return toFloat(sum) / float64(n), nil
+	case "$min", "$max":
+		var res interface{}
+		for _, v := range vals {
+			if v == nil {
+				continue
+			}
+			c := compareValues(v, res)
+			if res == nil || (acc.Key == "$min" && c < 0) || (acc.Key == "$max" && c > 0) {
+				res = v
+			}
+		}
+		return res, nil
+	case "$first":
+		if len(vals) == 0 {
+			return nil, nil
+		}
+		return vals[0], nil
+	case "$last":
+		if len(vals) == 0 {
+			return nil, nil
+		}
+		return vals[len(vals)-1], nil
+	case "$push":
+		return primitive.A(vals), nil
+	case "$addToSet":
+		set := primitive.A{}
+		for _, v := range vals {
+			if !contains(set, v) {
+				set = append(set, v)
+			}
+		}
+		return set, nil
+	}
+	return nil, badValue("unknown group operator '%s'", acc.Key)
+}
+
+// evalExpr evaluates an aggregation expression against the document
+func evalExpr(doc bson.D, expr interface{}) (interface{}, error) {
+	switch t := expr.(type) {
+	case string:
+		if strings.HasPrefix(t, "$$") {
+			if t == "$$ROOT" || t == "$$CURRENT" {
+				return doc, nil
+			}
+			return nil, badValue("Use of undefined variable: %s", strings.TrimPrefix(t, "$$"))
+		}
+		if strings.HasPrefix(t, "$") {
+			vals := lookup(doc, splitPath(t[1:]))
+			if v, ok := getPath(doc, splitPath(t[1:])); ok {
+				return v, nil
+			}
+			if len(vals) == 0 {
+				return nil, nil
+			}
+			return primitive.A(vals), nil
+		}
+		return t, nil
+	case primitive.A:
+		out := make(primitive.A, len(t))
+		for i, e := range t {
+			v, err := evalExpr(doc, e)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+	case primitive.D:
+		if isOperatorDoc(t) {
+			if len(t) != 1 {
+				return nil, badValue("An object representing an expression must have exactly one field")
+			}
+			return evalOp(doc, t[0])
+		}
+		out := make(primitive.D, 0, len(t))
+		for _, e := range t {
+			v, err := evalExpr(doc, e.Value)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, bson.E{Key: e.Key, Value: v})
+		}
+		return out, nil
+	}
+	return expr, nil
+}
+
+func evalOp(doc bson.D, op bson.E) (interface{}, error) {
+	if op.Key == "$literal" {
+		return op.Value, nil
+	}
+
+	argv, err := evalExpr(doc, op.Value)
+	if err != nil {
+		return nil, err
+	}
+	args, ok := argv.(primitive.A)
+	if !ok {
+		args = primitive.A{argv}
+	}
+
+	switch op.Key {
+	case "$add", "$multiply":
+		var res interface{} = int32(0)
+		if op.Key == "$multiply" {
+			res = int32(1)
+		}
+		for _, a := range args {
+			if a == nil {
+				return nil, nil
+			}
+			if !isNumber(a) {
+				return nil, newError(codeTypeMismatch, "TypeMismatch", "%s only supports numeric types, not %T", op.Key, a)
+			}
+			if op.Key == "$add" {
+				res = addNumbers(res, a)
+			} else {
+				res = mulNumbers(res, a)
+			}
+		}
+		return res, nil
+	case "$subtract", "$divide":
+		if len(args) != 2 {
+			return nil, badValue("Expression %s takes exactly 2 arguments. %d were passed in.", op.Key, len(args))
+		}
+		if args[0] == nil || args[1] == nil {
+			return nil, nil
+		}
+		if !isNumber(args[0]) || !isNumber(args[1]) {
+			return nil, newError(codeTypeMismatch, "TypeMismatch", "%s only supports numeric types", op.Key)
+		}
+		if op.Key == "$divide" {
+			if toFloat(args[1]) == 0 {
+				return nil, badValue("can't $divide by zero")
+			}
+			return toFloat(args[0]) / toFloat(args[1]), nil
+		}
+		return addNumbers(args[0], mulNumbers(args[1], int32(-1))), nil
+	case "$concat":
+		var sb strings.Builder
+		for _, a := range args {
+			if a == nil {
+				return nil, nil
+			}
+			s, ok := a.(string)
+			if !ok {
+				return nil, newError(codeTypeMismatch, "TypeMismatch", "$concat only supports strings, not %T", a)
+			}
+			sb.WriteString(s)
+		}
+		return sb.String(), nil
+	case "$toLower", "$toUpper":
+		s, _ := args[0].(string)
+		if op.Key == "$toLower" {
+			return strings.ToLower(s), nil
+		}
+		return strings.ToUpper(s), nil
+	case "$size":
+		a, ok := args[0].(primitive.A)
+		if !ok || len(args) != 1 {
+			return nil, badValue("The argument to $size must be an array")
+		}
+		return int32(len(a)), nil
+	case "$ifNull":
+		for _, a := range args[:len(args)-1] {
+			if a != nil {
+				return a, nil
+			}
+		}
+		return args[len(args)-1], nil
+	case "$cond":
+		if spec, ok := op.Value.(primitive.D); ok {
+			ifv, _ := get(spec, "if")
+			thenv, _ := get(spec, "then")
+			elsev, _ := get(spec, "else")
+			args = primitive.A{ifv, thenv, elsev}
+			for i := range args {
+				if args[i], err = evalExpr(doc, args[i]); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if len(args) != 3 {
+			return nil, badValue("Expression $cond takes exactly 3 arguments. %d were passed in.", len(args))
+		}
+		if truthy(args[0]) {
+			return args[1], nil
+		}
+		return args[2], nil
+	case "$eq", "$ne", "$gt", "$gte", "$lt", "$lte", "$cmp":
+		if len(args) != 2 {
+			return nil, badValue("Expression %s takes exactly 2 arguments. %d were passed in.", op.Key, len(args))
+		}
+		c := compareValues(args[0], args[1])
+		switch op.Key {
+		case "$eq":
+			return c == 0, nil
+		case "$ne":
+			return c != 0, nil
+		case "$gt":
+			return c > 0, nil
+		case "$gte":
+			return c >= 0, nil
+		case "$lt":
+			return c < 0, nil
+		case "$lte":
+			return c <= 0, nil
+		}
+		return int32(c), nil
+	case "$and":
+		for _, a := range args {
+			if !truthy(a) {
+				return false, nil
+			}
+		}
+		return true, nil
+	case "$or":
+		for _, a := range args {
+			if truthy(a) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "$not":
+		return !truthy(args[0]), nil
+	}
+	return nil, newError(codeInvalidPipelineOperator, "InvalidPipelineOperator", "Unrecognized expression '%s'", op.Key)
+}
@@ -0,0 +1,104 @@
+package memdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var aggregateDocs = []interface{}{
+	bson.D{{Key: "_id", Value: 1}, {Key: "cat", Value: "a"}, {Key: "n", Value: 1}, {Key: "tags", Value: bson.A{"x", "y"}}},
+	bson.D{{Key: "_id", Value: 2}, {Key: "cat", Value: "b"}, {Key: "n", Value: 5}, {Key: "tags", Value: bson.A{}}},
+	bson.D{{Key: "_id", Value: 3}, {Key: "cat", Value: "a"}, {Key: "n", Value: 3}, {Key: "tags", Value: bson.A{"y"}}},
+}
+
+var aggregateTests = map[string]struct {
+	pipeline mongo.Pipeline
+	expected []bson.D
+	err      string
+}{
+	"match sort limit": {
+		mongo.Pipeline{
+			{{Key: "$match", Value: bson.D{{Key: "cat", Value: "a"}}}},
+			{{Key: "$sort", Value: bson.D{{Key: "n", Value: -1}}}},
+			{{Key: "$limit", Value: 1}},
+			{{Key: "$project", Value: bson.D{{Key: "n", Value: 1}}}},
+		},
+		[]bson.D{{{Key: "_id", Value: 3}, {Key: "n", Value: 3}}},
+		"",
+	},
+	"skip and count": {
+		mongo.Pipeline{
+			{{Key: "$skip", Value: 1}},
+			{{Key: "$count", Value: "total"}},
+		},
+		[]bson.D{{{Key: "total", Value: 2}}},
+		"",
+	},
+	"group": {
+		mongo.Pipeline{
+			{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: "$cat"},
+				{Key: "total", Value: bson.D{{Key: "$sum", Value: "$n"}}},
+				{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+				{Key: "avg", Value: bson.D{{Key: "$avg", Value: "$n"}}},
+				{Key: "max", Value: bson.D{{Key: "$max", Value: "$n"}}},
+				{Key: "ids", Value: bson.D{{Key: "$push", Value: "$_id"}}},
+			}}},
+		},
+		[]bson.D{
+			{{Key: "_id", Value: "a"}, {Key: "total", Value: 4}, {Key: "count", Value: 2}, {Key: "avg", Value: 2.0}, {Key: "max", Value: 3}, {Key: "ids", Value: bson.A{1, 3}}},
+			{{Key: "_id", Value: "b"}, {Key: "total", Value: 5}, {Key: "count", Value: 1}, {Key: "avg", Value: 5.0}, {Key: "max", Value: 5}, {Key: "ids", Value: bson.A{2}}},
+		},
+		"",
+	},
+	"unwind": {
+		mongo.Pipeline{
+			{{Key: "$unwind", Value: "$tags"}},
+			{{Key: "$project", Value: bson.D{{Key: "_id", Value: 0}, {Key: "tags", Value: 1}}}},
+		},
+		[]bson.D{{{Key: "tags", Value: "x"}}, {{Key: "tags", Value: "y"}}, {{Key: "tags", Value: "y"}}},
+		"",
+	},
+	"addFields": {
+		mongo.Pipeline{
+			{{Key: "$match", Value: bson.D{{Key: "_id", Value: 2}}}},
+			{{Key: "$addFields", Value: bson.D{{Key: "double", Value: bson.D{{Key: "$multiply", Value: bson.A{"$n", 2}}}}}}},
+			{{Key: "$project", Value: bson.D{{Key: "double", Value: 1}}}},
+		},
+		[]bson.D{{{Key: "_id", Value: 2}, {Key: "double", Value: 10}}},
+		"",
+	},
+	"unknown stage": {
+		mongo.Pipeline{{{Key: "$foo", Value: 1}}},
+		nil,
+		"Unrecognized pipeline stage name: '$foo'",
+	},
+}
+
+func Test_Aggregate(t *testing.T) {
+	docs := make([]bson.D, len(aggregateDocs))
+	for i, d := range aggregateDocs {
+		docs[i], _ = toDoc(d)
+	}
+
+	for tn, tt := range aggregateTests {
+		stages, err := toDocs(tt.pipeline)
+		assert.Nilf(t, err, "Expected nil err for toDocs on test '%s'", tn)
+
+		res, err := aggregate(docs, stages)
+
+		if tt.err == "" {
+			assert.Nilf(t, err, "Expected nil err for aggregate on test '%s'", tn)
+			expected := make([]bson.D, len(tt.expected))
+			for i, d := range tt.expected {
+				expected[i], _ = toDoc(d)
+			}
+			assert.Equalf(t, expected, res, "Expected aggregate results on test '%s'", tn)
+		} else {
+			assert.EqualErrorf(t, err, tt.err, "Expected error for aggregate on test '%s'", tn)
+		}
+	}
+}
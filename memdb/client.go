@@ -0,0 +1,384 @@
+// Package memdb is an in-memory stand-in for a MongoDB server, implementing
+// the base package's client, database and collection interfaces so helpers,
+// seeders and migrations can be tested without running mongod.
+//
+// It covers query filters, sorting, skip and limit, projections, the common
+// update operators, upserts, unique indexes and the simpler aggregation
+// stages. Errors are returned as the driver's own error types with the
+// server's codes, so code checking for duplicate keys or missing documents
+// behaves the same against either.
+package memdb
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// collectionData holds the documents, indexes and options of a collection
+type collectionData struct {
+	docs    []bson.D
+	indexes []*index
+	// options are those given to create and collMod, such as the validator,
+	// which are stored but not enforced
+	options bson.D
+}
+
+// Client is an in-memory MongoClient. Databases and collections are created
+// on first write and live as long as the client.
+type Client struct {
+	mu           sync.RWMutex
+	dbs          map[string]map[string]*collectionData
+	disconnected bool
+}
+
+// Connect does nothing, the client is connected until Disconnect is called
+func (c *Client) Connect(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disconnected = false
+	return nil
+}
+
+// Disconnect marks the client as disconnected so Ping fails. The data is
+// kept and can still be read and written.
+func (c *Client) Disconnect(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disconnected = true
+	return nil
+}
+
+// Ping succeeds unless the context is done or the client is disconnected
+func (c *Client) Ping(ctx context.Context, rp *readpref.ReadPref) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.disconnected {
+		return mongo.ErrClientDisconnected
+	}
+	return nil
+}
+
+// ListDatabaseNames gets the sorted names of the databases with
+// collections matching the filter
+func (c *Client) ListDatabaseNames(ctx context.Context, filter interface{}, opts ...*options.ListDatabasesOptions) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := toDoc(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.dbs))
+	for name, colls := range c.dbs {
+		if len(colls) == 0 {
+			continue
+		}
+		m, err := matches(bson.D{{Key: "name", Value: name}, {Key: "empty", Value: false}}, f)
+		if err != nil {
+			return nil, commandError(err)
+		}
+		if m {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Database gets a handle for the named database
+func (c *Client) Database(name string, opts ...*options.DatabaseOptions) base.MongoDB {
+	return &database{client: c, name: name}
+}
+
+// NewClient gets a new in-memory client with no databases
+func NewClient() *Client {
+	return &Client{dbs: make(map[string]map[string]*collectionData)}
+}
+
+// NewDatabase gets an empty database on its own in-memory client
+func NewDatabase(name string) base.MongoDB {
+	return NewClient().Database(name)
+}
+
+type database struct {
+	client *Client
+	name   string
+}
+
+func (db *database) Name() string {
+	return db.name
+}
+
+// Client gets the client the database belongs to
+func (db *database) Client() base.MongoClient {
+	return db.client
+}
+
+func (db *database) Collection(name string, opts ...*options.CollectionOptions) base.MongoCollection {
+	return &collection{client: db.client, db: db.name, name: name}
+}
+
+// RunCommand runs the ping, create, collMod, listCollections,
+// renameCollection, drop and dropDatabase commands
+func (db *database) RunCommand(ctx context.Context, cmd interface{}, opts ...*options.RunCmdOptions) base.SingleResult {
+	if err := ctx.Err(); err != nil {
+		return &singleResult{err: err}
+	}
+	doc, err := toDoc(cmd)
+	if err != nil {
+		return &singleResult{err: err}
+	}
+	if len(doc) == 0 {
+		return &singleResult{err: commandError(badValue("empty command"))}
+	}
+
+	res, err := db.runCommand(doc)
+	if err != nil {
+		return &singleResult{err: commandError(err)}
+	}
+	return &singleResult{doc: res}
+}
+
+func (db *database) runCommand(cmd bson.D) (bson.D, error) {
+	ok := bson.D{{Key: "ok", Value: float64(1)}}
+	name, _ := cmd[0].Value.(string)
+
+	switch cmd[0].Key {
+	case "ping":
+		return ok, nil
+	case "create":
+		c := &collection{client: db.client, db: db.name, name: name}
+		if err := c.validate(); err != nil {
+			return nil, err
+		}
+		db.client.mu.Lock()
+		defer db.client.mu.Unlock()
+		if c.data(false) != nil {
+			return nil, newError(codeNamespaceExists, "NamespaceExists", "Collection already exists. NS: %s", c.namespace())
+		}
+		c.data(true).options = cmd[1:]
+		return ok, nil
+	case "collMod":
+		c := &collection{client: db.client, db: db.name, name: name}
+		db.client.mu.Lock()
+		defer db.client.mu.Unlock()
+		data := c.data(false)
+		if data == nil {
+			return nil, newError(codeNamespaceNotFound, "NamespaceNotFound", "ns does not exist")
+		}
+		for _, opt := range cmd[1:] {
+			data.options = setOption(data.options, opt)
+		}
+		return ok, nil
+	case "listCollections":
+		return db.listCollections(cmd)
+	case "renameCollection":
+		return db.renameCollection(cmd)
+	case "drop":
+		c := &collection{client: db.client, db: db.name, name: name}
+		db.client.mu.Lock()
+		defer db.client.mu.Unlock()
+		data := c.data(false)
+		if data == nil {
+			return nil, newError(codeNamespaceNotFound, "NamespaceNotFound", "ns not found")
+		}
+		delete(db.client.dbs[db.name], name)
+		return bson.D{
+			{Key: "nIndexesWas", Value: int32(len(data.indexes))},
+			{Key: "ns", Value: c.namespace()},
+			{Key: "ok", Value: float64(1)},
+		}, nil
+	case "dropDatabase":
+		db.client.mu.Lock()
+		defer db.client.mu.Unlock()
+		delete(db.client.dbs, db.name)
+		return ok, nil
+	}
+	return nil, newError(codeCommandNotFound, "CommandNotFound", "no such command: '%s'", cmd[0].Key)
+}
+
+// listCollections gets the collections matching the command's filter as a
+// single batch cursor
+func (db *database) listCollections(cmd bson.D) (bson.D, error) {
+	var filter bson.D
+	for _, e := range cmd[1:] {
+		if e.Key == "filter" && e.Value != nil {
+			f, ok := e.Value.(bson.D)
+			if !ok {
+				return nil, newError(codeTypeMismatch, "TypeMismatch", "BSON field 'listCollections.filter' is the wrong type")
+			}
+			filter = f
+		}
+	}
+
+	db.client.mu.RLock()
+	defer db.client.mu.RUnlock()
+
+	names := make([]string, 0, len(db.client.dbs[db.name]))
+	for name := range db.client.dbs[db.name] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	batch := bson.A{}
+	for _, name := range names {
+		opts := bson.D{}
+		if o := db.client.dbs[db.name][name].options; o != nil {
+			opts = cloneDoc(o)
+		}
+		info := bson.D{
+			{Key: "name", Value: name},
+			{Key: "type", Value: collectionType(opts)},
+			{Key: "options", Value: opts},
+		}
+		m, err := matches(info, filter)
+		if err != nil {
+			return nil, err
+		}
+		if m {
+			batch = append(batch, info)
+		}
+	}
+	return bson.D{
+		{Key: "cursor", Value: bson.D{
+			{Key: "id", Value: int64(0)},
+			{Key: "ns", Value: db.name + ".$cmd.listCollections"},
+			{Key: "firstBatch", Value: batch},
+		}},
+		{Key: "ok", Value: float64(1)},
+	}, nil
+}
+
+// collectionType gets the type listed for a collection with the options
+func collectionType(opts bson.D) string {
+	for _, e := range opts {
+		switch e.Key {
+		case "viewOn":
+			return "view"
+		case "timeseries":
+			return "timeseries"
+		}
+	}
+	return "collection"
+}
+
+// renameCollection moves a collection to another name, which may be in
+// another database. It must be run against the admin database.
+func (db *database) renameCollection(cmd bson.D) (bson.D, error) {
+	if db.name != "admin" {
+		return nil, newError(codeUnauthorized, "Unauthorized", "renameCollection may only be run against the admin database.")
+	}
+	from, _ := cmd[0].Value.(string)
+	var to string
+	var dropTarget bool
+	for _, e := range cmd[1:] {
+		switch e.Key {
+		case "to":
+			to, _ = e.Value.(string)
+		case "dropTarget":
+			dropTarget, _ = e.Value.(bool)
+		}
+	}
+	source, ok := splitNamespace(from)
+	if !ok {
+		return nil, newError(codeInvalidNamespace, "InvalidNamespace", "Invalid source namespace: %s", from)
+	}
+	target, ok := splitNamespace(to)
+	if !ok || target.validate() != nil {
+		return nil, newError(codeInvalidNamespace, "InvalidNamespace", "Invalid target namespace: %s", to)
+	}
+	source.client, target.client = db.client, db.client
+
+	db.client.mu.Lock()
+	defer db.client.mu.Unlock()
+	data := source.data(false)
+	if data == nil {
+		return nil, newError(codeNamespaceNotFound, "NamespaceNotFound", "Source collection %s does not exist", from)
+	}
+	if target.data(false) != nil {
+		if !dropTarget {
+			return nil, newError(codeNamespaceExists, "NamespaceExists", "target namespace exists")
+		}
+		delete(db.client.dbs[target.db], target.name)
+	}
+	delete(db.client.dbs[source.db], source.name)
+	*target.data(true) = *data
+	return bson.D{{Key: "ok", Value: float64(1)}}, nil
+}
+
+// splitNamespace gets the collection in a "db.collection" namespace
+func splitNamespace(ns string) (*collection, bool) {
+	i := strings.Index(ns, ".")
+	if i <= 0 || i == len(ns)-1 {
+		return nil, false
+	}
+	return &collection{db: ns[:i], name: ns[i+1:]}, true
+}
+
+// setOption sets the option, replacing any with the same key
+func setOption(opts bson.D, opt bson.E) bson.D {
+	for i, e := range opts {
+		if e.Key == opt.Key {
+			opts[i] = opt
+			return opts
+		}
+	}
+	return append(opts, opt)
+}
+
+// ListCollectionNames gets the sorted names of the collections matching the filter
+func (db *database) ListCollectionNames(ctx context.Context, filter interface{}, opts ...*options.ListCollectionsOptions) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := toDoc(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	db.client.mu.RLock()
+	defer db.client.mu.RUnlock()
+
+	names := make([]string, 0, len(db.client.dbs[db.name]))
+	for name := range db.client.dbs[db.name] {
+		m, err := matches(bson.D{{Key: "name", Value: name}, {Key: "type", Value: "collection"}}, f)
+		if err != nil {
+			return nil, commandError(err)
+		}
+		if m {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (db *database) Drop(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	db.client.mu.Lock()
+	defer db.client.mu.Unlock()
+	delete(db.client.dbs, db.name)
+	return nil
+}
+
+// validName reports whether a collection name would be accepted by the server
+func validName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, "$\x00") && !strings.HasPrefix(name, ".")
+}
@@ -0,0 +1,214 @@
+package memdb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+var runCommandTests = map[string]struct {
+	cmd interface{}
+	err string
+}{
+	"ping":            {bson.D{{Key: "ping", Value: 1}}, ""},
+	"create":          {bson.D{{Key: "create", Value: "new"}}, ""},
+	"create existing": {bson.D{{Key: "create", Value: "existing"}}, "(NamespaceExists) Collection already exists. NS: test.existing"},
+	"create invalid":  {bson.D{{Key: "create", Value: "$bad"}}, "(InvalidNamespace) Invalid collection name specified 'test.$bad'"},
+	"collMod":         {bson.D{{Key: "collMod", Value: "existing"}, {Key: "validationLevel", Value: "moderate"}}, ""},
+	"collMod missing": {bson.D{{Key: "collMod", Value: "missing"}}, "(NamespaceNotFound) ns does not exist"},
+	"listCollections": {bson.D{{Key: "listCollections", Value: 1}}, ""},
+	"drop":            {bson.D{{Key: "drop", Value: "existing"}}, ""},
+	"drop missing":    {bson.D{{Key: "drop", Value: "missing"}}, "(NamespaceNotFound) ns not found"},
+	"unknown":         {bson.D{{Key: "foo", Value: 1}}, "(CommandNotFound) no such command: 'foo'"},
+	"empty":           {bson.D{}, "(BadValue) empty command"},
+	"drop database":   {bson.D{{Key: "dropDatabase", Value: 1}}, ""},
+}
+
+func Test_RunCommand(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range runCommandTests {
+		db := NewDatabase("test")
+		db.Collection("existing").InsertOne(ctx, bson.M{"a": 1})
+
+		var res bson.M
+		err := db.RunCommand(ctx, tt.cmd).Decode(&res)
+
+		if tt.err == "" {
+			assert.Nilf(t, err, "Expected nil err for RunCommand on test '%s'", tn)
+			assert.Equalf(t, 1.0, res["ok"], "Expected ok result for RunCommand on test '%s'", tn)
+		} else {
+			assert.EqualErrorf(t, err, tt.err, "Expected error for RunCommand on test '%s'", tn)
+		}
+	}
+}
+
+func Test_ListCollectionNames(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient()
+	db := client.Database("test")
+	db.Collection("b").InsertOne(ctx, bson.M{"a": 1})
+	db.Collection("a").InsertOne(ctx, bson.M{"a": 1})
+	db.Collection("unused")
+	client.Database("other").Collection("c").InsertOne(ctx, bson.M{"a": 1})
+
+	names, err := db.ListCollectionNames(ctx, bson.M{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b"}, names)
+
+	names, _ = db.ListCollectionNames(ctx, bson.M{"name": "b"})
+	assert.Equal(t, []string{"b"}, names)
+
+	db.Collection("a").Drop(ctx)
+	names, _ = db.ListCollectionNames(ctx, bson.M{})
+	assert.Equal(t, []string{"b"}, names)
+
+	db.Drop(ctx)
+	names, _ = db.ListCollectionNames(ctx, bson.M{})
+	assert.Equal(t, []string{}, names)
+	names, _ = client.Database("other").ListCollectionNames(ctx, bson.M{})
+	assert.Equal(t, []string{"c"}, names, "Expected other databases to be kept")
+}
+
+func Test_ListDatabaseNames(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient()
+	client.Database("tenant_b").Collection("a").InsertOne(ctx, bson.M{"a": 1})
+	client.Database("tenant_a").Collection("a").InsertOne(ctx, bson.M{"a": 1})
+	client.Database("other").Collection("a").InsertOne(ctx, bson.M{"a": 1})
+	client.Database("unused")
+
+	names, err := client.ListDatabaseNames(ctx, bson.M{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"other", "tenant_a", "tenant_b"}, names)
+
+	names, _ = client.ListDatabaseNames(ctx, bson.M{"name": bson.M{"$regex": "^tenant_"}})
+	assert.Equal(t, []string{"tenant_a", "tenant_b"}, names)
+
+	client.Database("tenant_b").Drop(ctx)
+	names, _ = client.ListDatabaseNames(ctx, bson.M{"name": bson.M{"$regex": "^tenant_"}})
+	assert.Equal(t, []string{"tenant_a"}, names)
+}
+
+func Test_ListCollections(t *testing.T) {
+	ctx := context.Background()
+	db := NewDatabase("test")
+	db.Collection("plain").InsertOne(ctx, bson.M{"a": 1})
+	validator := bson.D{{Key: "$jsonSchema", Value: bson.D{{Key: "required", Value: bson.A{"a"}}}}}
+	db.RunCommand(ctx, bson.D{{Key: "create", Value: "validated"}, {Key: "validator", Value: validator}})
+	db.RunCommand(ctx, bson.D{{Key: "collMod", Value: "validated"}, {Key: "validationAction", Value: "warn"}})
+	db.RunCommand(ctx, bson.D{{Key: "create", Value: "view"}, {Key: "viewOn", Value: "plain"}, {Key: "pipeline", Value: bson.A{}}})
+
+	var res struct {
+		Cursor struct {
+			FirstBatch []bson.M `bson:"firstBatch"`
+		} `bson:"cursor"`
+	}
+	err := db.RunCommand(ctx, bson.D{{Key: "listCollections", Value: 1}}).Decode(&res)
+	assert.Nil(t, err)
+	assert.Equal(t, []bson.M{
+		{"name": "plain", "type": "collection", "options": bson.M{}},
+		{"name": "validated", "type": "collection", "options": bson.M{
+			"validator":        bson.M{"$jsonSchema": bson.M{"required": bson.A{"a"}}},
+			"validationAction": "warn",
+		}},
+		{"name": "view", "type": "view", "options": bson.M{"viewOn": "plain", "pipeline": bson.A{}}},
+	}, res.Cursor.FirstBatch)
+
+	err = db.RunCommand(ctx, bson.D{{Key: "listCollections", Value: 1}, {Key: "filter", Value: bson.D{{Key: "name", Value: "plain"}}}}).Decode(&res)
+	assert.Nil(t, err)
+	assert.Len(t, res.Cursor.FirstBatch, 1, "Expected the collections to be filtered")
+	assert.Equal(t, "plain", res.Cursor.FirstBatch[0]["name"])
+}
+
+var renameCollectionTests = map[string]struct {
+	db       string
+	cmd      bson.D
+	expected []string
+	err      string
+}{
+	"rename": {
+		"admin",
+		bson.D{{Key: "renameCollection", Value: "test.existing"}, {Key: "to", Value: "test.renamed"}},
+		[]string{"other", "renamed"},
+		"",
+	},
+	"missing source": {
+		"admin",
+		bson.D{{Key: "renameCollection", Value: "test.missing"}, {Key: "to", Value: "test.renamed"}},
+		[]string{"existing", "other"},
+		"(NamespaceNotFound) Source collection test.missing does not exist",
+	},
+	"existing target": {
+		"admin",
+		bson.D{{Key: "renameCollection", Value: "test.existing"}, {Key: "to", Value: "test.other"}},
+		[]string{"existing", "other"},
+		"(NamespaceExists) target namespace exists",
+	},
+	"drop target": {
+		"admin",
+		bson.D{{Key: "renameCollection", Value: "test.existing"}, {Key: "to", Value: "test.other"}, {Key: "dropTarget", Value: true}},
+		[]string{"other"},
+		"",
+	},
+	"invalid target": {
+		"admin",
+		bson.D{{Key: "renameCollection", Value: "test.existing"}, {Key: "to", Value: "test.$bad"}},
+		[]string{"existing", "other"},
+		"(InvalidNamespace) Invalid target namespace: test.$bad",
+	},
+	"not admin": {
+		"test",
+		bson.D{{Key: "renameCollection", Value: "test.existing"}, {Key: "to", Value: "test.renamed"}},
+		[]string{"existing", "other"},
+		"(Unauthorized) renameCollection may only be run against the admin database.",
+	},
+}
+
+func Test_RenameCollection(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range renameCollectionTests {
+		client := NewClient()
+		db := client.Database("test")
+		db.Collection("existing").InsertOne(ctx, bson.M{"a": 1})
+		db.Collection("other").InsertOne(ctx, bson.M{"a": 2})
+
+		err := client.Database(tt.db).RunCommand(ctx, tt.cmd).Err()
+
+		if tt.err == "" {
+			assert.Nilf(t, err, "Expected nil err on test '%s'", tn)
+		} else {
+			assert.EqualErrorf(t, err, tt.err, "Expected error on test '%s'", tn)
+		}
+		names, _ := db.ListCollectionNames(ctx, bson.M{})
+		assert.Equalf(t, tt.expected, names, "Expected the collections to match on test '%s'", tn)
+	}
+
+	// Documents move with the collection
+	client := NewClient()
+	db := client.Database("test")
+	db.Collection("existing").InsertOne(ctx, bson.M{"a": 1})
+	client.Database("admin").RunCommand(ctx, bson.D{{Key: "renameCollection", Value: "test.existing"}, {Key: "to", Value: "test.renamed"}})
+	count, _ := db.Collection("renamed").CountDocuments(ctx, bson.M{"a": 1})
+	assert.Equal(t, int64(1), count, "Expected the documents to be renamed")
+}
+
+func Test_Ping(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient()
+
+	assert.Nil(t, client.Ping(ctx, readpref.Primary()), "Expected a new client to be connected")
+	client.Disconnect(ctx)
+	assert.Equal(t, mongo.ErrClientDisconnected, client.Ping(ctx, nil), "Expected ping to fail once disconnected")
+	client.Connect(ctx)
+	assert.Nil(t, client.Ping(ctx, nil), "Expected ping to succeed once reconnected")
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Equal(t, context.Canceled, client.Ping(cancelled, nil), "Expected ping to fail on a cancelled context")
+}
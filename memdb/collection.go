@@ -0,0 +1,638 @@
+package memdb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Kinds of write operation
+const (
+	opInsert = iota
+	opUpdate
+	opReplace
+	opDelete
+)
+
+// writeOp is a write prepared from its driver model
+type writeOp struct {
+	kind   int
+	filter bson.D
+	doc    bson.D
+	multi  bool
+	upsert bool
+}
+
+type collection struct {
+	client *Client
+	db     string
+	name   string
+}
+
+func (c *collection) Name() string {
+	return c.name
+}
+
+func (c *collection) namespace() string {
+	return c.db + "." + c.name
+}
+
+// data gets the collection's data, creating it if asked. The client's lock
+// must be held.
+func (c *collection) data(create bool) *collectionData {
+	colls, ok := c.client.dbs[c.db]
+	if !ok {
+		if !create {
+			return nil
+		}
+		colls = make(map[string]*collectionData)
+		c.client.dbs[c.db] = colls
+	}
+	data, ok := colls[c.name]
+	if !ok && create {
+		data = &collectionData{indexes: []*index{newIDIndex()}}
+		colls[c.name] = data
+	}
+	return data
+}
+
+func (c *collection) validate() error {
+	if !validName(c.name) {
+		return newError(codeInvalidNamespace, "InvalidNamespace", "Invalid collection name specified '%s'", c.namespace())
+	}
+	return nil
+}
+
+// check validates the context and collection before an operation
+func (c *collection) check(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return commandError(c.validate())
+}
+
+// query gets the matching documents, sorted, windowed and projected
+func (c *collection) query(filter bson.D, sortBy interface{}, skip, limit *int64, projection interface{}) ([]bson.D, error) {
+	data := c.data(false)
+	if data == nil {
+		return nil, nil
+	}
+
+	var docs []bson.D
+	for _, d := range data.docs {
+		m, err := matches(d, filter)
+		if err != nil {
+			return nil, err
+		}
+		if m {
+			docs = append(docs, d)
+		}
+	}
+
+	if sortBy != nil {
+		spec, err := toDoc(sortBy)
+		if err != nil {
+			return nil, err
+		}
+		if err = sortDocs(docs, spec); err != nil {
+			return nil, err
+		}
+	}
+	docs = window(docs, skip, limit)
+
+	if projection != nil {
+		spec, err := toDoc(projection)
+		if err != nil {
+			return nil, err
+		}
+		if len(spec) > 0 {
+			for i, d := range docs {
+				if docs[i], err = project(d, spec); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return docs, nil
+}
+
+func (c *collection) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (base.MongoCursor, error) {
+	if err := c.check(ctx); err != nil {
+		return nil, err
+	}
+	f, err := toDoc(filter)
+	if err != nil {
+		return nil, err
+	}
+	o := options.MergeFindOptions(opts...)
+
+	c.client.mu.RLock()
+	defer c.client.mu.RUnlock()
+	docs, err := c.query(f, o.Sort, o.Skip, o.Limit, o.Projection)
+	if err != nil {
+		return nil, commandError(err)
+	}
+	return newCursor(docs), nil
+}
+
+func (c *collection) FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) base.SingleResult {
+	if err := c.check(ctx); err != nil {
+		return &singleResult{err: err}
+	}
+	f, err := toDoc(filter)
+	if err != nil {
+		return &singleResult{err: err}
+	}
+	o := options.MergeFindOneOptions(opts...)
+	one := int64(1)
+
+	c.client.mu.RLock()
+	defer c.client.mu.RUnlock()
+	docs, err := c.query(f, o.Sort, o.Skip, &one, o.Projection)
+	if err != nil {
+		return &singleResult{err: commandError(err)}
+	}
+	if len(docs) == 0 {
+		return &singleResult{err: mongo.ErrNoDocuments}
+	}
+	return &singleResult{doc: docs[0]}
+}
+
+func (c *collection) CountDocuments(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error) {
+	if err := c.check(ctx); err != nil {
+		return 0, err
+	}
+	f, err := toDoc(filter)
+	if err != nil {
+		return 0, err
+	}
+	o := options.MergeCountOptions(opts...)
+
+	c.client.mu.RLock()
+	defer c.client.mu.RUnlock()
+	docs, err := c.query(f, nil, o.Skip, o.Limit, nil)
+	if err != nil {
+		return 0, commandError(err)
+	}
+	return int64(len(docs)), nil
+}
+
+func (c *collection) Aggregate(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (base.MongoCursor, error) {
+	if err := c.check(ctx); err != nil {
+		return nil, err
+	}
+	stages, err := toDocs(pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	c.client.mu.RLock()
+	var docs []bson.D
+	if data := c.data(false); data != nil {
+		docs = append(docs, data.docs...)
+	}
+	c.client.mu.RUnlock()
+
+	docs, err = aggregate(docs, stages)
+	if err != nil {
+		return nil, commandError(err)
+	}
+	return newCursor(docs), nil
+}
+
+func (c *collection) InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error) {
+	if err := c.check(ctx); err != nil {
+		return nil, err
+	}
+	op, err := prepareInsert(document)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = c.write([]writeOp{op}, true); err != nil {
+		return nil, singleWriteError(err)
+	}
+	id, _ := get(op.doc, "_id")
+	return &mongo.InsertOneResult{InsertedID: id}, nil
+}
+
+func (c *collection) InsertMany(ctx context.Context, documents []interface{}, opts ...*options.InsertManyOptions) (*mongo.InsertManyResult, error) {
+	if err := c.check(ctx); err != nil {
+		return nil, err
+	}
+	if len(documents) == 0 {
+		return nil, mongo.ErrEmptySlice
+	}
+	o := options.MergeInsertManyOptions(opts...)
+
+	ops := make([]writeOp, len(documents))
+	res := &mongo.InsertManyResult{InsertedIDs: make([]interface{}, len(documents))}
+	for i, d := range documents {
+		op, err := prepareInsert(d)
+		if err != nil {
+			return nil, err
+		}
+		ops[i] = op
+		res.InsertedIDs[i], _ = get(op.doc, "_id")
+	}
+
+	_, err := c.write(ops, o.Ordered == nil || *o.Ordered)
+	return res, err
+}
+
+func (c *collection) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return c.updateSingle(ctx, filter, update, opUpdate, false, options.MergeUpdateOptions(opts...).Upsert)
+}
+
+func (c *collection) UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return c.updateSingle(ctx, filter, update, opUpdate, true, options.MergeUpdateOptions(opts...).Upsert)
+}
+
+func (c *collection) ReplaceOne(ctx context.Context, filter interface{}, replacement interface{}, opts ...*options.ReplaceOptions) (*mongo.UpdateResult, error) {
+	return c.updateSingle(ctx, filter, replacement, opReplace, false, options.MergeReplaceOptions(opts...).Upsert)
+}
+
+// updateSingle runs one update or replacement
+func (c *collection) updateSingle(ctx context.Context, filter, update interface{}, kind int, multi bool, upsert *bool) (*mongo.UpdateResult, error) {
+	if err := c.check(ctx); err != nil {
+		return nil, err
+	}
+	op, err := prepareUpdate(filter, update, kind, multi, upsert)
+	if err != nil {
+		return nil, err
+	}
+
+	bres, err := c.write([]writeOp{op}, true)
+	if err != nil {
+		return nil, singleWriteError(err)
+	}
+	res := &mongo.UpdateResult{
+		MatchedCount:  bres.MatchedCount,
+		ModifiedCount: bres.ModifiedCount,
+		UpsertedCount: bres.UpsertedCount,
+	}
+	if id, ok := bres.UpsertedIDs[0]; ok {
+		res.UpsertedID = id
+	}
+	return res, nil
+}
+
+func (c *collection) DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	return c.delete(ctx, filter, false)
+}
+
+func (c *collection) DeleteMany(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	return c.delete(ctx, filter, true)
+}
+
+func (c *collection) delete(ctx context.Context, filter interface{}, multi bool) (*mongo.DeleteResult, error) {
+	if err := c.check(ctx); err != nil {
+		return nil, err
+	}
+	f, err := toDoc(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	bres, err := c.write([]writeOp{{kind: opDelete, filter: f, multi: multi}}, true)
+	if err != nil {
+		return nil, singleWriteError(err)
+	}
+	return &mongo.DeleteResult{DeletedCount: bres.DeletedCount}, nil
+}
+
+// BulkWrite runs the writes, in order and stopping at the first error unless
+// unordered
+func (c *collection) BulkWrite(ctx context.Context, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	if err := c.check(ctx); err != nil {
+		return nil, err
+	}
+	if len(models) == 0 {
+		return nil, mongo.ErrEmptySlice
+	}
+	o := options.MergeBulkWriteOptions(opts...)
+
+	ops := make([]writeOp, len(models))
+	for i, m := range models {
+		op, err := prepareModel(m)
+		if err != nil {
+			return nil, err
+		}
+		ops[i] = op
+	}
+
+	res, err := c.write(ops, o.Ordered == nil || *o.Ordered)
+	if bwe, ok := err.(mongo.BulkWriteException); ok {
+		for i := range bwe.WriteErrors {
+			bwe.WriteErrors[i].Request = models[bwe.WriteErrors[i].Index]
+		}
+		return res, bwe
+	}
+	return res, err
+}
+
+// write applies the operations, returning a bulk write exception of any
+// server errors
+func (c *collection) write(ops []writeOp, ordered bool) (*mongo.BulkWriteResult, error) {
+	c.client.mu.Lock()
+	defer c.client.mu.Unlock()
+
+	create := false
+	for _, op := range ops {
+		create = create || op.kind == opInsert || op.upsert
+	}
+	data := c.data(create)
+	if data == nil {
+		data = &collectionData{}
+	}
+
+	res := &mongo.BulkWriteResult{UpsertedIDs: make(map[int64]interface{})}
+	var errs []mongo.BulkWriteError
+	for i, op := range ops {
+		err := c.apply(data, op, res, int64(i))
+		if err == nil {
+			continue
+		}
+		we, ok := writeError(i, err)
+		if !ok {
+			return res, err
+		}
+		errs = append(errs, mongo.BulkWriteError{WriteError: we})
+		if ordered {
+			break
+		}
+	}
+
+	if len(errs) > 0 {
+		return res, mongo.BulkWriteException{WriteErrors: errs}
+	}
+	return res, nil
+}
+
+// apply applies a single write, adding its effect to the result
+func (c *collection) apply(data *collectionData, op writeOp, res *mongo.BulkWriteResult, i int64) error {
+	ns := c.namespace()
+
+	if op.kind == opInsert {
+		if err := data.checkUnique(ns, op.doc, -1); err != nil {
+			return err
+		}
+		data.docs = append(data.docs, cloneDoc(op.doc))
+		res.InsertedCount++
+		return nil
+	}
+
+	var matched []int
+	for n, d := range data.docs {
+		m, err := matches(d, op.filter)
+		if err != nil {
+			return err
+		}
+		if m {
+			matched = append(matched, n)
+			if !op.multi {
+				break
+			}
+		}
+	}
+
+	if op.kind == opDelete {
+		for n := len(matched) - 1; n >= 0; n-- {
+			data.docs = append(data.docs[:matched[n]:matched[n]], data.docs[matched[n]+1:]...)
+		}
+		res.DeletedCount += int64(len(matched))
+		return nil
+	}
+
+	for _, n := range matched {
+		res.MatchedCount++
+		updated, err := modify(data.docs[n], op)
+		if err != nil {
+			return err
+		}
+		if sameDoc(data.docs[n], updated) {
+			continue
+		}
+		if err = data.checkUnique(ns, updated, n); err != nil {
+			return err
+		}
+		data.docs[n] = updated
+		res.ModifiedCount++
+	}
+
+	if len(matched) == 0 && op.upsert {
+		doc, err := upsertDoc(op)
+		if err != nil {
+			return err
+		}
+		if err = data.checkUnique(ns, doc, -1); err != nil {
+			return err
+		}
+		data.docs = append(data.docs, doc)
+		res.UpsertedCount++
+		res.UpsertedIDs[i], _ = get(doc, "_id")
+	}
+	return nil
+}
+
+// modify applies an update or replacement to a stored document
+func modify(doc bson.D, op writeOp) (bson.D, error) {
+	if op.kind == opReplace {
+		return replaceDoc(doc, op.doc)
+	}
+	return applyUpdate(doc, op.doc, false)
+}
+
+// upsertDoc builds the document inserted by an upsert
+func upsertDoc(op writeOp) (bson.D, error) {
+	seed, err := upsertSeed(op.filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc bson.D
+	if op.kind == opReplace {
+		doc = cloneDoc(op.doc)
+		if id, ok := get(seed, "_id"); ok {
+			if _, has := get(doc, "_id"); !has {
+				doc = set(doc, "_id", id)
+			}
+		}
+	} else if doc, err = applyUpdate(seed, op.doc, true); err != nil {
+		return nil, err
+	}
+	return ensureID(doc), nil
+}
+
+// ensureID gives the document a new ObjectID if it has no _id, and puts
+// the _id first
+func ensureID(doc bson.D) bson.D {
+	if _, ok := get(doc, "_id"); !ok {
+		return append(bson.D{{Key: "_id", Value: primitive.NewObjectID()}}, doc...)
+	}
+	return withIDFirst(doc)
+}
+
+func prepareInsert(document interface{}) (writeOp, error) {
+	if document == nil {
+		return writeOp{}, mongo.ErrNilDocument
+	}
+	doc, err := toDoc(document)
+	if err != nil {
+		return writeOp{}, err
+	}
+	return writeOp{kind: opInsert, doc: ensureID(doc)}, nil
+}
+
+func prepareUpdate(filter, update interface{}, kind int, multi bool, upsert *bool) (writeOp, error) {
+	f, err := toDoc(filter)
+	if err != nil {
+		return writeOp{}, err
+	}
+	u, err := toDoc(update)
+	if err != nil {
+		return writeOp{}, err
+	}
+	if kind == opReplace {
+		err = checkReplacement(u)
+	} else {
+		err = checkUpdate(u)
+	}
+	if err != nil {
+		return writeOp{}, err
+	}
+	return writeOp{kind: kind, filter: f, doc: u, multi: multi, upsert: upsert != nil && *upsert}, nil
+}
+
+// prepareModel prepares a bulk write model
+func prepareModel(m mongo.WriteModel) (writeOp, error) {
+	switch t := m.(type) {
+	case *mongo.InsertOneModel:
+		return prepareInsert(t.Document)
+	case *mongo.UpdateOneModel:
+		return prepareUpdate(t.Filter, t.Update, opUpdate, false, t.Upsert)
+	case *mongo.UpdateManyModel:
+		return prepareUpdate(t.Filter, t.Update, opUpdate, true, t.Upsert)
+	case *mongo.ReplaceOneModel:
+		return prepareUpdate(t.Filter, t.Replacement, opReplace, false, t.Upsert)
+	case *mongo.DeleteOneModel:
+		f, err := toDoc(t.Filter)
+		return writeOp{kind: opDelete, filter: f}, err
+	case *mongo.DeleteManyModel:
+		f, err := toDoc(t.Filter)
+		return writeOp{kind: opDelete, filter: f, multi: true}, err
+	}
+	return writeOp{}, fmt.Errorf("unsupported write model %T", m)
+}
+
+// FindOneAndUpdate updates the first match in sort order, getting the
+// document from before the update unless asked for the one after
+func (c *collection) FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) base.SingleResult {
+	if err := c.check(ctx); err != nil {
+		return &singleResult{err: err}
+	}
+	o := options.MergeFindOneAndUpdateOptions(opts...)
+	op, err := prepareUpdate(filter, update, opUpdate, false, o.Upsert)
+	if err != nil {
+		return &singleResult{err: err}
+	}
+	after := o.ReturnDocument != nil && *o.ReturnDocument == options.After
+
+	c.client.mu.Lock()
+	defer c.client.mu.Unlock()
+	data := c.data(true)
+
+	doc, err := c.findAndModify(data, op, o.Sort, after)
+	if err != nil {
+		return &singleResult{err: commandError(err)}
+	}
+	if doc == nil {
+		return &singleResult{err: mongo.ErrNoDocuments}
+	}
+	if o.Projection != nil {
+		spec, err := toDoc(o.Projection)
+		if err != nil {
+			return &singleResult{err: err}
+		}
+		if doc, err = project(doc, spec); err != nil {
+			return &singleResult{err: commandError(err)}
+		}
+	}
+	return &singleResult{doc: doc}
+}
+
+func (c *collection) findAndModify(data *collectionData, op writeOp, sortBy interface{}, after bool) (bson.D, error) {
+	var matched []int
+	for n, d := range data.docs {
+		m, err := matches(d, op.filter)
+		if err != nil {
+			return nil, err
+		}
+		if m {
+			matched = append(matched, n)
+		}
+	}
+
+	if len(matched) == 0 {
+		if !op.upsert {
+			return nil, nil
+		}
+		doc, err := upsertDoc(op)
+		if err != nil {
+			return nil, err
+		}
+		if err = data.checkUnique(c.namespace(), doc, -1); err != nil {
+			return nil, err
+		}
+		data.docs = append(data.docs, doc)
+		if after {
+			return doc, nil
+		}
+		return nil, nil
+	}
+
+	if sortBy != nil {
+		spec, err := toDoc(sortBy)
+		if err != nil {
+			return nil, err
+		}
+		if err = sortPositions(data.docs, matched, spec); err != nil {
+			return nil, err
+		}
+	}
+
+	n := matched[0]
+	before := data.docs[n]
+	updated, err := modify(before, op)
+	if err != nil {
+		return nil, err
+	}
+	if err = data.checkUnique(c.namespace(), updated, n); err != nil {
+		return nil, err
+	}
+	data.docs[n] = updated
+	if after {
+		return updated, nil
+	}
+	return before, nil
+}
+
+func (c *collection) Indexes() base.MongoIndexView {
+	return &indexView{coll: c}
+}
+
+func (c *collection) Drop(ctx context.Context) error {
+	if err := c.check(ctx); err != nil {
+		return err
+	}
+	c.client.mu.Lock()
+	defer c.client.mu.Unlock()
+	if colls, ok := c.client.dbs[c.db]; ok {
+		delete(colls, c.name)
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package memdb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type exampleItem struct {
+	ID    primitive.ObjectID `bson:"_id,omitempty"`
+	Name  string             `bson:"name"`
+	Price int                `bson:"price"`
+}
+
+func setupCollection(ctx context.Context) base.MongoCollection {
+	c := NewDatabase("test").Collection("items")
+	c.InsertMany(ctx, []interface{}{
+		exampleItem{Name: "c", Price: 30},
+		exampleItem{Name: "a", Price: 10},
+		exampleItem{Name: "b", Price: 20},
+	})
+	return c
+}
+
+var findTests = map[string]struct {
+	filter   interface{}
+	opts     *options.FindOptions
+	expected []string
+}{
+	"natural order": {bson.M{}, options.Find(), []string{"c", "a", "b"}},
+	"filtered":      {bson.M{"price": bson.M{"$gte": 20}}, options.Find(), []string{"c", "b"}},
+	"sorted":        {bson.M{}, options.Find().SetSort(bson.M{"name": 1}), []string{"a", "b", "c"}},
+	"sorted desc":   {bson.M{}, options.Find().SetSort(bson.D{{Key: "price", Value: -1}}), []string{"c", "b", "a"}},
+	"skip limit":    {bson.M{}, options.Find().SetSort(bson.M{"name": 1}).SetSkip(1).SetLimit(1), []string{"b"}},
+	"no matches":    {bson.M{"name": "z"}, options.Find(), []string{}},
+	"nil filter":    {nil, options.Find(), []string{"c", "a", "b"}},
+}
+
+func Test_Collection_Find(t *testing.T) {
+	ctx := context.Background()
+	c := setupCollection(ctx)
+
+	for tn, tt := range findTests {
+		cur, err := c.Find(ctx, tt.filter, tt.opts)
+		assert.Nilf(t, err, "Expected nil err for Find on test '%s'", tn)
+
+		var items []exampleItem
+		err = cur.All(ctx, &items)
+		assert.Nilf(t, err, "Expected nil err for All on test '%s'", tn)
+
+		names := make([]string, 0, len(items))
+		for _, i := range items {
+			assert.Falsef(t, i.ID.IsZero(), "Expected items to have been given IDs on test '%s'", tn)
+			names = append(names, i.Name)
+		}
+		assert.Equalf(t, tt.expected, names, "Expected names to match on test '%s'", tn)
+	}
+}
+
+func Test_Collection_FindOne(t *testing.T) {
+	ctx := context.Background()
+	c := setupCollection(ctx)
+
+	var item exampleItem
+	err := c.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.M{"price": -1})).Decode(&item)
+	assert.Nil(t, err)
+	assert.Equal(t, "c", item.Name)
+
+	err = c.FindOne(ctx, bson.M{"name": "z"}).Decode(&item)
+	assert.Equal(t, mongo.ErrNoDocuments, err)
+
+	var projected bson.M
+	err = c.FindOne(ctx, bson.M{"name": "a"}, options.FindOne().SetProjection(bson.M{"_id": 0, "name": 1})).Decode(&projected)
+	assert.Nil(t, err)
+	assert.Equal(t, bson.M{"name": "a"}, projected)
+}
+
+func Test_Collection_CountDocuments(t *testing.T) {
+	ctx := context.Background()
+	c := setupCollection(ctx)
+
+	n, err := c.CountDocuments(ctx, bson.M{"price": bson.M{"$lt": 25}})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+
+	n, err = c.CountDocuments(ctx, bson.M{}, options.Count().SetSkip(1).SetLimit(1))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), n)
+}
+
+func Test_Collection_Updates(t *testing.T) {
+	ctx := context.Background()
+	c := setupCollection(ctx)
+
+	res, err := c.UpdateMany(ctx, bson.M{"price": bson.M{"$gt": 15}}, bson.M{"$inc": bson.M{"price": 1}})
+	assert.Nil(t, err)
+	assert.Equal(t, &mongo.UpdateResult{MatchedCount: 2, ModifiedCount: 2}, res)
+
+	res, err = c.UpdateOne(ctx, bson.M{"name": "a"}, bson.M{"$set": bson.M{"price": 10}})
+	assert.Nil(t, err)
+	assert.Equal(t, &mongo.UpdateResult{MatchedCount: 1, ModifiedCount: 0}, res, "Expected no modification when unchanged")
+
+	res, err = c.UpdateOne(ctx, bson.M{"name": "d"}, bson.M{"$set": bson.M{"price": 40}}, options.Update().SetUpsert(true))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), res.UpsertedCount)
+	assert.IsType(t, primitive.ObjectID{}, res.UpsertedID)
+
+	var item exampleItem
+	c.FindOne(ctx, bson.M{"name": "d"}).Decode(&item)
+	assert.Equal(t, 40, item.Price, "Expected the upsert to take the filter's fields")
+
+	res, err = c.ReplaceOne(ctx, bson.M{"name": "c"}, exampleItem{Name: "c2", Price: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), res.ModifiedCount)
+	n, _ := c.CountDocuments(ctx, bson.M{"name": "c2"})
+	assert.Equal(t, int64(1), n)
+
+	_, err = c.UpdateOne(ctx, bson.M{}, bson.M{"price": 1})
+	assert.EqualError(t, err, "update document must contain key beginning with '$'")
+
+	_, err = c.ReplaceOne(ctx, bson.M{}, bson.M{"$set": bson.M{"price": 1}})
+	assert.EqualError(t, err, "replacement document cannot contain keys beginning with '$'")
+}
+
+func Test_Collection_Deletes(t *testing.T) {
+	ctx := context.Background()
+	c := setupCollection(ctx)
+
+	res, err := c.DeleteOne(ctx, bson.M{"price": bson.M{"$gt": 0}})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), res.DeletedCount)
+
+	res, err = c.DeleteMany(ctx, bson.M{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), res.DeletedCount)
+}
+
+func Test_Collection_FindOneAndUpdate(t *testing.T) {
+	ctx := context.Background()
+	c := NewDatabase("test").Collection("counters")
+	opt := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var doc struct {
+		Seq int64 `bson:"seq"`
+	}
+	for i := int64(1); i <= 3; i++ {
+		err := c.FindOneAndUpdate(ctx, bson.M{"_id": "orders"}, bson.M{"$inc": bson.M{"seq": int64(1)}}, opt).Decode(&doc)
+		assert.Nil(t, err)
+		assert.Equal(t, i, doc.Seq)
+	}
+
+	err := c.FindOneAndUpdate(ctx, bson.M{"_id": "orders"}, bson.M{"$set": bson.M{"seq": int64(10)}}).Decode(&doc)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), doc.Seq, "Expected the document from before the update by default")
+
+	err = c.FindOneAndUpdate(ctx, bson.M{"_id": "missing"}, bson.M{"$set": bson.M{"seq": 1}}).Err()
+	assert.Equal(t, mongo.ErrNoDocuments, err)
+}
+
+func Test_Collection_UniqueIndex(t *testing.T) {
+	ctx := context.Background()
+	c := setupCollection(ctx)
+
+	name, err := c.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "name_1", name)
+
+	_, err = c.InsertOne(ctx, exampleItem{Name: "a"})
+	if assert.IsType(t, mongo.WriteException{}, err) {
+		we := err.(mongo.WriteException).WriteErrors[0]
+		assert.Equal(t, 11000, we.Code)
+		assert.Equal(t, `E11000 duplicate key error collection: test.items index: name_1 dup key: { name: "a" }`, we.Message)
+	}
+
+	_, err = c.UpdateOne(ctx, bson.M{"name": "b"}, bson.M{"$set": bson.M{"name": "a"}})
+	assert.IsType(t, mongo.WriteException{}, err, "Expected updates to be checked against unique indexes")
+
+	_, err = c.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "price", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("price_unique"),
+	})
+	assert.Nil(t, err)
+	c.InsertOne(ctx, bson.M{"name": "x", "price": 99})
+	_, err = c.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "other", Value: 1}}, Options: options.Index().SetUnique(true)})
+	if assert.IsType(t, mongo.CommandError{}, err, "Expected existing duplicates to fail index creation") {
+		assert.Equal(t, int32(11000), err.(mongo.CommandError).Code)
+	}
+
+	var specs []bson.M
+	cur, err := c.Indexes().List(ctx)
+	assert.Nil(t, err)
+	cur.All(ctx, &specs)
+	names := []string{}
+	for _, s := range specs {
+		names = append(names, s["name"].(string))
+	}
+	assert.Equal(t, []string{"_id_", "name_1", "price_unique"}, names)
+
+	_, err = c.Indexes().DropOne(ctx, "price_unique")
+	assert.Nil(t, err)
+	_, err = c.Indexes().DropOne(ctx, "price_unique")
+	assert.EqualError(t, err, "(IndexNotFound) index not found with name [price_unique]")
+	_, err = c.Indexes().DropOne(ctx, "_id_")
+	assert.NotNil(t, err)
+}
+
+func Test_Collection_BulkWrite(t *testing.T) {
+	ctx := context.Background()
+	c := NewDatabase("test").Collection("bulk")
+	c.InsertOne(ctx, bson.M{"_id": 1, "n": 1})
+
+	models := []mongo.WriteModel{
+		mongo.NewInsertOneModel().SetDocument(bson.M{"_id": 1}),
+		mongo.NewInsertOneModel().SetDocument(bson.M{"_id": 2}),
+		mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": 1}).SetUpdate(bson.M{"$inc": bson.M{"n": 1}}),
+		mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": 3}).SetUpdate(bson.M{"$set": bson.M{"n": 3}}).SetUpsert(true),
+		mongo.NewDeleteOneModel().SetFilter(bson.M{"_id": 2}),
+	}
+
+	res, err := c.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if assert.IsType(t, mongo.BulkWriteException{}, err) {
+		bwe := err.(mongo.BulkWriteException)
+		assert.Len(t, bwe.WriteErrors, 1)
+		assert.Equal(t, 0, bwe.WriteErrors[0].Index)
+		assert.Equal(t, models[0], bwe.WriteErrors[0].Request)
+	}
+	assert.Equal(t, int64(1), res.InsertedCount)
+	assert.Equal(t, int64(1), res.ModifiedCount)
+	assert.Equal(t, int64(1), res.UpsertedCount)
+	assert.Equal(t, map[int64]interface{}{3: int32(3)}, res.UpsertedIDs)
+	assert.Equal(t, int64(1), res.DeletedCount)
+
+	res, err = c.BulkWrite(ctx, models)
+	assert.IsType(t, mongo.BulkWriteException{}, err)
+	assert.Equal(t, int64(0), res.InsertedCount, "Expected an ordered write to stop at the first error")
+
+	_, err = c.BulkWrite(ctx, []mongo.WriteModel{})
+	assert.Equal(t, mongo.ErrEmptySlice, err)
+}
+
+func Test_Collection_InvalidName(t *testing.T) {
+	ctx := context.Background()
+	c := NewDatabase("test").Collection("bad$name")
+
+	_, err := c.Find(ctx, bson.M{})
+	assert.EqualError(t, err, "(InvalidNamespace) Invalid collection name specified 'test.bad$name'")
+}
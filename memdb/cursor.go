@@ -0,0 +1,90 @@
+package memdb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// errResultsNotSlice is returned by All when not given a pointer to a slice
+var errResultsNotSlice = errors.New("results argument must be a pointer to a slice")
+
+// cursor iterates over a snapshot of query results
+type cursor struct {
+	docs    []bson.D
+	pos     int
+	current bson.D
+	err     error
+}
+
+func newCursor(docs []bson.D) *cursor {
+	return &cursor{docs: docs}
+}
+
+func (c *cursor) Next(ctx context.Context) bool {
+	if err := ctx.Err(); err != nil {
+		c.err = err
+		return false
+	}
+	if c.pos >= len(c.docs) {
+		c.current = nil
+		return false
+	}
+	c.current = c.docs[c.pos]
+	c.pos++
+	return true
+}
+
+func (c *cursor) Decode(v interface{}) error {
+	return decode(c.current, v)
+}
+
+// All decodes the remaining documents into the slice pointed to and closes the cursor
+func (c *cursor) All(ctx context.Context, results interface{}) error {
+	rv := reflect.ValueOf(results)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return errResultsNotSlice
+	}
+	defer c.Close(ctx)
+
+	slice := reflect.MakeSlice(rv.Elem().Type(), 0, len(c.docs)-c.pos)
+	elemType := slice.Type().Elem()
+	for c.Next(ctx) {
+		ev := reflect.New(elemType)
+		if err := c.Decode(ev.Interface()); err != nil {
+			return err
+		}
+		slice = reflect.Append(slice, ev.Elem())
+	}
+	rv.Elem().Set(slice)
+	return c.err
+}
+
+func (c *cursor) Err() error {
+	return c.err
+}
+
+func (c *cursor) Close(ctx context.Context) error {
+	c.pos = len(c.docs)
+	c.current = nil
+	return nil
+}
+
+// singleResult holds the document found by FindOne, FindOneAndUpdate or RunCommand
+type singleResult struct {
+	doc bson.D
+	err error
+}
+
+func (r *singleResult) Decode(v interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	return decode(r.doc, v)
+}
+
+func (r *singleResult) Err() error {
+	return r.err
+}
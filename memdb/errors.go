@@ -0,0 +1,72 @@
+package memdb
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Server error codes returned by the in-memory database
+const (
+	codeBadValue                 = 2
+	codeFailedToParse            = 9
+	codeUnauthorized             = 13
+	codeTypeMismatch             = 14
+	codeNamespaceNotFound        = 26
+	codeIndexNotFound            = 27
+	codePathNotViable            = 28
+	codeNamespaceExists          = 48
+	codeCommandNotFound          = 59
+	codeImmutableField           = 66
+	codeInvalidOptions           = 72
+	codeInvalidNamespace         = 73
+	codeIndexOptionsConflict     = 85
+	codeIndexKeySpecsConflict    = 86
+	codeInvalidPipelineOperator  = 168
+	codeDuplicateKey             = 11000
+	codeUnrecognizedPipelineStep = 40324
+)
+
+// opError is an error raised by the in-memory server
+type opError struct {
+	code int
+	name string
+	msg  string
+}
+
+func (e *opError) Error() string {
+	return e.msg
+}
+
+func newError(code int, name string, format string, args ...interface{}) *opError {
+	return &opError{code, name, fmt.Sprintf(format, args...)}
+}
+
+func badValue(format string, args ...interface{}) *opError {
+	return newError(codeBadValue, "BadValue", format, args...)
+}
+
+// commandError converts server errors to the driver's command error
+func commandError(err error) error {
+	if e, ok := err.(*opError); ok {
+		return mongo.CommandError{Code: int32(e.code), Message: e.msg, Name: e.name}
+	}
+	return err
+}
+
+// writeError converts server errors to the driver's write error
+func writeError(index int, err error) (mongo.WriteError, bool) {
+	if e, ok := err.(*opError); ok {
+		return mongo.WriteError{Index: index, Code: e.code, Message: e.msg}, true
+	}
+	return mongo.WriteError{}, false
+}
+
+// singleWriteError converts the bulk write exception from a single write
+// to the driver's write exception
+func singleWriteError(err error) error {
+	if bwe, ok := err.(mongo.BulkWriteException); ok && len(bwe.WriteErrors) == 1 {
+		return mongo.WriteException{WriteErrors: mongo.WriteErrors{bwe.WriteErrors[0].WriteError}}
+	}
+	return err
+}
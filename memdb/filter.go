@@ -0,0 +1,398 @@
+package memdb
+
+import (
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// bsonTypes maps $type aliases to their BSON type numbers
+var bsonTypes = map[string]int32{
+	"double":    1,
+	"string":    2,
+	"object":    3,
+	"array":     4,
+	"binData":   5,
+	"undefined": 6,
+	"objectId":  7,
+	"bool":      8,
+	"date":      9,
+	"null":      10,
+	"regex":     11,
+	"int":       16,
+	"timestamp": 17,
+	"long":      18,
+	"decimal":   19,
+	"minKey":    -1,
+	"maxKey":    127,
+}
+
+// matches reports whether the document satisfies the query filter
+func matches(doc bson.D, filter bson.D) (bool, error) {
+	for _, e := range filter {
+		ok, err := matchElem(doc, e)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchElem(doc bson.D, e bson.E) (bool, error) {
+	switch {
+	case isLogical(e.Key):
+		subs, ok := e.Value.(primitive.A)
+		if !ok || len(subs) == 0 {
+			return false, badValue("%s must be a nonempty array", e.Key)
+		}
+		for _, s := range subs {
+			sub, ok := s.(primitive.D)
+			if !ok {
+				return false, badValue("%s argument's entries must be objects", e.Key)
+			}
+			m, err := matches(doc, sub)
+			if err != nil {
+				return false, err
+			}
+			switch {
+			case e.Key == "$and" && !m:
+				return false, nil
+			case e.Key == "$or" && m:
+				return true, nil
+			case e.Key == "$nor" && m:
+				return false, nil
+			}
+		}
+		return e.Key != "$or", nil
+	case e.Key == "$comment":
+		return true, nil
+	}
+	if strings.HasPrefix(e.Key, "$") {
+		return false, badValue("unknown top level operator: %s", e.Key)
+	}
+
+	vals := lookup(doc, splitPath(e.Key))
+	if cond, ok := e.Value.(primitive.D); ok && isOperatorDoc(cond) {
+		return matchOps(vals, cond)
+	}
+	if re, ok := e.Value.(primitive.Regex); ok {
+		return matchRegex(vals, re.Pattern, re.Options)
+	}
+	return matchEq(vals, e.Value), nil
+}
+
+// isOperatorDoc reports whether a condition is made of query operators
+func isOperatorDoc(d primitive.D) bool {
+	return len(d) > 0 && strings.HasPrefix(d[0].Key, "$")
+}
+
+// isLogical reports whether the key is a logical query operator
+func isLogical(key string) bool {
+	return key == "$and" || key == "$or" || key == "$nor"
+}
+
+// expand adds the elements of array values to the values, as comparisons
+// match either an array or any of its elements
+func expand(vals []interface{}) []interface{} {
+	out := make([]interface{}, 0, len(vals))
+	for _, v := range vals {
+		out = append(out, v)
+		if a, ok := v.(primitive.A); ok {
+			out = append(out, a...)
+		}
+	}
+	return out
+}
+
+// matchEq reports whether any value equals v, with null matching missing fields
+func matchEq(vals []interface{}, v interface{}) bool {
+	if typeOrder(v) == typeOrder(nil) && len(vals) == 0 {
+		return true
+	}
+	for _, val := range expand(vals) {
+		if compareValues(val, v) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// matchCmp reports whether any value of the same type compares as wanted
+func matchCmp(vals []interface{}, v interface{}, ok func(int) bool) bool {
+	if typeOrder(v) == typeOrder(nil) {
+		return ok(0) && matchEq(vals, v)
+	}
+	for _, val := range expand(vals) {
+		if typeOrder(val) == typeOrder(v) && ok(compareValues(val, v)) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchRegex(vals []interface{}, pattern, opts string) (bool, error) {
+	flags := ""
+	for _, o := range opts {
+		switch o {
+		case 'i', 'm', 's':
+			flags += string(o)
+		}
+	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, badValue("Regular expression is invalid: %s", err.Error())
+	}
+	for _, val := range expand(vals) {
+		switch s := val.(type) {
+		case string:
+			if re.MatchString(s) {
+				return true, nil
+			}
+		case primitive.Symbol:
+			if re.MatchString(string(s)) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func matchIn(vals []interface{}, v interface{}, op string) (bool, error) {
+	list, ok := v.(primitive.A)
+	if !ok {
+		return false, badValue("%s needs an array", op)
+	}
+	for _, item := range list {
+		if re, ok := item.(primitive.Regex); ok {
+			m, err := matchRegex(vals, re.Pattern, re.Options)
+			if err != nil || m {
+				return m, err
+			}
+			continue
+		}
+		if matchEq(vals, item) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matchOps reports whether the values satisfy every operator in the condition
+func matchOps(vals []interface{}, cond primitive.D) (bool, error) {
+	for _, op := range cond {
+		ok, err := matchOp(vals, op, cond)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchOp(vals []interface{}, op bson.E, cond primitive.D) (bool, error) {
+	switch op.Key {
+	case "$eq":
+		return matchEq(vals, op.Value), nil
+	case "$ne":
+		return !matchEq(vals, op.Value), nil
+	case "$gt":
+		return matchCmp(vals, op.Value, func(c int) bool { return c > 0 }), nil
+	case "$gte":
+		return matchCmp(vals, op.Value, func(c int) bool { return c >= 0 }), nil
+	case "$lt":
+		return matchCmp(vals, op.Value, func(c int) bool { return c < 0 }), nil
+	case "$lte":
+		return matchCmp(vals, op.Value, func(c int) bool { return c <= 0 }), nil
+	case "$in":
+		return matchIn(vals, op.Value, op.Key)
+	case "$nin":
+		m, err := matchIn(vals, op.Value, op.Key)
+		return !m, err
+	case "$exists":
+		return truthy(op.Value) == (len(vals) > 0), nil
+	case "$not":
+		switch t := op.Value.(type) {
+		case primitive.D:
+			m, err := matchOps(vals, t)
+			return !m, err
+		case primitive.Regex:
+			m, err := matchRegex(vals, t.Pattern, t.Options)
+			return !m, err
+		}
+		return false, badValue("$not needs a regex or a document")
+	case "$regex":
+		opts, _ := get(cond, "$options")
+		optStr, _ := opts.(string)
+		switch t := op.Value.(type) {
+		case string:
+			return matchRegex(vals, t, optStr)
+		case primitive.Regex:
+			if optStr == "" {
+				optStr = t.Options
+			}
+			return matchRegex(vals, t.Pattern, optStr)
+		}
+		return false, badValue("$regex has to be a string")
+	case "$options":
+		if _, ok := get(cond, "$regex"); !ok {
+			return false, badValue("$options needs a $regex")
+		}
+		return true, nil
+	case "$size":
+		n, ok := toInt64(op.Value)
+		if !ok {
+			return false, badValue("$size needs a number")
+		}
+		for _, v := range vals {
+			if a, ok := v.(primitive.A); ok && int64(len(a)) == n {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "$all":
+		list, ok := op.Value.(primitive.A)
+		if !ok {
+			return false, badValue("$all needs an array")
+		}
+		if len(list) == 0 {
+			return false, nil
+		}
+		for _, item := range list {
+			if d, ok := item.(primitive.D); ok && len(d) > 0 && d[0].Key == "$elemMatch" {
+				m, err := matchOp(vals, d[0], d)
+				if err != nil || !m {
+					return false, err
+				}
+				continue
+			}
+			if !matchEq(vals, item) {
+				return false, nil
+			}
+		}
+		return true, nil
+	case "$elemMatch":
+		sub, ok := op.Value.(primitive.D)
+		if !ok {
+			return false, badValue("$elemMatch needs an Object")
+		}
+		for _, v := range vals {
+			a, ok := v.(primitive.A)
+			if !ok {
+				continue
+			}
+			for _, e := range a {
+				var m bool
+				var err error
+				if isOperatorDoc(sub) && !isLogical(sub[0].Key) {
+					m, err = matchOps([]interface{}{e}, sub)
+				} else if d, ok := e.(primitive.D); ok {
+					m, err = matches(d, sub)
+				}
+				if err != nil || m {
+					return m, err
+				}
+			}
+		}
+		return false, nil
+	case "$type":
+		types, ok := op.Value.(primitive.A)
+		if !ok {
+			types = primitive.A{op.Value}
+		}
+		for _, t := range types {
+			for _, v := range expand(vals) {
+				m, err := matchType(v, t)
+				if err != nil || m {
+					return m, err
+				}
+			}
+		}
+		return false, nil
+	case "$mod":
+		args, ok := op.Value.(primitive.A)
+		if !ok || len(args) != 2 {
+			return false, badValue("malformed mod, needs to be an array")
+		}
+		div, okD := toInt64(args[0])
+		rem, okR := toInt64(args[1])
+		if !okD || !okR {
+			return false, badValue("malformed mod, divisor and remainder must be numbers")
+		}
+		if div == 0 {
+			return false, badValue("divisor cannot be 0")
+		}
+		for _, v := range expand(vals) {
+			if isNumber(v) && int64(toFloat(v))%div == rem {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "$comment":
+		return true, nil
+	}
+	return false, badValue("unknown operator: %s", op.Key)
+}
+
+// matchType reports whether a value has the BSON type given by number or alias
+func matchType(v interface{}, t interface{}) (bool, error) {
+	if s, ok := t.(string); ok {
+		if s == "number" {
+			return isNumber(v), nil
+		}
+		n, ok := bsonTypes[s]
+		if !ok {
+			return false, badValue("Unknown type name alias: %s", s)
+		}
+		return bsonType(v) == n, nil
+	}
+	n, ok := toInt64(t)
+	if !ok {
+		return false, badValue("type must be represented as a number or a string")
+	}
+	return int64(bsonType(v)) == n, nil
+}
+
+// bsonType gets the BSON type number of a value
+func bsonType(v interface{}) int32 {
+	switch v.(type) {
+	case float64:
+		return 1
+	case string:
+		return 2
+	case primitive.D:
+		return 3
+	case primitive.A:
+		return 4
+	case primitive.Binary:
+		return 5
+	case primitive.Undefined:
+		return 6
+	case primitive.ObjectID:
+		return 7
+	case bool:
+		return 8
+	case primitive.DateTime:
+		return 9
+	case nil, primitive.Null:
+		return 10
+	case primitive.Regex:
+		return 11
+	case int32:
+		return 16
+	case primitive.Timestamp:
+		return 17
+	case int64:
+		return 18
+	case primitive.Decimal128:
+		return 19
+	case primitive.MinKey:
+		return -1
+	case primitive.MaxKey:
+		return 127
+	}
+	return 0
+}
@@ -0,0 +1,111 @@
+package memdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var filterDoc = bson.M{
+	"_id":   1,
+	"name":  "Widget",
+	"price": 9.5,
+	"qty":   int64(20),
+	"tags":  bson.A{"red", "blue"},
+	"dims":  bson.M{"h": 10, "w": 5},
+	"parts": bson.A{bson.M{"sku": "a", "n": 2}, bson.M{"sku": "b", "n": 7}},
+	"none":  nil,
+}
+
+var matchesTests = map[string]struct {
+	filter   interface{}
+	expected bool
+	err      string
+}{
+	"empty":                {bson.M{}, true, ""},
+	"equal":                {bson.M{"name": "Widget"}, true, ""},
+	"not equal":            {bson.M{"name": "Gadget"}, false, ""},
+	"numeric types":        {bson.M{"qty": 20.0}, true, ""},
+	"array contains":       {bson.M{"tags": "red"}, true, ""},
+	"whole array":          {bson.M{"tags": bson.A{"red", "blue"}}, true, ""},
+	"dotted":               {bson.M{"dims.h": 10}, true, ""},
+	"dotted through array": {bson.M{"parts.sku": "b"}, true, ""},
+	"array index":          {bson.M{"parts.1.sku": "b"}, true, ""},
+	"null matches missing": {bson.M{"missing": nil}, true, ""},
+	"null matches null":    {bson.M{"none": nil}, true, ""},
+	"$gt":                  {bson.M{"price": bson.M{"$gt": 9}}, true, ""},
+	"$gte and $lt":         {bson.M{"qty": bson.M{"$gte": 20, "$lt": 21}}, true, ""},
+	"$lte false":           {bson.M{"qty": bson.M{"$lte": 19}}, false, ""},
+	"$gt other type":       {bson.M{"name": bson.M{"$gt": 1}}, false, ""},
+	"$ne":                  {bson.M{"name": bson.M{"$ne": "Gadget"}}, true, ""},
+	"$ne array element":    {bson.M{"tags": bson.M{"$ne": "red"}}, false, ""},
+	"$in":                  {bson.M{"tags": bson.M{"$in": bson.A{"green", "blue"}}}, true, ""},
+	"$in regex":            {bson.M{"name": bson.M{"$in": bson.A{primitive.Regex{Pattern: "^wid", Options: "i"}}}}, true, ""},
+	"$nin":                 {bson.M{"name": bson.M{"$nin": bson.A{"Widget"}}}, false, ""},
+	"$exists":              {bson.M{"none": bson.M{"$exists": true}}, true, ""},
+	"$exists false":        {bson.M{"missing": bson.M{"$exists": false}}, true, ""},
+	"$not":                 {bson.M{"price": bson.M{"$not": bson.M{"$gt": 10}}}, true, ""},
+	"$regex":               {bson.M{"name": bson.M{"$regex": "^w", "$options": "i"}}, true, ""},
+	"regex value":          {bson.M{"name": primitive.Regex{Pattern: "dget$"}}, true, ""},
+	"$size":                {bson.M{"tags": bson.M{"$size": 2}}, true, ""},
+	"$all":                 {bson.M{"tags": bson.M{"$all": bson.A{"blue", "red"}}}, true, ""},
+	"$all missing":         {bson.M{"tags": bson.M{"$all": bson.A{"blue", "green"}}}, false, ""},
+	"$elemMatch doc":       {bson.M{"parts": bson.M{"$elemMatch": bson.M{"sku": "b", "n": bson.M{"$gt": 5}}}}, true, ""},
+	"$elemMatch split":     {bson.M{"parts": bson.M{"$elemMatch": bson.M{"sku": "a", "n": bson.M{"$gt": 5}}}}, false, ""},
+	"$elemMatch values":    {bson.M{"tags": bson.M{"$elemMatch": bson.M{"$gte": "c"}}}, true, ""},
+	"$type alias":          {bson.M{"qty": bson.M{"$type": "long"}}, true, ""},
+	"$type number":         {bson.M{"price": bson.M{"$type": 1}}, true, ""},
+	"$type array":          {bson.M{"tags": bson.M{"$type": "array"}}, true, ""},
+	"$mod":                 {bson.M{"qty": bson.M{"$mod": bson.A{3, 2}}}, true, ""},
+	"$and":                 {bson.M{"$and": bson.A{bson.M{"name": "Widget"}, bson.M{"qty": 20}}}, true, ""},
+	"$or":                  {bson.M{"$or": bson.A{bson.M{"name": "Gadget"}, bson.M{"qty": 20}}}, true, ""},
+	"$nor":                 {bson.M{"$nor": bson.A{bson.M{"name": "Gadget"}, bson.M{"qty": 20}}}, false, ""},
+	"bson.D filter":        {bson.D{{Key: "name", Value: "Widget"}, {Key: "qty", Value: 20}}, true, ""},
+	"unknown operator":     {bson.M{"qty": bson.M{"$foo": 1}}, false, "unknown operator: $foo"},
+	"unknown top level":    {bson.M{"$foo": 1}, false, "unknown top level operator: $foo"},
+	"bad $or":              {bson.M{"$or": bson.A{}}, false, "$or must be a nonempty array"},
+}
+
+func Test_Matches(t *testing.T) {
+	doc, _ := toDoc(filterDoc)
+
+	for tn, tt := range matchesTests {
+		filter, err := toDoc(tt.filter)
+		assert.Nilf(t, err, "Expected nil err for toDoc on test '%s'", tn)
+
+		res, err := matches(doc, filter)
+
+		if tt.err == "" {
+			assert.Nilf(t, err, "Expected nil err for matches on test '%s'", tn)
+		} else {
+			assert.EqualErrorf(t, err, tt.err, "Expected error for matches on test '%s'", tn)
+		}
+		assert.Equalf(t, tt.expected, res, "Expected match result on test '%s'", tn)
+	}
+}
+
+var compareValuesTests = map[string]struct {
+	a        interface{}
+	b        interface{}
+	expected int
+}{
+	"equal ints":         {int32(1), int64(1), 0},
+	"int and float":      {int32(1), 1.5, -1},
+	"strings":            {"b", "a", 1},
+	"null before number": {nil, int32(0), -1},
+	"number before text": {int32(100), "1", -1},
+	"object ids":         {primitive.ObjectID{1}, primitive.ObjectID{2}, -1},
+	"bools":              {true, false, 1},
+	"dates":              {primitive.DateTime(2), primitive.DateTime(1), 1},
+	"arrays":             {primitive.A{int32(1), int32(2)}, primitive.A{int32(1)}, 1},
+	"documents":          {primitive.D{{Key: "a", Value: int32(1)}}, primitive.D{{Key: "a", Value: int32(2)}}, -1},
+}
+
+func Test_CompareValues(t *testing.T) {
+	for tn, tt := range compareValuesTests {
+		res := compareValues(tt.a, tt.b)
+		assert.Equalf(t, tt.expected, res, "Expected comparison result on test '%s'", tn)
+	}
+}
@@ -0,0 +1,241 @@
+package memdb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// idIndexName is the name of the index every collection has on _id
+const idIndexName = "_id_"
+
+// index is an index on a collection, only unique indexes are enforced
+type index struct {
+	name   string
+	keys   bson.D
+	unique bool
+	sparse bool
+	spec   bson.D
+}
+
+func newIDIndex() *index {
+	keys := bson.D{{Key: "_id", Value: int32(1)}}
+	return &index{
+		name:   idIndexName,
+		keys:   keys,
+		unique: true,
+		spec:   bson.D{{Key: "v", Value: int32(2)}, {Key: "key", Value: keys}, {Key: "name", Value: idIndexName}},
+	}
+}
+
+// key gets the indexed values of the document, reporting false if a sparse
+// index doesn't cover it
+func (i *index) key(doc bson.D) (bson.D, bool) {
+	key := make(bson.D, len(i.keys))
+	found := false
+	for n, k := range i.keys {
+		v, ok := getPath(doc, splitPath(k.Key))
+		found = found || ok
+		key[n] = bson.E{Key: k.Key, Value: v}
+	}
+	return key, found || !i.sparse
+}
+
+// indexName generates the default name of an index from its keys
+func indexName(keys bson.D) string {
+	parts := make([]string, 0, len(keys)*2)
+	for _, k := range keys {
+		parts = append(parts, k.Key, fmt.Sprintf("%v", k.Value))
+	}
+	return strings.Join(parts, "_")
+}
+
+// duplicateKey builds the server's duplicate key error
+func duplicateKey(ns string, idx *index, key bson.D) *opError {
+	parts := make([]string, len(key))
+	for i, k := range key {
+		parts[i] = k.Key + ": " + formatValue(k.Value)
+	}
+	return newError(codeDuplicateKey, "DuplicateKey", "E11000 duplicate key error collection: %s index: %s dup key: { %s }",
+		ns, idx.name, strings.Join(parts, ", "))
+}
+
+type indexView struct {
+	coll *collection
+}
+
+// List gets a cursor over the index specifications
+func (iv *indexView) List(ctx context.Context, opts ...*options.ListIndexesOptions) (base.MongoCursor, error) {
+	if err := iv.coll.check(ctx); err != nil {
+		return nil, err
+	}
+	iv.coll.client.mu.RLock()
+	defer iv.coll.client.mu.RUnlock()
+
+	data := iv.coll.data(false)
+	if data == nil {
+		return newCursor(nil), nil
+	}
+	specs := make([]bson.D, len(data.indexes))
+	for i, idx := range data.indexes {
+		specs[i] = idx.spec
+	}
+	return newCursor(specs), nil
+}
+
+// CreateOne creates an index, doing nothing if an identical one exists
+func (iv *indexView) CreateOne(ctx context.Context, model mongo.IndexModel, opts ...*options.CreateIndexesOptions) (string, error) {
+	if err := iv.coll.check(ctx); err != nil {
+		return "", err
+	}
+	keys, err := toDoc(model.Keys)
+	if err != nil {
+		return "", err
+	}
+	if len(keys) == 0 {
+		return "", commandError(badValue("index keys cannot be empty"))
+	}
+
+	idx := &index{keys: keys, name: indexName(keys)}
+	if model.Options != nil && model.Options.Name != nil {
+		idx.name = *model.Options.Name
+	}
+	spec := bson.D{{Key: "v", Value: int32(2)}, {Key: "key", Value: keys}, {Key: "name", Value: idx.name}}
+	if o := model.Options; o != nil {
+		if o.Unique != nil && *o.Unique {
+			idx.unique = true
+			spec = append(spec, bson.E{Key: "unique", Value: true})
+		}
+		if o.Sparse != nil && *o.Sparse {
+			idx.sparse = true
+			spec = append(spec, bson.E{Key: "sparse", Value: true})
+		}
+		if o.ExpireAfterSeconds != nil {
+			spec = append(spec, bson.E{Key: "expireAfterSeconds", Value: *o.ExpireAfterSeconds})
+		}
+		if o.PartialFilterExpression != nil {
+			pfe, err := toDoc(o.PartialFilterExpression)
+			if err != nil {
+				return "", err
+			}
+			spec = append(spec, bson.E{Key: "partialFilterExpression", Value: pfe})
+		}
+	}
+	idx.spec = spec
+
+	iv.coll.client.mu.Lock()
+	defer iv.coll.client.mu.Unlock()
+	data := iv.coll.data(true)
+
+	for _, existing := range data.indexes {
+		sameKeys := compareValues(existing.keys, idx.keys) == 0
+		switch {
+		case existing.name == idx.name && sameKeys && existing.unique == idx.unique && existing.sparse == idx.sparse:
+			return idx.name, nil
+		case existing.name == idx.name:
+			return "", commandError(newError(codeIndexKeySpecsConflict, "IndexKeySpecsConflict",
+				"An existing index has the same name as the requested index. Requested index: %s, existing index: %s",
+				formatValue(idx.spec), formatValue(existing.spec)))
+		case sameKeys:
+			return "", commandError(newError(codeIndexOptionsConflict, "IndexOptionsConflict",
+				"Index with name: %s already exists with a different name", existing.name))
+		}
+	}
+
+	if idx.unique {
+		seen := make([]bson.D, 0, len(data.docs))
+		for _, doc := range data.docs {
+			key, ok := idx.key(doc)
+			if !ok {
+				continue
+			}
+			for _, s := range seen {
+				if compareValues(s, key) == 0 {
+					return "", commandError(duplicateKey(iv.coll.namespace(), idx, key))
+				}
+			}
+			seen = append(seen, key)
+		}
+	}
+
+	data.indexes = append(data.indexes, idx)
+	return idx.name, nil
+}
+
+// DropOne drops the named index
+func (iv *indexView) DropOne(ctx context.Context, name string, opts ...*options.DropIndexesOptions) (bson.Raw, error) {
+	if err := iv.coll.check(ctx); err != nil {
+		return nil, err
+	}
+	if name == "*" {
+		return nil, commandError(badValue("dropping all indexes with '*' is not supported by DropOne, use DropAll"))
+	}
+	if name == idIndexName {
+		return nil, commandError(newError(codeInvalidOptions, "InvalidOptions", "cannot drop _id index"))
+	}
+
+	iv.coll.client.mu.Lock()
+	defer iv.coll.client.mu.Unlock()
+	data := iv.coll.data(false)
+	if data == nil {
+		return nil, commandError(newError(codeNamespaceNotFound, "NamespaceNotFound", "ns not found %s", iv.coll.namespace()))
+	}
+
+	for i, idx := range data.indexes {
+		if idx.name == name {
+			was := len(data.indexes)
+			data.indexes = append(data.indexes[:i:i], data.indexes[i+1:]...)
+			return dropResult(was)
+		}
+	}
+	return nil, commandError(newError(codeIndexNotFound, "IndexNotFound", "index not found with name [%s]", name))
+}
+
+// DropAll drops every index other than the _id index
+func (iv *indexView) DropAll(ctx context.Context, opts ...*options.DropIndexesOptions) (bson.Raw, error) {
+	if err := iv.coll.check(ctx); err != nil {
+		return nil, err
+	}
+	iv.coll.client.mu.Lock()
+	defer iv.coll.client.mu.Unlock()
+	data := iv.coll.data(false)
+	if data == nil {
+		return nil, commandError(newError(codeNamespaceNotFound, "NamespaceNotFound", "ns not found %s", iv.coll.namespace()))
+	}
+
+	was := len(data.indexes)
+	data.indexes = data.indexes[:1]
+	return dropResult(was)
+}
+
+func dropResult(was int) (bson.Raw, error) {
+	return bson.Marshal(bson.D{{Key: "nIndexesWas", Value: int32(was)}, {Key: "ok", Value: float64(1)}})
+}
+
+// checkUnique checks the document against the collection's unique indexes,
+// skipping the document at position self when it's being replaced
+func (data *collectionData) checkUnique(ns string, doc bson.D, self int) error {
+	for _, idx := range data.indexes {
+		if !idx.unique {
+			continue
+		}
+		key, ok := idx.key(doc)
+		if !ok {
+			continue
+		}
+		for i, other := range data.docs {
+			if i == self {
+				continue
+			}
+			if otherKey, ok := idx.key(other); ok && compareValues(key, otherKey) == 0 {
+				return duplicateKey(ns, idx, key)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package memdb
+
+import (
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sortDocs sorts the documents by the sort spec, keeping the natural order of ties
+func sortDocs(docs []bson.D, spec bson.D) error {
+	dirs, err := sortDirs(spec)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		return sortsBefore(docs[i], docs[j], spec, dirs)
+	})
+	return nil
+}
+
+// sortPositions sorts positions in the documents by the sort spec
+func sortPositions(docs []bson.D, positions []int, spec bson.D) error {
+	dirs, err := sortDirs(spec)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(positions, func(i, j int) bool {
+		return sortsBefore(docs[positions[i]], docs[positions[j]], spec, dirs)
+	})
+	return nil
+}
+
+// sortDirs gets the direction of each key in the sort spec
+func sortDirs(spec bson.D) ([]int, error) {
+	dirs := make([]int, len(spec))
+	for i, e := range spec {
+		dir, ok := toInt64(e.Value)
+		if !ok || (dir != 1 && dir != -1) {
+			return nil, badValue("$sort key ordering must be 1 (for ascending) or -1 (for descending)")
+		}
+		dirs[i] = int(dir)
+	}
+	return dirs, nil
+}
+
+func sortsBefore(a, b bson.D, spec bson.D, dirs []int) bool {
+	for k, e := range spec {
+		c := compareValues(sortValue(a, e.Key), sortValue(b, e.Key)) * dirs[k]
+		if c != 0 {
+			return c < 0
+		}
+	}
+	return false
+}
+
+// sortValue gets the value a document sorts by, a missing field sorting as null
+func sortValue(doc bson.D, key string) interface{} {
+	vals := lookup(doc, splitPath(key))
+	if len(vals) == 0 {
+		return nil
+	}
+	return vals[0]
+}
+
+// window applies skip and limit to the documents
+func window(docs []bson.D, skip, limit *int64) []bson.D {
+	if skip != nil && *skip > 0 {
+		if *skip >= int64(len(docs)) {
+			return nil
+		}
+		docs = docs[*skip:]
+	}
+	if limit != nil && *limit != 0 {
+		n := *limit
+		if n < 0 {
+			n = -n
+		}
+		if n < int64(len(docs)) {
+			docs = docs[:n]
+		}
+	}
+	return docs
+}
+
+// isFlag reports whether a projection value includes or excludes a field
+// rather than computing it
+func isFlag(v interface{}) bool {
+	_, ok := v.(bool)
+	return ok || isNumber(v)
+}
+
+// flattenSpec flattens nested projection documents to dotted paths
+func flattenSpec(spec bson.D, prefix string) []bson.E {
+	var out []bson.E
+	for _, e := range spec {
+		key := prefix + e.Key
+		if d, ok := e.Value.(primitive.D); ok && len(d) > 0 && !isOperatorDoc(d) {
+			out = append(out, flattenSpec(d, key+".")...)
+			continue
+		}
+		out = append(out, bson.E{Key: key, Value: e.Value})
+	}
+	return out
+}
+
+// project applies an inclusion or exclusion projection to the document
+func project(doc bson.D, spec bson.D) (bson.D, error) {
+	fields := flattenSpec(spec, "")
+	var include, exclude, excludeID bool
+	for _, f := range fields {
+		flag := isFlag(f.Value)
+		switch {
+		case f.Key == "_id":
+			excludeID = flag && !truthy(f.Value)
+		case flag && !truthy(f.Value):
+			exclude = true
+		default:
+			include = true
+		}
+	}
+	if include && exclude {
+		return nil, badValue("Projection cannot have a mix of inclusion and exclusion.")
+	}
+
+	if !include {
+		var out interface{} = cloneDoc(doc)
+		for _, f := range fields {
+			if isFlag(f.Value) && !truthy(f.Value) {
+				out = unsetPath(out, splitPath(f.Key))
+			}
+		}
+		return out.(primitive.D), nil
+	}
+
+	var out interface{} = bson.D{}
+	if id, ok := get(doc, "_id"); ok && !excludeID {
+		out = bson.D{{Key: "_id", Value: id}}
+	}
+	for _, f := range fields {
+		var err error
+		path := splitPath(f.Key)
+		if isFlag(f.Value) {
+			if f.Key == "_id" {
+				continue
+			}
+			if v, ok := getPath(doc, path); ok {
+				out, err = setPath(out, path, v)
+			}
+		} else {
+			var v interface{}
+			if v, err = evalExpr(doc, f.Value); err == nil {
+				out, err = setPath(out, path, v)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out.(primitive.D), nil
+}
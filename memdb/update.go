@@ -0,0 +1,323 @@
+package memdb
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Client side errors, worded as the driver words them
+var (
+	errUpdateNeedsOperators   = errors.New("update document must contain key beginning with '$'")
+	errReplacementHasOperator = errors.New("replacement document cannot contain keys beginning with '$'")
+)
+
+// checkUpdate validates an update document before it's sent
+func checkUpdate(update bson.D) error {
+	if len(update) == 0 || !strings.HasPrefix(update[0].Key, "$") {
+		return errUpdateNeedsOperators
+	}
+	return nil
+}
+
+// checkReplacement validates a replacement document before it's sent
+func checkReplacement(doc bson.D) error {
+	if len(doc) > 0 && strings.HasPrefix(doc[0].Key, "$") {
+		return errReplacementHasOperator
+	}
+	return nil
+}
+
+// replaceDoc replaces the document, keeping its _id
+func replaceDoc(doc bson.D, replacement bson.D) (bson.D, error) {
+	id, _ := get(doc, "_id")
+	if newID, ok := get(replacement, "_id"); ok && compareValues(id, newID) != 0 {
+		return nil, immutableID()
+	}
+	out := cloneDoc(replacement)
+	return withIDFirst(set(out, "_id", id)), nil
+}
+
+func immutableID() *opError {
+	return newError(codeImmutableField, "ImmutableField",
+		"After applying the update, the (immutable) field '_id' was found to have been altered")
+}
+
+// applyUpdate applies the update operators to a copy of the document.
+// $setOnInsert only applies when inserting.
+func applyUpdate(doc bson.D, update bson.D, inserting bool) (bson.D, error) {
+	var out interface{} = cloneDoc(doc)
+	id, _ := get(doc, "_id")
+
+	for _, op := range update {
+		fields, ok := op.Value.(primitive.D)
+		if !ok {
+			return nil, newError(codeFailedToParse, "FailedToParse",
+				"Modifiers operate on fields but we found type %T instead", op.Value)
+		}
+		for _, f := range fields {
+			var err error
+			out, err = applyOp(out, op.Key, f, inserting)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	res := out.(primitive.D)
+	if newID, ok := get(res, "_id"); ok && !inserting && compareValues(id, newID) != 0 {
+		return nil, immutableID()
+	}
+	return withIDFirst(res), nil
+}
+
+func applyOp(doc interface{}, op string, f bson.E, inserting bool) (interface{}, error) {
+	path := splitPath(f.Key)
+	cur, exists := getPath(doc, path)
+
+	switch op {
+	case "$set":
+		return setPath(doc, path, f.Value)
+	case "$setOnInsert":
+		if !inserting {
+			return doc, nil
+		}
+		return setPath(doc, path, f.Value)
+	case "$unset":
+		return unsetPath(doc, path), nil
+	case "$inc", "$mul":
+		if !isNumber(f.Value) {
+			verb := "increment"
+			if op == "$mul" {
+				verb = "multiply"
+			}
+			return nil, newError(codeTypeMismatch, "TypeMismatch", "Cannot %s with non-numeric argument: {%s: %s}",
+				verb, f.Key, formatValue(f.Value))
+		}
+		if !exists {
+			if op == "$mul" {
+				return setPath(doc, path, mulNumbers(f.Value, int32(0)))
+			}
+			return setPath(doc, path, f.Value)
+		}
+		if !isNumber(cur) {
+			return nil, newError(codeTypeMismatch, "TypeMismatch",
+				"Cannot apply %s to a value of non-numeric type. {_id: %s} has the field '%s' of non-numeric type %T",
+				op, formatValue(idOf(doc)), f.Key, cur)
+		}
+		if op == "$mul" {
+			return setPath(doc, path, mulNumbers(cur, f.Value))
+		}
+		return setPath(doc, path, addNumbers(cur, f.Value))
+	case "$min", "$max":
+		c := compareValues(f.Value, cur)
+		if !exists || (op == "$min" && c < 0) || (op == "$max" && c > 0) {
+			return setPath(doc, path, f.Value)
+		}
+		return doc, nil
+	case "$currentDate":
+		now := time.Now()
+		var v interface{} = primitive.NewDateTimeFromTime(now)
+		if spec, ok := f.Value.(primitive.D); ok {
+			if t, _ := get(spec, "$type"); t == "timestamp" {
+				v = primitive.Timestamp{T: uint32(now.Unix())}
+			}
+		}
+		return setPath(doc, path, v)
+	case "$rename":
+		to, ok := f.Value.(string)
+		if !ok {
+			return nil, badValue("The 'to' field for $rename must be a string: %s: %s", f.Key, formatValue(f.Value))
+		}
+		if !exists {
+			return doc, nil
+		}
+		return setPath(unsetPath(doc, path), splitPath(to), cur)
+	case "$push", "$addToSet", "$pull", "$pullAll", "$pop":
+		arr := primitive.A{}
+		if exists {
+			a, ok := cur.(primitive.A)
+			if !ok {
+				return nil, badValue("The field '%s' must be an array but is of type %T in document {_id: %s}",
+					f.Key, cur, formatValue(idOf(doc)))
+			}
+			arr = a
+		} else if op != "$push" && op != "$addToSet" {
+			return doc, nil
+		}
+		arr, err := applyArrayOp(arr, op, f.Value)
+		if err != nil {
+			return nil, err
+		}
+		return setPath(doc, path, arr)
+	}
+	return nil, newError(codeFailedToParse, "FailedToParse", "Unknown modifier: %s", op)
+}
+
+// applyArrayOp applies an array update operator
+func applyArrayOp(arr primitive.A, op string, v interface{}) (primitive.A, error) {
+	switch op {
+	case "$push":
+		items := primitive.A{v}
+		var slice interface{}
+		if spec, ok := v.(primitive.D); ok && len(spec) > 0 && spec[0].Key == "$each" {
+			each, ok := spec[0].Value.(primitive.A)
+			if !ok {
+				return nil, badValue("The argument to $each in $push must be an array")
+			}
+			items = each
+			slice, _ = get(spec, "$slice")
+			if pos, ok := get(spec, "$position"); ok {
+				p, _ := toInt64(pos)
+				if p < 0 {
+					p += int64(len(arr))
+				}
+				if p < 0 {
+					p = 0
+				}
+				if p > int64(len(arr)) {
+					p = int64(len(arr))
+				}
+				out := append(primitive.A{}, arr[:p]...)
+				out = append(out, items...)
+				arr = append(out, arr[p:]...)
+				items = nil
+			}
+		}
+		arr = append(arr, items...)
+		if slice != nil {
+			n, _ := toInt64(slice)
+			switch {
+			case n >= 0 && n < int64(len(arr)):
+				arr = arr[:n]
+			case n < 0 && -n < int64(len(arr)):
+				arr = arr[int64(len(arr))+n:]
+			}
+		}
+		return arr, nil
+	case "$addToSet":
+		items := primitive.A{v}
+		if spec, ok := v.(primitive.D); ok && len(spec) > 0 && spec[0].Key == "$each" {
+			each, ok := spec[0].Value.(primitive.A)
+			if !ok {
+				return nil, badValue("The argument to $each in $addToSet must be an array")
+			}
+			items = each
+		}
+		for _, item := range items {
+			if !contains(arr, item) {
+				arr = append(arr, item)
+			}
+		}
+		return arr, nil
+	case "$pull", "$pullAll":
+		out := primitive.A{}
+		for _, e := range arr {
+			var pull bool
+			var err error
+			switch cond := v.(type) {
+			case primitive.A:
+				if op == "$pullAll" {
+					pull = contains(cond, e)
+				} else {
+					pull = compareValues(e, cond) == 0
+				}
+			case primitive.D:
+				if isOperatorDoc(cond) && !isLogical(cond[0].Key) {
+					pull, err = matchOps([]interface{}{e}, cond)
+				} else if d, ok := e.(primitive.D); ok {
+					pull, err = matches(d, cond)
+				}
+			default:
+				if op == "$pullAll" {
+					return nil, badValue("$pullAll requires an array argument but was given a %T", v)
+				}
+				pull = compareValues(e, cond) == 0
+			}
+			if err != nil {
+				return nil, err
+			}
+			if !pull {
+				out = append(out, e)
+			}
+		}
+		return out, nil
+	case "$pop":
+		if len(arr) == 0 {
+			return arr, nil
+		}
+		n, _ := toInt64(v)
+		if n < 0 {
+			return arr[1:], nil
+		}
+		return arr[:len(arr)-1], nil
+	}
+	return arr, nil
+}
+
+func contains(arr primitive.A, v interface{}) bool {
+	for _, e := range arr {
+		if compareValues(e, v) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func idOf(doc interface{}) interface{} {
+	if d, ok := doc.(primitive.D); ok {
+		id, _ := get(d, "_id")
+		return id
+	}
+	return nil
+}
+
+// upsertSeed builds the document an upsert starts from out of the
+// equality conditions in the filter
+func upsertSeed(filter bson.D) (bson.D, error) {
+	var doc interface{} = bson.D{}
+	var err error
+	for _, e := range filter {
+		switch {
+		case e.Key == "$and":
+			subs, _ := e.Value.(primitive.A)
+			for _, s := range subs {
+				sub, ok := s.(primitive.D)
+				if !ok {
+					continue
+				}
+				seed, err := upsertSeed(sub)
+				if err != nil {
+					return nil, err
+				}
+				for _, se := range seed {
+					if doc, err = setPath(doc, []string{se.Key}, se.Value); err != nil {
+						return nil, err
+					}
+				}
+			}
+			continue
+		case strings.HasPrefix(e.Key, "$"):
+			continue
+		}
+
+		v := e.Value
+		if cond, ok := v.(primitive.D); ok && isOperatorDoc(cond) {
+			eq, ok := get(cond, "$eq")
+			if !ok {
+				continue
+			}
+			v = eq
+		}
+		if _, ok := v.(primitive.Regex); ok {
+			continue
+		}
+		if doc, err = setPath(doc, splitPath(e.Key), v); err != nil {
+			return nil, err
+		}
+	}
+	return doc.(primitive.D), nil
+}
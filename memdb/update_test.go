@@ -0,0 +1,169 @@
+package memdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var applyUpdateTests = map[string]struct {
+	doc       bson.D
+	update    bson.D
+	inserting bool
+	expected  bson.D
+	err       string
+}{
+	"$set": {
+		bson.D{{Key: "_id", Value: 1}, {Key: "a", Value: 1}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "a", Value: 2}, {Key: "b.c", Value: "x"}}}},
+		false,
+		bson.D{{Key: "_id", Value: 1}, {Key: "a", Value: 2}, {Key: "b", Value: bson.D{{Key: "c", Value: "x"}}}},
+		"",
+	},
+	"$unset": {
+		bson.D{{Key: "_id", Value: 1}, {Key: "a", Value: 1}, {Key: "b", Value: 2}},
+		bson.D{{Key: "$unset", Value: bson.D{{Key: "a", Value: ""}}}},
+		false,
+		bson.D{{Key: "_id", Value: 1}, {Key: "b", Value: 2}},
+		"",
+	},
+	"$inc": {
+		bson.D{{Key: "_id", Value: 1}, {Key: "n", Value: 1}},
+		bson.D{{Key: "$inc", Value: bson.D{{Key: "n", Value: 2}, {Key: "m", Value: int64(5)}}}},
+		false,
+		bson.D{{Key: "_id", Value: 1}, {Key: "n", Value: 3}, {Key: "m", Value: int64(5)}},
+		"",
+	},
+	"$inc non-numeric": {
+		bson.D{{Key: "_id", Value: 1}, {Key: "n", Value: "x"}},
+		bson.D{{Key: "$inc", Value: bson.D{{Key: "n", Value: 1}}}},
+		false,
+		nil,
+		"Cannot apply $inc to a value of non-numeric type. {_id: 1} has the field 'n' of non-numeric type string",
+	},
+	"$mul": {
+		bson.D{{Key: "_id", Value: 1}, {Key: "n", Value: 3}},
+		bson.D{{Key: "$mul", Value: bson.D{{Key: "n", Value: 1.5}}}},
+		false,
+		bson.D{{Key: "_id", Value: 1}, {Key: "n", Value: 4.5}},
+		"",
+	},
+	"$min and $max": {
+		bson.D{{Key: "_id", Value: 1}, {Key: "lo", Value: 5}, {Key: "hi", Value: 5}},
+		bson.D{{Key: "$min", Value: bson.D{{Key: "lo", Value: 3}}}, {Key: "$max", Value: bson.D{{Key: "hi", Value: 4}}}},
+		false,
+		bson.D{{Key: "_id", Value: 1}, {Key: "lo", Value: 3}, {Key: "hi", Value: 5}},
+		"",
+	},
+	"$push $each $slice": {
+		bson.D{{Key: "_id", Value: 1}, {Key: "a", Value: bson.A{1}}},
+		bson.D{{Key: "$push", Value: bson.D{{Key: "a", Value: bson.D{{Key: "$each", Value: bson.A{2, 3}}, {Key: "$slice", Value: -2}}}}}},
+		false,
+		bson.D{{Key: "_id", Value: 1}, {Key: "a", Value: bson.A{2, 3}}},
+		"",
+	},
+	"$push non-array": {
+		bson.D{{Key: "_id", Value: 1}, {Key: "a", Value: 1}},
+		bson.D{{Key: "$push", Value: bson.D{{Key: "a", Value: 2}}}},
+		false,
+		nil,
+		"The field 'a' must be an array but is of type int32 in document {_id: 1}",
+	},
+	"$addToSet": {
+		bson.D{{Key: "_id", Value: 1}, {Key: "a", Value: bson.A{1, 2}}},
+		bson.D{{Key: "$addToSet", Value: bson.D{{Key: "a", Value: bson.D{{Key: "$each", Value: bson.A{2, 3}}}}}}},
+		false,
+		bson.D{{Key: "_id", Value: 1}, {Key: "a", Value: bson.A{1, 2, 3}}},
+		"",
+	},
+	"$pull condition": {
+		bson.D{{Key: "_id", Value: 1}, {Key: "a", Value: bson.A{1, 5, 9}}},
+		bson.D{{Key: "$pull", Value: bson.D{{Key: "a", Value: bson.D{{Key: "$gte", Value: 5}}}}}},
+		false,
+		bson.D{{Key: "_id", Value: 1}, {Key: "a", Value: bson.A{1}}},
+		"",
+	},
+	"$pop": {
+		bson.D{{Key: "_id", Value: 1}, {Key: "a", Value: bson.A{1, 2, 3}}},
+		bson.D{{Key: "$pop", Value: bson.D{{Key: "a", Value: -1}}}},
+		false,
+		bson.D{{Key: "_id", Value: 1}, {Key: "a", Value: bson.A{2, 3}}},
+		"",
+	},
+	"$rename": {
+		bson.D{{Key: "_id", Value: 1}, {Key: "a", Value: 1}},
+		bson.D{{Key: "$rename", Value: bson.D{{Key: "a", Value: "b"}}}},
+		false,
+		bson.D{{Key: "_id", Value: 1}, {Key: "b", Value: 1}},
+		"",
+	},
+	"$setOnInsert updating": {
+		bson.D{{Key: "_id", Value: 1}},
+		bson.D{{Key: "$setOnInsert", Value: bson.D{{Key: "a", Value: 1}}}},
+		false,
+		bson.D{{Key: "_id", Value: 1}},
+		"",
+	},
+	"$setOnInsert inserting": {
+		bson.D{{Key: "_id", Value: 1}},
+		bson.D{{Key: "$setOnInsert", Value: bson.D{{Key: "a", Value: 1}}}},
+		true,
+		bson.D{{Key: "_id", Value: 1}, {Key: "a", Value: 1}},
+		"",
+	},
+	"changing _id": {
+		bson.D{{Key: "_id", Value: 1}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "_id", Value: 2}}}},
+		false,
+		nil,
+		"After applying the update, the (immutable) field '_id' was found to have been altered",
+	},
+	"unknown modifier": {
+		bson.D{{Key: "_id", Value: 1}},
+		bson.D{{Key: "$foo", Value: bson.D{{Key: "a", Value: 1}}}},
+		false,
+		nil,
+		"Unknown modifier: $foo",
+	},
+}
+
+func Test_ApplyUpdate(t *testing.T) {
+	for tn, tt := range applyUpdateTests {
+		doc, _ := toDoc(tt.doc)
+		update, _ := toDoc(tt.update)
+		expected, _ := toDoc(tt.expected)
+
+		res, err := applyUpdate(doc, update, tt.inserting)
+
+		if tt.err == "" {
+			assert.Nilf(t, err, "Expected nil err for applyUpdate on test '%s'", tn)
+			assert.Equalf(t, expected, res, "Expected updated document on test '%s'", tn)
+		} else {
+			assert.EqualErrorf(t, err, tt.err, "Expected error for applyUpdate on test '%s'", tn)
+		}
+	}
+}
+
+var upsertSeedTests = map[string]struct {
+	filter   bson.D
+	expected bson.D
+}{
+	"equality":  {bson.D{{Key: "a", Value: 1}, {Key: "b.c", Value: 2}}, bson.D{{Key: "a", Value: 1}, {Key: "b", Value: bson.D{{Key: "c", Value: 2}}}}},
+	"$eq":       {bson.D{{Key: "a", Value: bson.D{{Key: "$eq", Value: 1}}}}, bson.D{{Key: "a", Value: 1}}},
+	"operators": {bson.D{{Key: "a", Value: bson.D{{Key: "$gt", Value: 1}}}, {Key: "b", Value: 2}}, bson.D{{Key: "b", Value: 2}}},
+	"$and":      {bson.D{{Key: "$and", Value: bson.A{bson.D{{Key: "a", Value: 1}}, bson.D{{Key: "b", Value: 2}}}}}, bson.D{{Key: "a", Value: 1}, {Key: "b", Value: 2}}},
+	"$or":       {bson.D{{Key: "$or", Value: bson.A{bson.D{{Key: "a", Value: 1}}}}}, bson.D{}},
+}
+
+func Test_UpsertSeed(t *testing.T) {
+	for tn, tt := range upsertSeedTests {
+		filter, _ := toDoc(tt.filter)
+		expected, _ := toDoc(tt.expected)
+
+		res, err := upsertSeed(filter)
+
+		assert.Nilf(t, err, "Expected nil err for upsertSeed on test '%s'", tn)
+		assert.Equalf(t, expected, res, "Expected seed document on test '%s'", tn)
+	}
+}
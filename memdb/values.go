@@ -0,0 +1,485 @@
+package memdb
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// toDoc converts any marshallable value to a bson.D, normalising nested
+// documents to primitive.D and arrays to primitive.A as the server would.
+// The result never shares memory with v.
+func toDoc(v interface{}) (bson.D, error) {
+	if v == nil {
+		return bson.D{}, nil
+	}
+	b, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	doc := bson.D{}
+	err = bson.Unmarshal(b, &doc)
+	return doc, err
+}
+
+// toDocs converts an array of documents, such as a pipeline, to a []bson.D
+func toDocs(v interface{}) ([]bson.D, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := bson.Marshal(bson.M{"v": v})
+	if err != nil {
+		return nil, err
+	}
+	var wrapper struct {
+		V []bson.D `bson:"v"`
+	}
+	err = bson.Unmarshal(b, &wrapper)
+	return wrapper.V, err
+}
+
+// cloneDoc makes a deep copy of a stored document
+func cloneDoc(doc bson.D) bson.D {
+	c, err := toDoc(doc)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// decode unmarshals a stored document into v
+func decode(doc bson.D, v interface{}) error {
+	b, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(b, v)
+}
+
+// sameDoc reports whether two documents have identical BSON encodings
+func sameDoc(a, b bson.D) bool {
+	ab, errA := bson.Marshal(a)
+	bb, errB := bson.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ab, bb)
+}
+
+// get gets the top level field of a document
+func get(doc bson.D, key string) (interface{}, bool) {
+	for _, e := range doc {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+// set sets the top level field of a document, keeping its position if present
+func set(doc bson.D, key string, v interface{}) bson.D {
+	for i, e := range doc {
+		if e.Key == key {
+			doc[i].Value = v
+			return doc
+		}
+	}
+	return append(doc, bson.E{Key: key, Value: v})
+}
+
+// remove removes the top level field of a document
+func remove(doc bson.D, key string) bson.D {
+	for i, e := range doc {
+		if e.Key == key {
+			return append(doc[:i:i], doc[i+1:]...)
+		}
+	}
+	return doc
+}
+
+// withIDFirst moves the _id field to the start of the document
+func withIDFirst(doc bson.D) bson.D {
+	id, ok := get(doc, "_id")
+	if !ok || doc[0].Key == "_id" {
+		return doc
+	}
+	return append(bson.D{{Key: "_id", Value: id}}, remove(doc, "_id")...)
+}
+
+// splitPath splits a dotted field path
+func splitPath(path string) []string {
+	return strings.Split(path, ".")
+}
+
+// lookup gets every value at the path, descending into arrays as query
+// filters do. A missing field gives no values.
+func lookup(v interface{}, path []string) []interface{} {
+	if len(path) == 0 {
+		return []interface{}{v}
+	}
+	switch t := v.(type) {
+	case primitive.D:
+		val, ok := get(t, path[0])
+		if !ok {
+			return nil
+		}
+		return lookup(val, path[1:])
+	case primitive.A:
+		var out []interface{}
+		if i, err := strconv.Atoi(path[0]); err == nil && i >= 0 {
+			if i < len(t) {
+				out = append(out, lookup(t[i], path[1:])...)
+			}
+			return out
+		}
+		for _, e := range t {
+			if d, ok := e.(primitive.D); ok {
+				out = append(out, lookup(d, path)...)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// getPath gets the single value at the path, only descending into arrays
+// by numeric index
+func getPath(v interface{}, path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		return v, true
+	}
+	switch t := v.(type) {
+	case primitive.D:
+		val, ok := get(t, path[0])
+		if !ok {
+			return nil, false
+		}
+		return getPath(val, path[1:])
+	case primitive.A:
+		i, err := strconv.Atoi(path[0])
+		if err != nil || i < 0 || i >= len(t) {
+			return nil, false
+		}
+		return getPath(t[i], path[1:])
+	}
+	return nil, false
+}
+
+// setPath sets the value at the path, creating documents along the way
+func setPath(v interface{}, path []string, val interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case primitive.D:
+		if len(path) == 1 {
+			return set(t, path[0], val), nil
+		}
+		cur, ok := get(t, path[0])
+		if !ok || cur == nil {
+			cur = primitive.D{}
+		}
+		nv, err := setPath(cur, path[1:], val)
+		if err != nil {
+			return nil, err
+		}
+		return set(t, path[0], nv), nil
+	case primitive.A:
+		i, err := strconv.Atoi(path[0])
+		if err != nil || i < 0 {
+			return nil, newError(codePathNotViable, "PathNotViable", "Cannot create field '%s' in element {%s}", path[0], formatValue(t))
+		}
+		for len(t) <= i {
+			t = append(t, nil)
+		}
+		if len(path) == 1 {
+			t[i] = val
+			return t, nil
+		}
+		cur := t[i]
+		if cur == nil {
+			cur = primitive.D{}
+		}
+		nv, err := setPath(cur, path[1:], val)
+		if err != nil {
+			return nil, err
+		}
+		t[i] = nv
+		return t, nil
+	}
+	return nil, newError(codePathNotViable, "PathNotViable", "Cannot create field '%s' in element {%s}", path[0], formatValue(v))
+}
+
+// unsetPath removes the value at the path, nulling array elements in place
+func unsetPath(v interface{}, path []string) interface{} {
+	switch t := v.(type) {
+	case primitive.D:
+		if len(path) == 1 {
+			return remove(t, path[0])
+		}
+		if cur, ok := get(t, path[0]); ok {
+			return set(t, path[0], unsetPath(cur, path[1:]))
+		}
+	case primitive.A:
+		i, err := strconv.Atoi(path[0])
+		if err != nil || i < 0 || i >= len(t) {
+			return t
+		}
+		if len(path) == 1 {
+			t[i] = nil
+		} else {
+			t[i] = unsetPath(t[i], path[1:])
+		}
+	}
+	return v
+}
+
+// typeOrder gets the position of a value's type in the BSON comparison order
+func typeOrder(v interface{}) int {
+	switch v.(type) {
+	case primitive.MinKey:
+		return 1
+	case nil, primitive.Null, primitive.Undefined:
+		return 2
+	case int32, int64, float64, primitive.Decimal128:
+		return 3
+	case string, primitive.Symbol:
+		return 4
+	case primitive.D:
+		return 5
+	case primitive.A:
+		return 6
+	case primitive.Binary:
+		return 7
+	case primitive.ObjectID:
+		return 8
+	case bool:
+		return 9
+	case primitive.DateTime:
+		return 10
+	case primitive.Timestamp:
+		return 11
+	case primitive.Regex:
+		return 12
+	case primitive.MaxKey:
+		return 14
+	}
+	return 13
+}
+
+// compareValues orders two values as the server does, returning -1, 0 or 1
+func compareValues(a, b interface{}) int {
+	oa, ob := typeOrder(a), typeOrder(b)
+	if oa != ob {
+		return sign(int64(oa - ob))
+	}
+
+	switch av := a.(type) {
+	case int32, int64, float64, primitive.Decimal128:
+		ai, aInt := toInt64(a)
+		bi, bInt := toInt64(b)
+		if aInt && bInt {
+			return sign(ai - bi)
+		}
+		af, bf := toFloat(a), toFloat(b)
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(av, toString(b))
+	case primitive.Symbol:
+		return strings.Compare(string(av), toString(b))
+	case primitive.D:
+		bv := b.(primitive.D)
+		for i := 0; i < len(av) && i < len(bv); i++ {
+			if c := compareValues(av[i].Value, bv[i].Value); c != 0 {
+				return c
+			}
+			if c := strings.Compare(av[i].Key, bv[i].Key); c != 0 {
+				return c
+			}
+		}
+		return sign(int64(len(av) - len(bv)))
+	case primitive.A:
+		bv := b.(primitive.A)
+		for i := 0; i < len(av) && i < len(bv); i++ {
+			if c := compareValues(av[i], bv[i]); c != 0 {
+				return c
+			}
+		}
+		return sign(int64(len(av) - len(bv)))
+	case primitive.Binary:
+		bv := b.(primitive.Binary)
+		if av.Subtype != bv.Subtype {
+			return sign(int64(av.Subtype) - int64(bv.Subtype))
+		}
+		return bytes.Compare(av.Data, bv.Data)
+	case primitive.ObjectID:
+		bv := b.(primitive.ObjectID)
+		return bytes.Compare(av[:], bv[:])
+	case bool:
+		bv := b.(bool)
+		if av == bv {
+			return 0
+		}
+		if bv {
+			return -1
+		}
+		return 1
+	case primitive.DateTime:
+		return sign(int64(av) - int64(b.(primitive.DateTime)))
+	case primitive.Timestamp:
+		bv := b.(primitive.Timestamp)
+		if av.T != bv.T {
+			return sign(int64(av.T) - int64(bv.T))
+		}
+		return sign(int64(av.I) - int64(bv.I))
+	case primitive.Regex:
+		bv := b.(primitive.Regex)
+		if c := strings.Compare(av.Pattern, bv.Pattern); c != 0 {
+			return c
+		}
+		return strings.Compare(av.Options, bv.Options)
+	}
+	return 0
+}
+
+func sign(n int64) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+func toString(v interface{}) string {
+	if s, ok := v.(primitive.Symbol); ok {
+		return string(s)
+	}
+	s, _ := v.(string)
+	return s
+}
+
+// isNumber reports whether the value is a BSON number
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case int32, int64, float64:
+		return true
+	}
+	return false
+}
+
+// toInt64 gets the value of a whole number, reporting false for other values
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		if n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+			return int64(n), true
+		}
+	}
+	return 0, false
+}
+
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	case primitive.Decimal128:
+		f, err := strconv.ParseFloat(n.String(), 64)
+		if err == nil {
+			return f
+		}
+	}
+	return math.NaN()
+}
+
+// addNumbers adds two numbers, widening the result type as the server does
+func addNumbers(a, b interface{}) interface{} {
+	if _, ok := a.(float64); ok {
+		return toFloat(a) + toFloat(b)
+	}
+	if _, ok := b.(float64); ok {
+		return toFloat(a) + toFloat(b)
+	}
+	ai, _ := toInt64(a)
+	bi, _ := toInt64(b)
+	sum := ai + bi
+	_, a32 := a.(int32)
+	_, b32 := b.(int32)
+	if a32 && b32 && sum >= math.MinInt32 && sum <= math.MaxInt32 {
+		return int32(sum)
+	}
+	return sum
+}
+
+// mulNumbers multiplies two numbers, widening the result type as the server does
+func mulNumbers(a, b interface{}) interface{} {
+	if _, ok := a.(float64); ok {
+		return toFloat(a) * toFloat(b)
+	}
+	if _, ok := b.(float64); ok {
+		return toFloat(a) * toFloat(b)
+	}
+	ai, _ := toInt64(a)
+	bi, _ := toInt64(b)
+	prod := ai * bi
+	_, a32 := a.(int32)
+	_, b32 := b.(int32)
+	if a32 && b32 && prod >= math.MinInt32 && prod <= math.MaxInt32 {
+		return int32(prod)
+	}
+	return prod
+}
+
+// truthy reports whether a value is considered true in a projection or expression
+func truthy(v interface{}) bool {
+	switch t := v.(type) {
+	case nil, primitive.Null, primitive.Undefined:
+		return false
+	case bool:
+		return t
+	case int32, int64, float64:
+		return toFloat(t) != 0
+	}
+	return true
+}
+
+// formatValue formats a value in the shell notation used by server messages
+func formatValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil, primitive.Null:
+		return "null"
+	case string:
+		return strconv.Quote(t)
+	case primitive.ObjectID:
+		return "ObjectId('" + t.Hex() + "')"
+	case primitive.DateTime:
+		return fmt.Sprintf("new Date(%d)", int64(t))
+	case primitive.D:
+		parts := make([]string, len(t))
+		for i, e := range t {
+			parts[i] = e.Key + ": " + formatValue(e.Value)
+		}
+		return "{ " + strings.Join(parts, ", ") + " }"
+	case primitive.A:
+		parts := make([]string, len(t))
+		for i, e := range t {
+			parts[i] = formatValue(e)
+		}
+		return "[ " + strings.Join(parts, ", ") + " ]"
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -0,0 +1,178 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/archy-bold/mongo-go-helper/migration/schema"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DefaultBackfillBatchSize is the number of documents a backfill task reads
+// and writes at a time unless its BatchSize is set
+const DefaultBackfillBatchSize = 1000
+
+// backfill transforms the task's documents a batch at a time, resuming from
+// the checkpoint in the history
+func (m *migrator) backfill(ctx context.Context, tn string, task *schema.BackfillTask) error {
+	if task.Collection == "" {
+		return ErrNoCollection
+	}
+	if task.Transform == nil {
+		return ErrNoTransform
+	}
+	batchSize := task.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBackfillBatchSize
+	}
+
+	hist, err := m.getHistory(ctx, tn)
+	if err != nil {
+		return err
+	}
+	if hist == nil {
+		hist = &taskHistory{Name: tn}
+	}
+	task.Stats = schema.BackfillStats{Processed: hist.Processed, Updated: hist.Updated}
+	if hist.Done {
+		return nil
+	}
+
+	for {
+		docs, err := m.helper.Aggregate(ctx, task.Collection, backfillPipeline(task.Filter, hist.Checkpoint, batchSize))
+		if err != nil {
+			return err
+		}
+		if len(docs) == 0 {
+			break
+		}
+
+		var models []mongo.WriteModel
+		for _, doc := range docs {
+			update, err := task.Transform(doc)
+			if err != nil {
+				return fmt.Errorf("could not transform document '%v': %w", doc["_id"], err)
+			}
+			if update != nil {
+				models = append(models, mongo.NewUpdateOneModel().SetFilter(bson.D{{Key: "_id", Value: doc["_id"]}}).SetUpdate(update))
+			}
+		}
+		var updated int64
+		if len(models) > 0 {
+			res, err := m.helper.BulkWrite(ctx, task.Collection, models)
+			if err != nil {
+				return err
+			}
+			if res != nil {
+				updated = res.ModifiedCount
+			}
+		}
+
+		hist.Checkpoint = docs[len(docs)-1]["_id"]
+		hist.Processed += len(docs)
+		hist.Updated += int(updated)
+		task.Stats = schema.BackfillStats{Processed: hist.Processed, Updated: hist.Updated}
+		if err := m.saveHistory(ctx, hist); err != nil {
+			return err
+		}
+		if len(docs) < batchSize {
+			break
+		}
+
+		if task.Throttle > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(task.Throttle):
+			}
+		}
+	}
+
+	hist.Done = true
+	return m.saveHistory(ctx, hist)
+}
+
+// idTypes are the type aliases of the values an _id may have, grouped by
+// their place in the BSON sort order. Query comparisons only match values
+// in the same group.
+var idTypes = [][]string{
+	{"minKey"},
+	{"null"},
+	{"int", "long", "double", "decimal"},
+	{"string"},
+	{"object"},
+	{"binData"},
+	{"objectId"},
+	{"bool"},
+	{"date"},
+	{"timestamp"},
+	{"regex"},
+	{"maxKey"},
+}
+
+// idTypeGroup gets the group in idTypes of the _id's type, or -1 if unknown
+func idTypeGroup(id interface{}) int {
+	switch id.(type) {
+	case primitive.MinKey:
+		return 0
+	case nil, primitive.Null:
+		return 1
+	case int32, int64, float64, primitive.Decimal128:
+		return 2
+	case string:
+		return 3
+	case primitive.M, primitive.D:
+		return 4
+	case primitive.Binary:
+		return 5
+	case primitive.ObjectID:
+		return 6
+	case bool:
+		return 7
+	case primitive.DateTime:
+		return 8
+	case primitive.Timestamp:
+		return 9
+	case primitive.Regex:
+		return 10
+	case primitive.MaxKey:
+		return 11
+	}
+	return -1
+}
+
+// afterCheckpoint gets the filter matching the _ids sorted after the
+// checkpoint, those greater of its type and those of the types after it
+func afterCheckpoint(checkpoint interface{}) bson.D {
+	after := bson.D{{Key: "_id", Value: bson.D{{Key: "$gt", Value: checkpoint}}}}
+	group := idTypeGroup(checkpoint)
+	if group < 0 || group == len(idTypes)-1 {
+		return after
+	}
+
+	var types bson.A
+	for _, aliases := range idTypes[group+1:] {
+		for _, alias := range aliases {
+			types = append(types, alias)
+		}
+	}
+	return bson.D{{Key: "$or", Value: bson.A{after, bson.D{{Key: "_id", Value: bson.D{{Key: "$type", Value: types}}}}}}}
+}
+
+// backfillPipeline gets the pipeline reading the batch of documents after the checkpoint
+func backfillPipeline(filter interface{}, checkpoint interface{}, batchSize int) mongo.Pipeline {
+	if filter == nil {
+		filter = bson.D{}
+	}
+	if checkpoint != nil {
+		filter = bson.D{{Key: "$and", Value: bson.A{filter, afterCheckpoint(checkpoint)}}}
+	}
+	return mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: batchSize}},
+	}
+}
@@ -0,0 +1,215 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/memdb"
+	"github.com/archy-bold/mongo-go-helper/migration/schema"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var errTransform = errors.New("transform failed")
+
+// setupBackfill gets a database of people with IDs 1 to 5, the fourth
+// already having a full name
+func setupBackfill(ctx context.Context) base.MongoDB {
+	db := memdb.NewDatabase(testDB)
+	for i := 1; i <= 5; i++ {
+		doc := bson.M{"_id": i, "first": fmt.Sprintf("first%d", i), "last": "last"}
+		if i == 4 {
+			doc["fullName"] = "first4 last"
+		}
+		db.Collection("people").InsertOne(ctx, doc)
+	}
+	return db
+}
+
+// fullNameTransform sets the full name of people without one, recording the
+// IDs it's called with and failing on the ID in failOn
+func fullNameTransform(calls *[]int32, failOn int32) schema.TransformFunction {
+	return func(doc bson.M) (interface{}, error) {
+		id := doc["_id"].(int32)
+		*calls = append(*calls, id)
+		if id == failOn {
+			return nil, errTransform
+		}
+		if _, ok := doc["fullName"]; ok {
+			return nil, nil
+		}
+		return bson.M{"$set": bson.M{"fullName": doc["first"].(string) + " " + doc["last"].(string)}}, nil
+	}
+}
+
+func Test_Backfill_MemDB(t *testing.T) {
+	ctx := context.Background()
+	db := setupBackfill(ctx)
+	m := NewMigrator(base.NewHelper(db))
+	var calls []int32
+	task := &schema.BackfillTask{
+		Task:      schema.Task{Collection: "people"},
+		Transform: fullNameTransform(&calls, 0),
+		BatchSize: 2,
+	}
+
+	err := m.Run(ctx, map[string]schema.TaskContract{"01_full_names": task})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []int32{1, 2, 3, 4, 5}, calls, "Expected every document to be transformed in _id order")
+	assert.Equal(t, schema.BackfillStats{Processed: 5, Updated: 4}, task.Stats)
+	assertCount(t, db, "people", bson.M{"fullName": bson.M{"$exists": true}}, 5)
+	assertCount(t, db, "people", bson.M{"_id": 2, "fullName": "first2 last"}, 1)
+	assertCount(t, db, DefaultHistoryCollection, bson.M{"_id": "01_full_names", "checkpoint": 5, "processed": 5, "updated": 4, "done": true}, 1)
+
+	// Finished backfills are skipped
+	calls = nil
+	err = m.Run(ctx, map[string]schema.TaskContract{"01_full_names": task})
+	assert.Nil(t, err)
+	assert.Empty(t, calls, "Expected a finished backfill not to run again")
+	assert.Equal(t, schema.BackfillStats{Processed: 5, Updated: 4}, task.Stats, "Expected the stats to be kept")
+}
+
+func Test_Backfill_Resume_MemDB(t *testing.T) {
+	ctx := context.Background()
+	db := setupBackfill(ctx)
+	m := NewMigratorWithOptions(base.NewHelper(db), MigratorOptions{HistoryCollection: "history"})
+
+	// The first run fails in the second batch
+	var calls []int32
+	task := &schema.BackfillTask{
+		Task:      schema.Task{Collection: "people"},
+		Transform: fullNameTransform(&calls, 3),
+		BatchSize: 2,
+	}
+	err := m.Run(ctx, map[string]schema.TaskContract{"01_full_names": task})
+	assert.Equal(t, buildMultiError([]error{fmt.Errorf("could not transform document '3': %w", errTransform)}), err)
+	assert.Equal(t, schema.BackfillStats{Processed: 2, Updated: 2}, task.Stats)
+	assertCount(t, db, "history", bson.M{"_id": "01_full_names", "checkpoint": 2, "done": false}, 1)
+	assertCount(t, db, "people", bson.M{"fullName": bson.M{"$exists": true}}, 3)
+
+	// The second run resumes from the checkpoint
+	calls = nil
+	task.Transform = fullNameTransform(&calls, 0)
+	err = m.Run(ctx, map[string]schema.TaskContract{"01_full_names": task})
+	assert.Nil(t, err)
+	assert.Equal(t, []int32{3, 4, 5}, calls, "Expected the backfill to resume after the last written batch")
+	assert.Equal(t, schema.BackfillStats{Processed: 5, Updated: 4}, task.Stats)
+	assertCount(t, db, "people", bson.M{"fullName": bson.M{"$exists": true}}, 5)
+}
+
+func Test_Backfill_Unmodified_MemDB(t *testing.T) {
+	ctx := context.Background()
+	db := setupBackfill(ctx)
+	m := NewMigrator(base.NewHelper(db))
+	task := &schema.BackfillTask{
+		Task: schema.Task{Collection: "people"},
+		Transform: func(doc bson.M) (interface{}, error) {
+			return bson.M{"$set": bson.M{"fullName": doc["first"].(string) + " " + doc["last"].(string)}}, nil
+		},
+		BatchSize: 2,
+	}
+
+	err := m.Run(ctx, map[string]schema.TaskContract{"01_full_names": task})
+
+	assert.Nil(t, err)
+	assert.Equal(t, schema.BackfillStats{Processed: 5, Updated: 4}, task.Stats, "Expected updates leaving documents unchanged not to count")
+}
+
+func Test_Backfill_MixedIDs_MemDB(t *testing.T) {
+	ctx := context.Background()
+	db := memdb.NewDatabase(testDB)
+	oid := primitive.NewObjectID()
+	for _, id := range []interface{}{oid, "c", 2, 1} {
+		db.Collection("people").InsertOne(ctx, bson.M{"_id": id})
+	}
+	m := NewMigrator(base.NewHelper(db))
+	var calls []interface{}
+	task := &schema.BackfillTask{
+		Task: schema.Task{Collection: "people"},
+		Transform: func(doc bson.M) (interface{}, error) {
+			calls = append(calls, doc["_id"])
+			return bson.M{"$set": bson.M{"done": true}}, nil
+		},
+		BatchSize: 2,
+	}
+
+	err := m.Run(ctx, map[string]schema.TaskContract{"01_mark": task})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{int32(1), int32(2), "c", oid}, calls, "Expected the _ids to be read across types in sort order")
+	assert.Equal(t, schema.BackfillStats{Processed: 4, Updated: 4}, task.Stats)
+	assertCount(t, db, "people", bson.M{"done": true}, 4)
+}
+
+func Test_Backfill_Filter_MemDB(t *testing.T) {
+	ctx := context.Background()
+	db := setupBackfill(ctx)
+	m := NewMigrator(base.NewHelper(db))
+	var calls []int32
+	task := &schema.BackfillTask{
+		Task:      schema.Task{Collection: "people"},
+		Filter:    bson.M{"_id": bson.M{"$in": bson.A{1, 3, 5}}},
+		Transform: fullNameTransform(&calls, 0),
+		BatchSize: 2,
+	}
+
+	err := m.Run(ctx, map[string]schema.TaskContract{"01_full_names": task})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []int32{1, 3, 5}, calls, "Expected only the filtered documents to be transformed")
+	assertCount(t, db, "people", bson.M{"_id": 2, "fullName": bson.M{"$exists": true}}, 0)
+}
+
+func Test_Backfill_Throttle_MemDB(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	db := setupBackfill(ctx)
+	m := NewMigrator(base.NewHelper(db))
+	var calls []int32
+	task := &schema.BackfillTask{
+		Task:      schema.Task{Collection: "people"},
+		Transform: fullNameTransform(&calls, 0),
+		BatchSize: 2,
+		Throttle:  time.Hour,
+	}
+
+	err := m.Run(ctx, map[string]schema.TaskContract{"01_full_names": task})
+
+	assert.Equal(t, buildMultiError([]error{context.DeadlineExceeded}), err, "Expected the throttle to stop when the context is done")
+	assert.Equal(t, []int32{1, 2}, calls)
+	assertCount(t, db, DefaultHistoryCollection, bson.M{"_id": "01_full_names", "checkpoint": 2}, 1)
+}
+
+var backfillErrorsTests = map[string]struct {
+	task *schema.BackfillTask
+	err  error
+}{
+	"no collection": {&schema.BackfillTask{Transform: fullNameTransform(&[]int32{}, 0)}, ErrNoCollection},
+	"no transform":  {&schema.BackfillTask{Task: schema.Task{Collection: "people"}}, ErrNoTransform},
+}
+
+func Test_Backfill_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range backfillErrorsTests {
+		m := &migrator{helper: base.NewHelper(setupBackfill(ctx))}
+
+		err := m.runTask(ctx, tn, tt.task)
+
+		assert.Equalf(t, tt.err, err, "Expected err to match on test '%s'", tn)
+	}
+}
+
+// assertCount asserts the number of documents in the collection matching the filter
+func assertCount(t *testing.T, db base.MongoDB, collection string, filter interface{}, expected int64) {
+	t.Helper()
+	count, err := db.Collection(collection).CountDocuments(context.Background(), filter)
+	assert.Nil(t, err)
+	assert.Equalf(t, expected, count, "Expected %d documents in '%s' matching %v", expected, collection, filter)
+}
@@ -0,0 +1,281 @@
+package migration
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/migration/schema"
+	multierror "github.com/hashicorp/go-multierror"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// batchItem represents an item being seeded as part of a batch
+type batchItem struct {
+	item      base.ModelInterface
+	filter    interface{}
+	model     int // index of the item's write model, -1 if there is none
+	unchanged bool
+	failed    bool
+}
+
+// existingDoc represents a document found for a batch
+type existingDoc struct {
+	raw   bson.Raw
+	model base.ModelInterface
+}
+
+func (s *seeder) seedBatches(ctx context.Context, task *schema.SeedTableTask) error {
+	var errs *multierror.Error
+
+	for start := 0; start < len(task.Items); {
+		end := start + task.BatchSize
+		if end > len(task.Items) {
+			end = len(task.Items)
+		}
+		n, err := s.seedBatch(ctx, task, task.Items[start:end])
+		if err != nil {
+			errs = multierror.Append(errs, err.Errors...)
+		}
+		start += n
+	}
+	return errs.ErrorOrNil()
+}
+
+// seedBatch seeds the items with a single lookup and bulk write, returning
+// how many it seeded. The batch ends early at an item that would match or
+// reference an earlier item of the batch, so it's seeded in the next batch
+// once the earlier one is written, as it would be unbatched.
+func (s *seeder) seedBatch(ctx context.Context, task *schema.SeedTableTask, items []base.ModelInterface) (int, *multierror.Error) {
+	var errs *multierror.Error
+
+	// Get the filters for every item in the batch
+	seeded := len(items)
+	batch := make([]*batchItem, 0, len(items))
+	filters := make([]interface{}, 0, len(items))
+	pending := make([]*existingDoc, 0, len(items))
+	for i, it := range items {
+		item := copyItem(it)
+		err := s.refs.resolve(item)
+		if i > 0 && errors.Is(err, ErrUnresolvedRef) {
+			seeded = i
+			break
+		}
+
+		var filter interface{}
+		if err == nil {
+			filter, err = task.FindFilterFn(item)
+		}
+		if err == nil && filter != nil {
+			if ex, _ := matchExisting(pending, filter); ex != nil {
+				seeded = i
+				break
+			}
+		}
+		if err != nil {
+			errs = multierror.Append(errs, err)
+			continue
+		}
+		batch = append(batch, &batchItem{item: item, filter: filter, model: -1})
+		if filter != nil {
+			filters = append(filters, filter)
+		}
+		if raw, err := bson.Marshal(item); err == nil {
+			pending = append(pending, &existingDoc{raw: raw})
+		}
+	}
+
+	// Look up all the existing items at once
+	existing, err := s.findExisting(ctx, task, filters)
+	if err != nil {
+		return seeded, multierror.Append(errs, err)
+	}
+
+	// Build the writes for the items that are new or have changed
+	models := make([]mongo.WriteModel, 0, len(batch))
+	for _, bi := range batch {
+		ex, err := matchExisting(existing, bi.filter)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+			bi.failed = true
+			continue
+		}
+
+		if ex == nil {
+			if err = assignID(ctx, task, bi.item); err != nil {
+				errs = multierror.Append(errs, err)
+				bi.failed = true
+				continue
+			}
+			bi.model = len(models)
+			models = append(models, mongo.NewInsertOneModel().SetDocument(bi.item))
+			continue
+		}
+
+		keepExistingID(bi.item, ex.model)
+		keepExistingFields(bi.item, ex.model)
+		bi.unchanged, err = isUnchanged(bi.item, ex.model)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+			bi.failed = true
+		} else if !bi.unchanged {
+			bi.model = len(models)
+			models = append(models, mongo.NewReplaceOneModel().SetFilter(bi.filter).SetReplacement(bi.item))
+		}
+	}
+
+	// Write the changes and mark any failed models
+	failed := make(map[int]bool)
+	if len(models) > 0 {
+		// Run unordered so a single failed write doesn't stop the rest
+		_, err = s.helper.BulkWrite(ctx, task.Collection, models, options.BulkWrite().SetOrdered(false))
+		var bwe mongo.BulkWriteException
+		if errors.As(err, &bwe) {
+			for _, we := range bwe.WriteErrors {
+				failed[we.Index] = true
+				errs = multierror.Append(errs, we)
+			}
+			if bwe.WriteConcernError != nil {
+				errs = multierror.Append(errs, bwe.WriteConcernError)
+			}
+		} else if err != nil {
+			for i := range models {
+				failed[i] = true
+			}
+			errs = multierror.Append(errs, err)
+		}
+	}
+
+	for _, bi := range batch {
+		switch {
+		case bi.failed || (bi.model >= 0 && failed[bi.model]):
+			continue
+		case bi.unchanged:
+			task.Stats.Unchanged++
+		case isInsert(models[bi.model]):
+			task.Stats.Inserted++
+		default:
+			task.Stats.Updated++
+		}
+		s.refs.register(task, bi.item)
+		if task.Callback != nil {
+			task.Callback(bi.item)
+		}
+	}
+	return seeded, errs
+}
+
+// findExisting gets the documents matching any of the filters with a single query
+func (s *seeder) findExisting(ctx context.Context, task *schema.SeedTableTask, filters []interface{}) ([]*existingDoc, error) {
+	if len(filters) == 0 {
+		return nil, nil
+	}
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"$or": filters}}},
+	}
+	docs, err := s.helper.Aggregate(ctx, task.Collection, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make([]*existingDoc, 0, len(docs))
+	for _, doc := range docs {
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		model := newModel(task.Model)
+		if err = bson.Unmarshal(raw, model); err != nil {
+			return nil, err
+		}
+		existing = append(existing, &existingDoc{raw, model})
+	}
+	return existing, nil
+}
+
+// matchExisting finds the first existing document matching the given equality filter
+func matchExisting(existing []*existingDoc, filter interface{}) (*existingDoc, error) {
+	if filter == nil {
+		return nil, nil
+	}
+
+	f, err := bson.Marshal(filter)
+	if err != nil {
+		return nil, err
+	}
+	elems, err := bson.Raw(f).Elements()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ex := range existing {
+		matches := true
+		for _, el := range elems {
+			val := el.Value()
+			if val.Type == bsontype.EmbeddedDocument {
+				if op, err := val.Document().IndexErr(0); err == nil && strings.HasPrefix(op.Key(), "$") {
+					return nil, ErrUnsupportedBatchFilter
+				}
+			}
+			if strings.HasPrefix(el.Key(), "$") {
+				return nil, ErrUnsupportedBatchFilter
+			}
+
+			docVal, err := ex.raw.LookupErr(strings.Split(el.Key(), ".")...)
+			if err != nil || !equalValues(docVal, val) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return ex, nil
+		}
+	}
+	return nil, nil
+}
+
+// equalValues compares two bson values, treating all numeric types as comparable
+func equalValues(a, b bson.RawValue) bool {
+	if ai, ok := toInt(a); ok {
+		if bi, ok := toInt(b); ok {
+			return ai == bi
+		}
+	}
+	if af, ok := toFloat(a); ok {
+		if bf, ok := toFloat(b); ok {
+			return af == bf
+		}
+	}
+	return a.Type == b.Type && bytes.Equal(a.Value, b.Value)
+}
+
+func toInt(v bson.RawValue) (int64, bool) {
+	switch v.Type {
+	case bsontype.Int32:
+		return int64(v.Int32()), true
+	case bsontype.Int64:
+		return v.Int64(), true
+	}
+	return 0, false
+}
+
+func toFloat(v bson.RawValue) (float64, bool) {
+	if i, ok := toInt(v); ok {
+		return float64(i), true
+	}
+	if v.Type == bsontype.Double {
+		return v.Double(), true
+	}
+	return 0, false
+}
+
+func isInsert(model mongo.WriteModel) bool {
+	_, ok := model.(*mongo.InsertOneModel)
+	return ok
+}
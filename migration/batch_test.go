@@ -0,0 +1,145 @@
+package migration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/migration/schema"
+	bMocks "github.com/archy-bold/mongo-go-helper/mocks/base"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var seedBatchesTests = map[string]struct {
+	items     []base.ModelInterface
+	batchSize int
+	existing  []bson.M
+	bulkErr   error
+	writes    []int
+	expected  schema.SeedStats
+	callbacks int
+	err       bool
+}{
+	"all new in one batch": {
+		[]base.ModelInterface{&exampleModel{Str: "test1", Num: 1}, &exampleModel{Str: "test2", Num: 2}},
+		10, nil, nil,
+		[]int{2},
+		schema.SeedStats{Inserted: 2},
+		2, false,
+	},
+	"mixed over two batches": {
+		[]base.ModelInterface{&exampleModel{Str: "new", Num: 1}, &exampleModel{Str: "same", Num: 2}, &exampleModel{Str: "changed", Num: 3}},
+		2,
+		[]bson.M{
+			{"_id": sampleID, "str": "same", "num": int32(2)},
+			{"_id": sampleID, "str": "old", "num": int64(3)},
+		},
+		nil,
+		[]int{1, 1},
+		schema.SeedStats{Inserted: 1, Updated: 1, Unchanged: 1},
+		3, false,
+	},
+	"unchanged skips the write": {
+		[]base.ModelInterface{&exampleModel{Str: "same", Num: 2}},
+		5,
+		[]bson.M{{"_id": sampleID, "str": "same", "num": int32(2)}},
+		nil, nil,
+		schema.SeedStats{Unchanged: 1},
+		1, false,
+	},
+	"partial bulk failure": {
+		[]base.ModelInterface{&exampleModel{Str: "test1", Num: 1}, &exampleModel{Str: "test2", Num: 2}},
+		2, nil,
+		base.MapError(mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{{WriteError: mongo.WriteError{Index: 1, Code: 11000, Message: "duplicate"}}}}),
+		[]int{2},
+		schema.SeedStats{Inserted: 1},
+		1, true,
+	},
+}
+
+func Test_SeedData_Batches(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range seedBatchesTests {
+		// Set up the mocks
+		helper := &bMocks.MongoHelper{}
+		existing := tt.existing
+		helper.On("Aggregate", ctx, "test", mock.AnythingOfType("mongo.Pipeline")).
+			Return(func(ctx context.Context, coll string, pipeline mongo.Pipeline) []bson.M {
+				return existing
+			}, nil)
+		var writes []int
+		helper.On("BulkWrite", ctx, "test", mock.Anything, mock.AnythingOfType("*options.BulkWriteOptions")).
+			Run(func(args mock.Arguments) {
+				writes = append(writes, len(args.Get(2).([]mongo.WriteModel)))
+				assert.Falsef(t, *args.Get(3).(*options.BulkWriteOptions).Ordered, "Expected unordered bulk writes on test '%s'", tn)
+			}).
+			Return(&mongo.BulkWriteResult{}, tt.bulkErr)
+		callbacks := 0
+		s := newSeeder(helper)
+
+		task := &schema.SeedTableTask{
+			Task:         schema.Task{Collection: "test"},
+			Items:        tt.items,
+			FindFilterFn: findFilterFn,
+			Model:        &exampleModel{},
+			Callback:     func(item base.ModelInterface) { callbacks++ },
+			BatchSize:    tt.batchSize,
+		}
+		err := s.SeedData(ctx, task)
+
+		// The assertions
+		if tt.err {
+			assert.NotNilf(t, err, "Expected not nil err for SeedData on test '%s'", tn)
+		} else {
+			assert.Nilf(t, err, "Expected nil err for SeedData on test '%s'", tn)
+		}
+		assert.Equalf(t, tt.writes, writes, "Expected bulk writes to match for SeedData on test '%s'", tn)
+		assert.Equalf(t, tt.expected, task.Stats, "Expected stats to match for SeedData on test '%s'", tn)
+		assert.Equalf(t, tt.callbacks, callbacks, "Expected callbacks to match for SeedData on test '%s'", tn)
+	}
+}
+
+var matchExistingTests = map[string]struct {
+	filter   interface{}
+	expected int
+	err      error
+}{
+	"nil filter":       {nil, -1, nil},
+	"matches int":      {bson.M{"num": 2}, 0, nil},
+	"matches int64":    {bson.M{"num": int64(2)}, 0, nil},
+	"matches float":    {bson.M{"num": 2.0}, 0, nil},
+	"matches nested":   {bson.D{{Key: "sub.type_id", Value: "b"}}, 1, nil},
+	"matches multiple": {bson.M{"num": 3, "str": "other"}, 1, nil},
+	"no match":         {bson.M{"num": 4}, -1, nil},
+	"missing field":    {bson.M{"nope": 1}, -1, nil},
+	"operator":         {bson.M{"num": bson.M{"$gt": 1}}, -1, ErrUnsupportedBatchFilter},
+	"top-level or":     {bson.M{"$or": bson.A{bson.M{"num": 1}}}, -1, ErrUnsupportedBatchFilter},
+}
+
+func Test_MatchExisting(t *testing.T) {
+	docs := []bson.M{
+		{"str": "test", "num": int32(2), "sub": bson.M{"type_id": "a"}},
+		{"str": "other", "num": int64(3), "sub": bson.M{"type_id": "b"}},
+	}
+	existing := make([]*existingDoc, 0, len(docs))
+	for _, doc := range docs {
+		raw, _ := bson.Marshal(doc)
+		existing = append(existing, &existingDoc{raw: raw})
+	}
+
+	for tn, tt := range matchExistingTests {
+		ex, err := matchExisting(existing, tt.filter)
+
+		assert.Equalf(t, tt.err, err, "Expected err to match for matchExisting on test '%s'", tn)
+		if tt.expected < 0 {
+			assert.Nilf(t, ex, "Expected no match for matchExisting on test '%s'", tn)
+		} else {
+			assert.Equalf(t, existing[tt.expected], ex, "Expected match for matchExisting on test '%s'", tn)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package migration
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/migration/schema"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// createCollection creates the task's collection unless it exists
+func (m *migrator) createCollection(ctx context.Context, task *schema.CreateCollectionTask) error {
+	if task.Collection == "" {
+		return ErrNoCollection
+	}
+
+	info, err := m.helper.GetCollection(ctx, task.Collection)
+	if err != nil || info != nil {
+		return err
+	}
+	return m.helper.CreateCollection(ctx, task.Collection, task.Options)
+}
+
+// createView creates the task's view, replacing it if its definition has changed
+func (m *migrator) createView(ctx context.Context, task *schema.CreateViewTask) error {
+	if task.Collection == "" {
+		return ErrNoCollection
+	}
+	if task.ViewOn == "" {
+		return ErrNoViewOn
+	}
+
+	info, err := m.helper.GetCollection(ctx, task.Collection)
+	if err != nil {
+		return err
+	}
+	if info != nil {
+		if info.Type != base.CollectionTypeView {
+			return ErrNotView
+		}
+		if info.Options.ViewOn == task.ViewOn && samePipeline(info.Options.Pipeline, task.Pipeline) {
+			return nil
+		}
+		// Views hold no documents so can be recreated
+		if err := m.helper.DropCollection(ctx, task.Collection); err != nil {
+			return err
+		}
+	}
+	return m.helper.CreateCollection(ctx, task.Collection, base.CollectionOptions{ViewOn: task.ViewOn, Pipeline: task.Pipeline})
+}
+
+// samePipeline gets whether the pipelines have the same stages
+func samePipeline(a mongo.Pipeline, b mongo.Pipeline) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	aBytes, errA := bson.Marshal(bson.D{{Key: "pipeline", Value: a}})
+	bBytes, errB := bson.Marshal(bson.D{{Key: "pipeline", Value: b}})
+	return errA == nil && errB == nil && bytes.Equal(aBytes, bBytes)
+}
+
+// renameCollection renames the task's collection unless it's already been renamed
+func (m *migrator) renameCollection(ctx context.Context, task *schema.RenameCollectionTask) error {
+	if task.Collection == "" {
+		return ErrNoCollection
+	}
+	if task.To == "" {
+		return ErrNoRenameTarget
+	}
+
+	from, err := m.helper.GetCollection(ctx, task.Collection)
+	if err != nil {
+		return err
+	}
+	if from == nil {
+		to, err := m.helper.GetCollection(ctx, task.To)
+		if err != nil || to != nil {
+			return err
+		}
+		// Neither exist, so the rename reports the missing collection
+	}
+	return m.helper.RenameCollection(ctx, task.Collection, task.To, task.DropTarget)
+}
+
+// dropCollection drops the task's collection
+func (m *migrator) dropCollection(ctx context.Context, task *schema.DropCollectionTask) error {
+	if task.Collection == "" {
+		return ErrNoCollection
+	}
+	return m.helper.DropCollection(ctx, task.Collection)
+}
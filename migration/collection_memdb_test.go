@@ -0,0 +1,163 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/memdb"
+	"github.com/archy-bold/mongo-go-helper/migration/schema"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var recentPipeline = mongo.Pipeline{{{Key: "$sort", Value: bson.D{{Key: "_id", Value: -1}}}}, {{Key: "$limit", Value: 10}}}
+
+func collectionTasks() map[string]schema.TaskContract {
+	return map[string]schema.TaskContract{
+		"01_events": &schema.CreateCollectionTask{
+			Task:    schema.Task{Collection: "events"},
+			Options: base.CollectionOptions{Capped: true, Size: 1 << 20, Max: 1000},
+		},
+		"02_readings": &schema.CreateCollectionTask{
+			Task: schema.Task{Collection: "readings"},
+			Options: base.CollectionOptions{
+				TimeSeries:         &base.TimeSeriesOptions{TimeField: "at", MetaField: "sensor", Granularity: "minutes"},
+				ExpireAfterSeconds: 86400,
+			},
+		},
+		"03_recent":  &schema.CreateViewTask{Task: schema.Task{Collection: "recent"}, ViewOn: "events", Pipeline: recentPipeline},
+		"04_archive": &schema.RenameCollectionTask{Task: schema.Task{Collection: "legacy"}, To: "archive"},
+	}
+}
+
+func Test_CollectionTasks_MemDB(t *testing.T) {
+	ctx := context.Background()
+	db := memdb.NewDatabase(testDB)
+	helper := base.NewHelper(db)
+	db.Collection("legacy").InsertOne(ctx, bson.M{"a": 1})
+	db.Collection("old").InsertOne(ctx, bson.M{"a": 1})
+	m := NewMigrator(helper)
+
+	tasks := collectionTasks()
+	tasks["05_old"] = &schema.DropCollectionTask{Task: schema.Task{Collection: "old"}}
+	err := m.Run(ctx, tasks)
+	assert.Nil(t, err, "Expected nil err for the first run")
+	err = m.Run(ctx, tasks)
+	assert.Nil(t, err, "Expected the tasks to be idempotent")
+
+	names, _ := db.ListCollectionNames(ctx, bson.M{})
+	assert.Equal(t, []string{"archive", "events", "readings", "recent"}, names)
+	info, _ := helper.GetCollection(ctx, "events")
+	if assert.NotNil(t, info) {
+		assert.Equal(t, base.CollectionOptions{Capped: true, Size: 1 << 20, Max: 1000}, info.Options)
+	}
+	info, _ = helper.GetCollection(ctx, "readings")
+	if assert.NotNil(t, info) {
+		assert.Equal(t, base.CollectionTypeTimeSeries, info.Type)
+		assert.Equal(t, "at", info.Options.TimeSeries.TimeField)
+	}
+	info, _ = helper.GetCollection(ctx, "recent")
+	if assert.NotNil(t, info) {
+		assert.Equal(t, base.CollectionTypeView, info.Type)
+		assert.Equal(t, "events", info.Options.ViewOn)
+		assert.True(t, samePipeline(recentPipeline, info.Options.Pipeline), "Expected the view's pipeline to be set")
+	}
+	count, _ := db.Collection("archive").CountDocuments(ctx, bson.M{"a": 1})
+	assert.Equal(t, int64(1), count, "Expected the documents to be renamed")
+
+	// Changed views are replaced
+	changed := mongo.Pipeline{{{Key: "$limit", Value: 5}}}
+	err = m.Run(ctx, map[string]schema.TaskContract{
+		"03_recent": &schema.CreateViewTask{Task: schema.Task{Collection: "recent"}, ViewOn: "events", Pipeline: changed},
+	})
+	assert.Nil(t, err, "Expected nil err changing the view")
+	info, _ = helper.GetCollection(ctx, "recent")
+	if assert.NotNil(t, info) {
+		assert.True(t, samePipeline(changed, info.Options.Pipeline), "Expected the view to be replaced")
+	}
+
+	// Rolling back undoes every task
+	err = m.Rollback(ctx, collectionTasks())
+	assert.Nil(t, err, "Expected nil err rolling back")
+	names, _ = db.ListCollectionNames(ctx, bson.M{})
+	assert.Equal(t, []string{"legacy"}, names)
+}
+
+// collectionTaskErrorsTests have a nil err when any server error is expected
+var collectionTaskErrorsTests = map[string]struct {
+	task schema.TaskContract
+	err  error
+}{
+	"create without collection": {&schema.CreateCollectionTask{}, ErrNoCollection},
+	"view without collection":   {&schema.CreateViewTask{ViewOn: "events"}, ErrNoCollection},
+	"view without view on":      {&schema.CreateViewTask{Task: schema.Task{Collection: "recent"}}, ErrNoViewOn},
+	"view of a collection":      {&schema.CreateViewTask{Task: schema.Task{Collection: "events"}, ViewOn: "other"}, ErrNotView},
+	"rename without collection": {&schema.RenameCollectionTask{To: "archive"}, ErrNoCollection},
+	"rename without target":     {&schema.RenameCollectionTask{Task: schema.Task{Collection: "events"}}, ErrNoRenameTarget},
+	"rename missing":            {&schema.RenameCollectionTask{Task: schema.Task{Collection: "missing"}, To: "archive"}, nil},
+	"rename to existing":        {&schema.RenameCollectionTask{Task: schema.Task{Collection: "events"}, To: "other"}, nil},
+	"drop without collection":   {&schema.DropCollectionTask{}, ErrNoCollection},
+}
+
+func Test_CollectionTasks_Errors_MemDB(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range collectionTaskErrorsTests {
+		db := memdb.NewDatabase(testDB)
+		db.Collection("events").InsertOne(ctx, bson.M{"a": 1})
+		db.Collection("other").InsertOne(ctx, bson.M{"a": 1})
+		m := &migrator{helper: base.NewHelper(db)}
+
+		err := m.runTask(ctx, tn, tt.task)
+
+		if tt.err == nil {
+			assert.NotNilf(t, err, "Expected an error on test '%s'", tn)
+		} else {
+			assert.Truef(t, errors.Is(err, tt.err), "Expected err to match on test '%s', got %v", tn, err)
+		}
+	}
+}
+
+func Test_Rollback_Irreversible(t *testing.T) {
+	ctx := context.Background()
+	db := memdb.NewDatabase(testDB)
+	db.Collection("events").InsertOne(ctx, bson.M{"a": 1})
+	m := NewMigrator(base.NewHelper(db))
+
+	err := m.Rollback(ctx, map[string]schema.TaskContract{
+		"01_events": &schema.CreateCollectionTask{Task: schema.Task{Collection: "events"}},
+		"02_index":  &schema.CreateIndexTask{Task: schema.Task{Collection: "events"}, IndexName: "a_1", Keys: bson.M{"a": 1}},
+		"03_rename": &schema.RenameCollectionTask{Task: schema.Task{Collection: "a"}, To: "b", DropTarget: true},
+	})
+
+	assert.Equal(t, buildMultiError([]error{
+		errors.New("could not roll back migration task '03_rename': the task can't be reversed"),
+		errors.New("could not roll back migration task '02_index': the task can't be reversed"),
+	}).Error(), err.Error())
+	var merr *multierror.Error
+	if assert.True(t, errors.As(err, &merr)) {
+		assert.True(t, errors.Is(merr.Errors[0], ErrIrreversibleTask), "Expected an irreversible task error")
+	}
+	names, _ := db.ListCollectionNames(ctx, bson.M{})
+	assert.Equal(t, []string{"events"}, names, "Expected nothing to be rolled back")
+}
+
+func Test_Rollback_StopsOnError(t *testing.T) {
+	ctx := context.Background()
+	db := memdb.NewDatabase(testDB)
+	db.Collection("b").InsertOne(ctx, bson.M{"a": 1})
+	m := NewMigrator(base.NewHelper(db))
+
+	err := m.Rollback(ctx, map[string]schema.TaskContract{
+		"01_events": &schema.CreateCollectionTask{Task: schema.Task{Collection: "b"}},
+		"02_rename": &schema.RenameCollectionTask{Task: schema.Task{Collection: "a"}, To: "c"},
+	})
+
+	assert.NotNil(t, err, "Expected the failed rename back to be returned")
+	names, _ := db.ListCollectionNames(ctx, bson.M{})
+	assert.Equal(t, []string{"b"}, names, "Expected earlier tasks not to be rolled back after a failure")
+}
@@ -10,3 +10,27 @@ var ErrNoFindFilterFn = errors.New("you must specify a FindFilterFn")
 
 // ErrNoModel error indicating no model was supplied
 var ErrNoModel = errors.New("you must specify a Model")
+
+// ErrUnsupportedBatchFilter error indicating a find filter can't be used for batched seeding
+var ErrUnsupportedBatchFilter = errors.New("batched seeding only supports equality find filters")
+
+// ErrUnresolvedRef error indicating a seed item references an item that hasn't been seeded
+var ErrUnresolvedRef = errors.New("could not resolve the seed reference")
+
+// ErrNoTransform error indicating no transform function was supplied
+var ErrNoTransform = errors.New("you must specify a Transform")
+
+// ErrNoViewOn error indicating no collection was supplied for a view
+var ErrNoViewOn = errors.New("you must specify the collection to view")
+
+// ErrNoRenameTarget error indicating no new name was supplied for a rename
+var ErrNoRenameTarget = errors.New("you must specify the collection to rename to")
+
+// ErrNotView error indicating a view's name is taken by a collection
+var ErrNotView = errors.New("a collection that isn't a view has the view's name")
+
+// ErrIrreversibleTask error indicating a task can't be rolled back
+var ErrIrreversibleTask = errors.New("the task can't be reversed")
+
+// ErrNoTenants error indicating no tenant databases or pattern were supplied
+var ErrNoTenants = errors.New("you must specify the Databases or a Pattern")
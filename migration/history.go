@@ -0,0 +1,63 @@
+package migration
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DefaultHistoryCollection is the collection holding the migration history
+// unless MigratorOptions.HistoryCollection is set
+const DefaultHistoryCollection = "migrations"
+
+// taskHistory is a task's entry in the migration history
+type taskHistory struct {
+	Name string `bson:"_id"`
+	// Checkpoint is the _id of the last document a backfill wrote
+	Checkpoint interface{} `bson:"checkpoint,omitempty"`
+	Processed  int         `bson:"processed"`
+	Updated    int         `bson:"updated"`
+	Done       bool        `bson:"done"`
+	UpdatedAt  time.Time   `bson:"updatedAt"`
+}
+
+func (m *migrator) historyCollection() string {
+	if m.opts.HistoryCollection != "" {
+		return m.opts.HistoryCollection
+	}
+	return DefaultHistoryCollection
+}
+
+// getHistory gets the named task's entry in the history, or nil if it
+// doesn't have one
+func (m *migrator) getHistory(ctx context.Context, name string) (*taskHistory, error) {
+	docs, err := m.helper.Aggregate(ctx, m.historyCollection(), mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "_id", Value: name}}}},
+	})
+	if err != nil || len(docs) == 0 {
+		return nil, err
+	}
+
+	b, err := bson.Marshal(docs[0])
+	if err != nil {
+		return nil, err
+	}
+	var hist taskHistory
+	if err := bson.Unmarshal(b, &hist); err != nil {
+		return nil, err
+	}
+	return &hist, nil
+}
+
+// saveHistory saves the task's entry in the history
+func (m *migrator) saveHistory(ctx context.Context, hist *taskHistory) error {
+	hist.UpdatedAt = time.Now().UTC().Truncate(time.Millisecond)
+	model := mongo.NewReplaceOneModel().
+		SetFilter(bson.D{{Key: "_id", Value: hist.Name}}).
+		SetReplacement(hist).
+		SetUpsert(true)
+	_, err := m.helper.BulkWrite(ctx, m.historyCollection(), []mongo.WriteModel{model})
+	return err
+}
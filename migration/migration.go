@@ -4,43 +4,142 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/archy-bold/mongo-go-helper/base"
 	"github.com/archy-bold/mongo-go-helper/migration/schema"
 	multierror "github.com/hashicorp/go-multierror"
 )
 
+// MigratorOptions represents options for the migrator
+type MigratorOptions struct {
+	// Hooks are called around each task, BeforeTask in order and AfterTask
+	// in reverse order. Hooks that implement schema.RunHook are also called
+	// around the whole run.
+	Hooks []schema.TaskHook
+	// HistoryCollection holds the progress of backfill tasks. Defaults to
+	// DefaultHistoryCollection.
+	HistoryCollection string
+}
+
 type migrator struct {
 	helper base.MongoHelper
 	seeder schema.Seeder
+	opts   MigratorOptions
 }
 
 func (m *migrator) Run(ctx context.Context, tasks map[string]schema.TaskContract) error {
+	// Run in name order so seeds can reference items from earlier tasks
+	names := make([]string, 0, len(tasks))
+	for tn := range tasks {
+		names = append(names, tn)
+	}
+	sort.Strings(names)
+
+	return m.run(ctx, names, tasks, false)
+}
+
+func (m *migrator) Rollback(ctx context.Context, tasks map[string]schema.TaskContract) error {
+	// Reverse in reverse name order, undoing later tasks first
+	names := make([]string, 0, len(tasks))
+	for tn := range tasks {
+		names = append(names, tn)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+
+	var errs *multierror.Error
+	reversed := make(map[string]schema.TaskContract, len(tasks))
+	for _, tn := range names {
+		var reverse schema.TaskContract
+		if rt, ok := tasks[tn].(schema.ReversibleTask); ok {
+			reverse = rt.Reverse()
+		}
+		if reverse == nil {
+			errs = multierror.Append(errs, fmt.Errorf("could not roll back migration task '%s': %w", tn, ErrIrreversibleTask))
+		}
+		reversed[tn] = reverse
+	}
+	if err := errs.ErrorOrNil(); err != nil {
+		return err
+	}
+
+	return m.run(ctx, names, reversed, true)
+}
+
+// run runs the named tasks in order, calling the hooks around each task and
+// the run. It stops at the first failed task if stopOnError is set.
+func (m *migrator) run(ctx context.Context, names []string, tasks map[string]schema.TaskContract, stopOnError bool) error {
 	var errs *multierror.Error
 
-	for tn, t := range tasks {
-		var err error
-		switch task := interface{}(t).(type) {
-		case *schema.CreateIndexTask:
-			err = m.helper.AddIndexIfNotExists(ctx, task.Collection, task.IndexName, task.Keys)
-		case *schema.SeedTableTask:
-			err = m.seeder.SeedData(ctx, task)
-		default:
-			errStr := fmt.Sprintf("could not run migration task '%s': unknown type '%s'", tn, t.GetType())
-			err = errors.New(errStr)
+	for _, hook := range m.opts.Hooks {
+		if rh, ok := hook.(schema.RunHook); ok {
+			ctx = rh.BeforeRun(ctx, names)
+		}
+	}
+
+	for _, tn := range names {
+		t := tasks[tn]
+		taskCtx := ctx
+		for _, hook := range m.opts.Hooks {
+			taskCtx = hook.BeforeTask(taskCtx, tn, t)
 		}
 
+		err := m.runTask(taskCtx, tn, t)
+
+		for i := len(m.opts.Hooks) - 1; i >= 0; i-- {
+			m.opts.Hooks[i].AfterTask(taskCtx, tn, t, err)
+		}
 		if err != nil {
-			errs = multierror.Append(err)
+			errs = multierror.Append(errs, err)
+			if stopOnError {
+				break
+			}
 		}
 	}
 
-	return errs.ErrorOrNil()
+	err := errs.ErrorOrNil()
+	for i := len(m.opts.Hooks) - 1; i >= 0; i-- {
+		if rh, ok := m.opts.Hooks[i].(schema.RunHook); ok {
+			rh.AfterRun(ctx, err)
+		}
+	}
+
+	return err
+}
+
+// runTask runs the named task
+func (m *migrator) runTask(ctx context.Context, tn string, t schema.TaskContract) error {
+	switch task := interface{}(t).(type) {
+	case *schema.CreateIndexTask:
+		return m.helper.AddIndexIfNotExists(ctx, task.Collection, task.IndexName, task.Keys)
+	case *schema.SeedTableTask:
+		return m.seeder.SeedData(ctx, task)
+	case *schema.JSONSchemaTask:
+		return m.applySchema(ctx, task)
+	case *schema.CreateCollectionTask:
+		return m.createCollection(ctx, task)
+	case *schema.CreateViewTask:
+		return m.createView(ctx, task)
+	case *schema.RenameCollectionTask:
+		return m.renameCollection(ctx, task)
+	case *schema.DropCollectionTask:
+		return m.dropCollection(ctx, task)
+	case *schema.BackfillTask:
+		return m.backfill(ctx, tn, task)
+	}
+
+	errStr := fmt.Sprintf("could not run migration task '%s': unknown type '%s'", tn, t.GetType())
+	return errors.New(errStr)
 }
 
 // NewMigrator returns a new migrator instance for the given tasks
 func NewMigrator(helper base.MongoHelper) (m schema.Migrator) {
-	seeder := &seeder{helper}
-	m = &migrator{helper, seeder}
+	return NewMigratorWithOptions(helper, MigratorOptions{})
+}
+
+// NewMigratorWithOptions returns a new migrator instance using the given options
+func NewMigratorWithOptions(helper base.MongoHelper, opts MigratorOptions) (m schema.Migrator) {
+	seeder := newSeeder(helper)
+	m = &migrator{helper, seeder, opts}
 	return
 }
@@ -3,6 +3,7 @@ package migration
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/archy-bold/mongo-go-helper/base"
@@ -16,6 +17,8 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+type ctxKey string
+
 var (
 	sampleDocument = &bson.M{"num": 999}
 	errExample     = errors.New("error")
@@ -93,6 +96,18 @@ var runMigrationsTests = map[string]struct {
 		[]seedCall{seedCall{"seed", errExample}},
 		buildMultiError([]error{errExample}),
 	},
+	"multiple errors": {
+		map[string]schema.TaskContract{
+			"01_index": &schema.CreateIndexTask{Task: schema.Task{Collection: "test-coll"}, IndexName: "index_1", Keys: sampleDocument},
+			"02_blank": &schema.Task{},
+		},
+		[]indexCall{indexCall{"test-coll", "index_1", sampleDocument, errExample}},
+		nil,
+		buildMultiError([]error{
+			errExample,
+			errors.New("could not run migration task '02_blank': unknown type '*schema.Task'"),
+		}),
+	},
 }
 
 func Test_RunMigrations(t *testing.T) {
@@ -102,7 +117,7 @@ func Test_RunMigrations(t *testing.T) {
 		// Set up the mock
 		helper := &bMocks.MongoHelper{}
 		seeder := &mMocks.Seeder{}
-		m := &migrator{helper, seeder}
+		m := &migrator{helper: helper, seeder: seeder}
 		numSeedCalls := len(tt.seedCalls)
 		if len(tt.indexCalls) > 0 {
 			helper.On("AddIndexIfNotExists", ctx, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("*primitive.M")).
@@ -135,6 +150,104 @@ func Test_RunMigrations(t *testing.T) {
 	}
 }
 
+func Test_RunMigrations_Order(t *testing.T) {
+	ctx := context.Background()
+	helper := &bMocks.MongoHelper{}
+	seeder := &mMocks.Seeder{}
+	m := &migrator{helper: helper, seeder: seeder}
+
+	tasks := map[string]schema.TaskContract{
+		"02_products":   &schema.SeedTableTask{Task: schema.Task{Collection: "products"}},
+		"01_categories": &schema.SeedTableTask{Task: schema.Task{Collection: "categories"}},
+		"03_reviews":    &schema.SeedTableTask{Task: schema.Task{Collection: "reviews"}},
+	}
+	var order []string
+	seeder.On("SeedData", ctx, mock.AnythingOfType("*schema.SeedTableTask")).
+		Run(func(args mock.Arguments) {
+			order = append(order, args.Get(1).(*schema.SeedTableTask).Collection)
+		}).
+		Return(nil)
+
+	err := m.Run(ctx, tasks)
+
+	assert.Nil(t, err, "Expected nil err on RunMigration order test")
+	assert.Equal(t, []string{"categories", "products", "reviews"}, order, "Expected tasks to run in name order")
+}
+
+func Test_RunMigrations_Hooks(t *testing.T) {
+	ctx := context.Background()
+	helper := &bMocks.MongoHelper{}
+	seeder := &mMocks.Seeder{}
+	hook := &mMocks.TaskHook{}
+	m := NewMigratorWithOptions(helper, MigratorOptions{Hooks: []schema.TaskHook{hook}}).(*migrator)
+	m.seeder = seeder
+
+	index := &schema.CreateIndexTask{Task: schema.Task{Collection: "test-coll"}, IndexName: "index_1", Keys: sampleDocument}
+	seed := &schema.SeedTableTask{Task: schema.Task{Collection: "test-coll"}}
+	tasks := map[string]schema.TaskContract{"01_index": index, "02_seed": seed}
+	taskCtx := context.WithValue(ctx, ctxKey("task"), true)
+	hook.On("BeforeTask", ctx, mock.AnythingOfType("string"), mock.Anything).Return(taskCtx)
+	hook.On("AfterTask", taskCtx, mock.AnythingOfType("string"), mock.Anything, mock.Anything)
+	helper.On("AddIndexIfNotExists", taskCtx, "test-coll", "index_1", sampleDocument).Return(nil)
+	seeder.On("SeedData", taskCtx, seed).Return(errExample)
+
+	err := m.Run(ctx, tasks)
+
+	assert.Equal(t, buildMultiError([]error{errExample}), err, "Expected the seed error")
+	hook.AssertCalled(t, "BeforeTask", ctx, "01_index", index)
+	hook.AssertCalled(t, "AfterTask", taskCtx, "01_index", index, nil)
+	hook.AssertCalled(t, "BeforeTask", ctx, "02_seed", seed)
+	hook.AssertCalled(t, "AfterTask", taskCtx, "02_seed", seed, errExample)
+	helper.AssertExpectations(t)
+}
+
+// runHook records the calls to a hook that's also called around runs
+type runHook struct {
+	calls []string
+}
+
+func (h *runHook) BeforeRun(ctx context.Context, names []string) context.Context {
+	h.calls = append(h.calls, fmt.Sprintf("BeforeRun %v", names))
+	return context.WithValue(ctx, ctxKey("run"), true)
+}
+
+func (h *runHook) AfterRun(ctx context.Context, err error) {
+	h.calls = append(h.calls, fmt.Sprintf("AfterRun %v %v", ctx.Value(ctxKey("run")), err != nil))
+}
+
+func (h *runHook) BeforeTask(ctx context.Context, name string, task schema.TaskContract) context.Context {
+	h.calls = append(h.calls, fmt.Sprintf("BeforeTask %s %v", name, ctx.Value(ctxKey("run"))))
+	return ctx
+}
+
+func (h *runHook) AfterTask(ctx context.Context, name string, task schema.TaskContract, err error) {
+	h.calls = append(h.calls, fmt.Sprintf("AfterTask %s %v", name, err != nil))
+}
+
+func Test_RunMigrations_RunHooks(t *testing.T) {
+	ctx := context.Background()
+	helper := &bMocks.MongoHelper{}
+	hook := &runHook{}
+	m := NewMigratorWithOptions(helper, MigratorOptions{Hooks: []schema.TaskHook{hook}})
+
+	helper.On("AddIndexIfNotExists", mock.Anything, "test-coll", "index_1", sampleDocument).Return(nil)
+
+	err := m.Run(ctx, map[string]schema.TaskContract{
+		"02_blank": &schema.Task{},
+		"01_index": &schema.CreateIndexTask{Task: schema.Task{Collection: "test-coll"}, IndexName: "index_1", Keys: sampleDocument},
+	})
+
+	assert.NotNil(t, err, "Expected the unknown task to fail")
+	assert.Equal(t, []string{
+		"BeforeRun [01_index 02_blank]",
+		"BeforeTask 01_index true",
+		"AfterTask 01_index false",
+		"BeforeTask 02_blank true",
+		"AfterTask 02_blank true",
+		"AfterRun true true",
+	}, hook.calls, "Expected the run hooks to be called around the tasks")
+}
+
 var newMigratorTests = map[string]struct {
 	helper base.MongoHelper
 }{
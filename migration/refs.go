@@ -0,0 +1,119 @@
+package migration
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/migration/schema"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// refs holds the IDs of seeded items that can be referenced by later seeds
+type refs struct {
+	mu  sync.RWMutex
+	ids map[string]interface{}
+}
+
+func newRefs() *refs {
+	return &refs{ids: make(map[string]interface{})}
+}
+
+// register stores the item's ID if the task defines a reference key for it
+func (r *refs) register(task *schema.SeedTableTask, item base.ModelInterface) {
+	if task.RefKeyFn == nil {
+		return
+	}
+	key := task.RefKeyFn(item)
+	if key == "" {
+		return
+	}
+
+	r.mu.Lock()
+	r.ids[schema.Ref(task.Collection, key)] = item.GetID()
+	r.mu.Unlock()
+}
+
+// resolve replaces every reference string in the item with the referenced ID
+func (r *refs) resolve(item interface{}) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.resolveValue(reflect.ValueOf(item))
+}
+
+func (r *refs) resolveValue(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Interface && v.CanSet() {
+			if s, ok := v.Interface().(string); ok {
+				return r.set(v, s)
+			}
+		}
+		return r.resolveValue(v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Field(i); f.CanSet() {
+				if err := r.resolveValue(f); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := r.resolveValue(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			// Map values aren't addressable so resolve a copy and put it back
+			el := reflect.New(v.Type().Elem()).Elem()
+			el.Set(v.MapIndex(k))
+			if err := r.resolveValue(el); err != nil {
+				return err
+			}
+			v.SetMapIndex(k, el)
+		}
+	case reflect.String:
+		if v.CanSet() {
+			return r.set(v, v.String())
+		}
+	}
+	return nil
+}
+
+// set replaces the value with the ID if it holds a reference
+func (r *refs) set(v reflect.Value, ref string) error {
+	if !strings.HasPrefix(ref, schema.RefPrefix) {
+		return nil
+	}
+	id, ok := r.ids[ref]
+	if !ok {
+		return errors.Wrap(ErrUnresolvedRef, ref)
+	}
+
+	if v.Kind() == reflect.String {
+		switch i := id.(type) {
+		case string:
+			v.SetString(i)
+		case primitive.ObjectID:
+			v.SetString(i.Hex())
+		default:
+			v.SetString(fmt.Sprint(i))
+		}
+		return nil
+	}
+
+	iv := reflect.ValueOf(id)
+	if !iv.Type().AssignableTo(v.Type()) {
+		return errors.Wrap(ErrUnresolvedRef, ref)
+	}
+	v.Set(iv)
+	return nil
+}
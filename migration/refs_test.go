@@ -0,0 +1,266 @@
+package migration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/memdb"
+	"github.com/archy-bold/mongo-go-helper/migration/schema"
+	bMocks "github.com/archy-bold/mongo-go-helper/mocks/base"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type exampleProduct struct {
+	ID          primitive.ObjectID `bson:"_id"`
+	Name        string             `bson:"name"`
+	CategoryID  interface{}        `bson:"category_id"`
+	CategoryHex string             `bson:"category_hex"`
+	Extra       bson.M             `bson:"extra"`
+	Related     []interface{}      `bson:"related"`
+	Key         string             `bson:"-"`
+}
+
+func (m exampleProduct) Exists() bool {
+	return !m.ID.IsZero()
+}
+
+func (m exampleProduct) GetID() interface{} {
+	return m.ID
+}
+
+func (m *exampleProduct) SetID(id interface{}) {
+	m.ID = id.(primitive.ObjectID)
+}
+
+var resolveRefsTests = map[string]struct {
+	item     *exampleProduct
+	expected *exampleProduct
+	err      error
+}{
+	"no refs": {
+		&exampleProduct{Name: "tv"},
+		&exampleProduct{Name: "tv"},
+		nil,
+	},
+	"all fields": {
+		&exampleProduct{
+			Name:        "tv",
+			CategoryID:  "$ref:categories:electronics",
+			CategoryHex: "$ref:categories:electronics",
+			Extra:       bson.M{"brand": "$ref:brands:acme", "size": 55},
+			Related:     []interface{}{"$ref:brands:acme", "plain"},
+		},
+		&exampleProduct{
+			Name:        "tv",
+			CategoryID:  sampleID,
+			CategoryHex: sampleID.Hex(),
+			Extra:       bson.M{"brand": "acme-id", "size": 55},
+			Related:     []interface{}{"acme-id", "plain"},
+		},
+		nil,
+	},
+	"unresolved": {
+		&exampleProduct{Name: "tv", CategoryID: "$ref:categories:garden"},
+		nil,
+		ErrUnresolvedRef,
+	},
+}
+
+func Test_ResolveRefs(t *testing.T) {
+	r := newRefs()
+	r.register(
+		&schema.SeedTableTask{Task: schema.Task{Collection: "categories"}, RefKeyFn: func(item base.ModelInterface) string { return "electronics" }},
+		&exampleModel{ID: sampleID},
+	)
+	r.register(
+		&schema.SeedTableTask{Task: schema.Task{Collection: "brands"}, RefKeyFn: func(item base.ModelInterface) string { return "acme" }},
+		&exampleModelStrID{ID: "acme-id"},
+	)
+
+	for tn, tt := range resolveRefsTests {
+		err := r.resolve(tt.item)
+
+		if tt.err == nil {
+			assert.Nilf(t, err, "Expected nil err for resolve on test '%s'", tn)
+			assert.Equalf(t, tt.expected, tt.item, "Expected item to match for resolve on test '%s'", tn)
+		} else {
+			assert.NotNilf(t, err, "Expected not nil err for resolve on test '%s'", tn)
+			assert.Equalf(t, tt.err, errors.Cause(err), "Expected err to match for resolve on test '%s'", tn)
+		}
+	}
+}
+
+func Test_SeedData_Refs(t *testing.T) {
+	ctx := context.Background()
+
+	// Set up the mock with nothing seeded yet
+	helper := &bMocks.MongoHelper{}
+	helper.On("FindOne", ctx, mock.AnythingOfType("string"), mock.Anything, mock.Anything)
+	var inserted []interface{}
+	helper.On("InsertOne", ctx, mock.AnythingOfType("string"), mock.Anything).
+		Run(func(args mock.Arguments) {
+			inserted = append(inserted, args.Get(2))
+		}).
+		Return(sampleID, nil)
+	s := newSeeder(helper)
+
+	categories := &schema.SeedTableTask{
+		Task:         schema.Task{Collection: "categories"},
+		Items:        []base.ModelInterface{&exampleModel{Str: "electronics", Num: 1}},
+		FindFilterFn: findFilterFn,
+		Model:        &exampleModel{},
+		RefKeyFn: func(item base.ModelInterface) string {
+			return item.(*exampleModel).Str
+		},
+	}
+	products := &schema.SeedTableTask{
+		Task:  schema.Task{Collection: "products"},
+		Items: []base.ModelInterface{&exampleProduct{Name: "tv", CategoryID: schema.Ref("categories", "electronics")}},
+		FindFilterFn: func(item interface{}) (interface{}, error) {
+			return &bson.M{"name": item.(*exampleProduct).Name}, nil
+		},
+		Model: &exampleProduct{},
+	}
+
+	err := s.SeedData(ctx, categories)
+	assert.Nil(t, err, "Expected nil err seeding categories")
+	err = s.SeedData(ctx, products)
+	assert.Nil(t, err, "Expected nil err seeding products")
+
+	// The product should reference the category's generated ID
+	assert.Len(t, inserted, 2)
+	category := inserted[0].(*exampleModel)
+	product := inserted[1].(*exampleProduct)
+	assert.False(t, category.ID.IsZero(), "Expected category to have an ID")
+	assert.Equal(t, category.ID, product.CategoryID, "Expected product to reference the category ID")
+}
+
+func Test_SeedData_SameTaskRefs_MemDB(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range seedDataStatsTests {
+		task := &schema.SeedTableTask{
+			Task: schema.Task{Collection: "products"},
+			Items: []base.ModelInterface{
+				&exampleProduct{Name: "tv"},
+				&exampleProduct{Name: "remote", CategoryID: schema.Ref("products", "tv")},
+			},
+			FindFilterFn: func(item interface{}) (interface{}, error) {
+				return &bson.M{"name": item.(*exampleProduct).Name}, nil
+			},
+			Model: &exampleProduct{},
+			RefKeyFn: func(item base.ModelInterface) string {
+				return item.(*exampleProduct).Name
+			},
+			BatchSize: tt.batchSize,
+		}
+
+		// Seed the same task into two databases
+		for _, name := range []string{"first", "second"} {
+			helper := base.NewHelper(memdb.NewDatabase(name))
+
+			err := newSeeder(helper).SeedData(ctx, task)
+
+			assert.Nilf(t, err, "Expected nil err seeding '%s' on test '%s'", name, tn)
+			var tv, remote exampleProduct
+			helper.FindOne(ctx, "products", bson.M{"name": "tv"}, &tv)
+			helper.FindOne(ctx, "products", bson.M{"name": "remote"}, &remote)
+			assert.Equalf(t, tv.ID, remote.CategoryID, "Expected the reference to the earlier item in '%s' on test '%s'", name, tn)
+		}
+		assert.Equalf(t, schema.Ref("products", "tv"), task.Items[1].(*exampleProduct).CategoryID, "Expected the task's items to keep their references on test '%s'", tn)
+	}
+}
+
+func Test_SeedData_UnstoredRefKey_MemDB(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range seedDataStatsTests {
+		helper := base.NewHelper(memdb.NewDatabase(testDB))
+		task := &schema.SeedTableTask{
+			Task: schema.Task{Collection: "products"},
+			Items: []base.ModelInterface{
+				&exampleProduct{Name: "tv", Key: "television"},
+				&exampleProduct{Name: "remote", CategoryID: schema.Ref("products", "television")},
+			},
+			FindFilterFn: func(item interface{}) (interface{}, error) {
+				return &bson.M{"name": item.(*exampleProduct).Name}, nil
+			},
+			Model: &exampleProduct{},
+			RefKeyFn: func(item base.ModelInterface) string {
+				return item.(*exampleProduct).Key
+			},
+			BatchSize: tt.batchSize,
+		}
+
+		err := newSeeder(helper).SeedData(ctx, task)
+
+		assert.Nilf(t, err, "Expected nil err on test '%s'", tn)
+		var tv, remote exampleProduct
+		helper.FindOne(ctx, "products", bson.M{"name": "tv"}, &tv)
+		helper.FindOne(ctx, "products", bson.M{"name": "remote"}, &remote)
+		assert.Equalf(t, tv.ID, remote.CategoryID, "Expected the key from an unstored field to be registered on test '%s'", tn)
+	}
+}
+
+// validatedProduct holds a reference in a field with a validate rule
+type validatedProduct struct {
+	ID          primitive.ObjectID `bson:"_id"`
+	Name        string             `bson:"name"`
+	CategoryHex string             `bson:"category_hex" validate:"regex=^[0-9a-f]{24}$"`
+}
+
+func (m validatedProduct) Exists() bool {
+	return !m.ID.IsZero()
+}
+
+func (m validatedProduct) GetID() interface{} {
+	return m.ID
+}
+
+func (m *validatedProduct) SetID(id interface{}) {
+	m.ID = id.(primitive.ObjectID)
+}
+
+func Test_SeedData_ValidatedRefs_MemDB(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range seedDataStatsTests {
+		db := memdb.NewDatabase(testDB)
+		helper := base.NewHelper(db)
+		s := newSeeder(helper)
+		categories := &schema.SeedTableTask{
+			Task:  schema.Task{Collection: "categories"},
+			Items: []base.ModelInterface{&exampleProduct{Name: "electronics"}},
+			FindFilterFn: func(item interface{}) (interface{}, error) {
+				return &bson.M{"name": item.(*exampleProduct).Name}, nil
+			},
+			Model: &exampleProduct{},
+			RefKeyFn: func(item base.ModelInterface) string {
+				return item.(*exampleProduct).Name
+			},
+		}
+		products := &schema.SeedTableTask{
+			Task:  schema.Task{Collection: "products"},
+			Items: []base.ModelInterface{&validatedProduct{Name: "tv", CategoryHex: schema.Ref("categories", "electronics")}},
+			FindFilterFn: func(item interface{}) (interface{}, error) {
+				return &bson.M{"name": item.(*validatedProduct).Name}, nil
+			},
+			Model:     &validatedProduct{},
+			BatchSize: tt.batchSize,
+		}
+
+		err := s.SeedData(ctx, categories)
+		assert.Nilf(t, err, "Expected nil err seeding categories on test '%s'", tn)
+		err = s.SeedData(ctx, products)
+
+		assert.Nilf(t, err, "Expected the resolved reference to be validated on test '%s'", tn)
+		var category exampleProduct
+		helper.FindOne(ctx, "categories", bson.M{"name": "electronics"}, &category)
+		assertCount(t, db, "products", bson.M{"category_hex": category.ID.Hex()}, 1)
+	}
+}
@@ -0,0 +1,42 @@
+package schema
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TransformFunction gets the update to apply to a document, such as
+// bson.M{"$set": bson.M{"fullName": doc["first"].(string) + " " + doc["last"].(string)}},
+// or nil to leave it unchanged
+type TransformFunction func(doc bson.M) (interface{}, error)
+
+// BackfillTask is a migration task for rewriting existing documents. The
+// collection is read in batches in _id order, each document is passed to the
+// Transform function, and the updates it returns are written in bulk.
+//
+// The last _id of each batch is checkpointed in the migration history, so an
+// interrupted run resumes from the batch it was writing. Transforms must be
+// safe to apply twice, as that batch is transformed again. Once finished the
+// task is skipped, unless its entry in the history is removed.
+
+type BackfillTask struct {
+	Task
+	// Filter limits the documents that are transformed
+	Filter interface{}
+	// Transform gets the update for each document
+	Transform TransformFunction
+	// BatchSize is the number of documents read and written at a time.
+	// Defaults to 1000.
+	BatchSize int
+	// Throttle is the time to wait between batches, to limit the load on the server
+	Throttle time.Duration
+	// Stats are the totals of the task's runs, including interrupted ones
+	Stats BackfillStats
+}
+
+// BackfillStats represents the progress of a backfill
+type BackfillStats struct {
+	Processed int `bson:"processed"`
+	Updated   int `bson:"updated"`
+}
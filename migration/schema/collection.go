@@ -0,0 +1,68 @@
+package schema
+
+import (
+	"github.com/archy-bold/mongo-go-helper/base"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ReversibleTask is a migration task that can be rolled back by running the
+// task it reverses to. Reverse gets nil if the task can't be reversed as
+// it's configured.
+type ReversibleTask interface {
+	TaskContract
+	Reverse() TaskContract
+}
+
+// CreateCollectionTask is a migration task for creating a collection with
+// options, such as a capped or time-series collection. Existing collections
+// are left as they are, as most options can't be changed. It's reversed by
+// dropping the collection.
+type CreateCollectionTask struct {
+	Task
+	Options base.CollectionOptions
+}
+
+// Reverse gets the task dropping the collection
+func (t *CreateCollectionTask) Reverse() TaskContract {
+	return &DropCollectionTask{Task: t.Task}
+}
+
+// CreateViewTask is a migration task for creating a view of the ViewOn
+// collection's documents transformed by the pipeline. A view with another
+// definition is replaced. It's reversed by dropping the view.
+type CreateViewTask struct {
+	Task
+	ViewOn   string
+	Pipeline mongo.Pipeline
+}
+
+// Reverse gets the task dropping the view
+func (t *CreateViewTask) Reverse() TaskContract {
+	return &DropCollectionTask{Task: t.Task}
+}
+
+// RenameCollectionTask is a migration task for renaming a collection to To.
+// It does nothing if the collection has already been renamed. It's reversed
+// by renaming the collection back.
+type RenameCollectionTask struct {
+	Task
+	To string
+	// DropTarget replaces any existing collection named To, and makes the
+	// task irreversible
+	DropTarget bool
+}
+
+// Reverse gets the task renaming the collection back, or nil if the rename
+// dropped a collection that can't be restored
+func (t *RenameCollectionTask) Reverse() TaskContract {
+	if t.DropTarget {
+		return nil
+	}
+	return &RenameCollectionTask{Task: Task{Collection: t.To}, To: t.Collection}
+}
+
+// DropCollectionTask is a migration task for dropping a collection or view
+// if it exists. It can't be reversed as the documents are lost.
+type DropCollectionTask struct {
+	Task
+}
@@ -7,9 +7,28 @@ import (
 	"github.com/archy-bold/mongo-go-helper/base"
 )
 
-// Migrator is used for running migrations
+// Migrator is used for running migrations. Tasks are run in order of their
+// names, and rolled back in reverse order.
 type Migrator interface {
 	Run(ctx context.Context, tasks map[string]TaskContract) error
+	// Rollback runs the reverse of each task. Nothing is run if any task
+	// isn't a ReversibleTask, and it stops at the first task that fails.
+	Rollback(ctx context.Context, tasks map[string]TaskContract) error
+}
+
+// TaskHook is called around each task a migrator runs. BeforeTask may return
+// a new context, which is used for the task and passed to AfterTask.
+type TaskHook interface {
+	BeforeTask(ctx context.Context, name string, task TaskContract) context.Context
+	AfterTask(ctx context.Context, name string, task TaskContract, err error)
+}
+
+// RunHook may be implemented by a TaskHook to also be called around each run.
+// BeforeRun gets the names of the tasks in the order they'll run and AfterRun
+// gets the error returned by the run.
+type RunHook interface {
+	BeforeRun(ctx context.Context, names []string) context.Context
+	AfterRun(ctx context.Context, err error)
 }
 
 // TaskContract represents a migration task
@@ -27,6 +46,17 @@ func (t *Task) GetType() string {
 	return reflect.TypeOf(t).String()
 }
 
+// GetCollection gets the collection the task works on
+func (t *Task) GetCollection() string {
+	return t.Collection
+}
+
+// CollectionTask is implemented by the tasks embedding Task
+type CollectionTask interface {
+	TaskContract
+	GetCollection() string
+}
+
 // CreateIndexTask is a migration task for creating an index
 type CreateIndexTask struct {
 	Task
@@ -34,8 +64,35 @@ type CreateIndexTask struct {
 	Keys      interface{}
 }
 
+// JSONSchemaTask is a migration task for validating a collection's documents
+// against a $jsonSchema generated from a model by base.JSONSchema. The
+// collection is created if it doesn't exist, and its validator is only
+// updated when the model or options have changed.
+type JSONSchemaTask struct {
+	Task
+	// Model is the struct, or pointer to one, the collection's documents are decoded into
+	Model interface{}
+	// ValidationLevel defaults to base.ValidationLevelStrict
+	ValidationLevel string
+	// ValidationAction defaults to base.ValidationActionError
+	ValidationAction string
+	// Updated is whether the last run changed the collection's validator
+	Updated bool
+}
+
 // FindFilterFunction represents a function to get the filter to find an item
 type FindFilterFunction func(item interface{}) (interface{}, error)
 
 // SeededCallbackFunction represents a function that's called when an item is seeded
 type SeededCallbackFunction func(item base.ModelInterface)
+
+// RefKeyFunction represents a function to get the key other seeds use to reference an item
+type RefKeyFunction func(item base.ModelInterface) string
+
+// RefPrefix is the prefix of a string referencing another seeded item
+const RefPrefix = "$ref:"
+
+// Ref gets the string referencing the item seeded into the collection with the given key
+func Ref(collection string, key string) string {
+	return RefPrefix + collection + ":" + key
+}
@@ -14,6 +14,23 @@ type SeedTableTask struct {
 	Callback     SeededCallbackFunction
 	Items        []base.ModelInterface
 	Result       interface{}
+	Stats        SeedStats
+	// BatchSize seeds the items in chunks of this size using a single lookup
+	// and a single bulk write per chunk. Zero seeds item by item.
+	BatchSize int
+	// IDGenerator sets the IDs of new items without one. Defaults to
+	// generating ObjectIDs for models with ObjectID IDs.
+	IDGenerator base.IDGenerator
+	// RefKeyFn registers each seeded item's ID under the returned key so
+	// items in later tasks can reference it using Ref(Collection, key)
+	RefKeyFn RefKeyFunction
+}
+
+// SeedStats represents the outcome of the last seeding run of a task
+type SeedStats struct {
+	Inserted  int
+	Updated   int
+	Unchanged int
 }
 
 // Seeder represents a seeder
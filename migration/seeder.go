@@ -1,17 +1,27 @@
 package migration
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"reflect"
 
 	"github.com/archy-bold/mongo-go-helper/base"
 	"github.com/archy-bold/mongo-go-helper/migration/schema"
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/jinzhu/copier"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type seeder struct {
 	helper base.MongoHelper
+	refs   *refs
+}
+
+func newSeeder(helper base.MongoHelper) *seeder {
+	return &seeder{helper, newRefs()}
 }
 
 func (s *seeder) SeedData(ctx context.Context, task *schema.SeedTableTask) error {
@@ -31,14 +41,27 @@ func (s *seeder) SeedData(ctx context.Context, task *schema.SeedTableTask) error
 		return ErrNoModel
 	}
 
+	// Validate every item so none are written if any are invalid
+	if err := s.validateItems(task.Items); err != nil {
+		return err
+	}
+
+	task.Stats = schema.SeedStats{}
+	if task.BatchSize > 0 {
+		return s.seedBatches(ctx, task)
+	}
+
 	for _, it := range task.Items {
-		// Get a copy to avoid issues when referencing the slice element
-		var item, existing base.ModelInterface
-		copier.Copy(&item, &it)
-		copier.Copy(&existing, &task.Model)
+		// Get a copy so resolving references doesn't change the task's items
+		item := copyItem(it)
+		existing := newModel(task.Model)
 
-		// Find if it already exists
-		filter, err := task.FindFilterFn(item)
+		// Resolve references then find if it already exists
+		var filter interface{}
+		err := s.refs.resolve(item)
+		if err == nil {
+			filter, err = task.FindFilterFn(item)
+		}
 
 		if err == nil {
 			if filter != nil {
@@ -46,23 +69,152 @@ func (s *seeder) SeedData(ctx context.Context, task *schema.SeedTableTask) error
 			}
 
 			if existing != nil && !existing.Exists() {
-				if _, ok := existing.GetID().(primitive.ObjectID); ok {
-					item.SetID(primitive.NewObjectID())
+				err = assignID(ctx, task, item)
+				if err == nil {
+					_, err = s.helper.InsertOne(ctx, task.Collection, item)
+				}
+				if err == nil {
+					task.Stats.Inserted++
 				}
-				_, err = s.helper.InsertOne(ctx, task.Collection, item)
 			} else {
-				if _, ok := existing.GetID().(primitive.ObjectID); ok {
-					item.SetID(existing.GetID())
+				keepExistingID(item, existing)
+				keepExistingFields(item, existing)
+
+				// Skip the write when the stored document already matches
+				var unchanged bool
+				unchanged, err = isUnchanged(item, existing)
+				if err == nil && unchanged {
+					task.Stats.Unchanged++
+				} else if err == nil {
+					err = s.helper.UpdateOne(ctx, task.Collection, filter, item)
+					if err == nil {
+						task.Stats.Updated++
+					}
 				}
-				err = s.helper.UpdateOne(ctx, task.Collection, filter, item)
 			}
 		}
 
 		if err != nil {
 			errs = multierror.Append(errs, err)
-		} else if task.Callback != nil {
+			continue
+		}
+		s.refs.register(task, item)
+		if task.Callback != nil {
 			task.Callback(item)
 		}
 	}
 	return errs.ErrorOrNil()
 }
+
+// validateItems validates the items, with their references resolved, with
+// their validate tags and Validate methods, prefixing the fields of each
+// item's errors with its index
+func (s *seeder) validateItems(items []base.ModelInterface) error {
+	errs := &base.ValidationError{}
+	for i, it := range items {
+		item := copyItem(it)
+		err := s.refs.resolve(item)
+		if errors.Is(err, ErrUnresolvedRef) {
+			// It references an item of its own task, so it's validated when written
+			continue
+		}
+		if err == nil {
+			err = base.ValidateStruct(item)
+		}
+		if v, ok := item.(base.Validator); ok && err == nil {
+			err = v.Validate()
+		}
+		if err != nil {
+			errs.Add(fmt.Sprintf("items[%d]", i), err)
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// newModel gets an empty instance of the given model's type
+func newModel(model base.ModelInterface) base.ModelInterface {
+	t := reflect.TypeOf(model)
+	if t.Kind() == reflect.Ptr {
+		if m, ok := reflect.New(t.Elem()).Interface().(base.ModelInterface); ok {
+			return m
+		}
+	}
+
+	var m base.ModelInterface
+	copier.Copy(&m, &model)
+	return m
+}
+
+// copyItem gets a deep copy of the item with the same type
+func copyItem(item base.ModelInterface) base.ModelInterface {
+	v := reflect.ValueOf(item)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		// Values are copied with the interface
+		return item
+	}
+	cp := reflect.New(v.Type()).Elem()
+	base.DeepCopy(cp, v)
+	return cp.Interface().(base.ModelInterface)
+}
+
+// assignID gives a new item an ID using the task's generator, or an ObjectID if the model uses them
+func assignID(ctx context.Context, task *schema.SeedTableTask, item base.ModelInterface) error {
+	if task.IDGenerator == nil {
+		if _, ok := task.Model.GetID().(primitive.ObjectID); ok {
+			item.SetID(primitive.NewObjectID())
+		}
+		return nil
+	}
+	if item.Exists() {
+		return nil
+	}
+
+	id, err := task.IDGenerator.NewID(ctx, task.Collection)
+	if err != nil {
+		return err
+	}
+	item.SetID(id)
+	return nil
+}
+
+// keepExistingID gives the item the existing document's ID when it's generated rather than seeded
+func keepExistingID(item base.ModelInterface, existing base.ModelInterface) {
+	if _, ok := existing.GetID().(primitive.ObjectID); ok || !item.Exists() {
+		item.SetID(existing.GetID())
+	}
+}
+
+// keepExistingFields gives the item the existing document's timestamps, audit
+// fields and version, so they're kept and unchanged items aren't written
+func keepExistingFields(item base.ModelInterface, existing base.ModelInterface) {
+	if m, ok := item.(base.TimestampedModel); ok {
+		if ex, ok := existing.(base.TimestampedModel); ok {
+			m.SetCreatedAt(ex.GetCreatedAt())
+			m.SetUpdatedAt(ex.GetUpdatedAt())
+		}
+	}
+	if m, ok := item.(base.AuditedModel); ok {
+		if ex, ok := existing.(base.AuditedModel); ok {
+			m.SetCreatedBy(ex.GetCreatedBy())
+			m.SetUpdatedBy(ex.GetUpdatedBy())
+		}
+	}
+	if m, ok := item.(base.VersionedModel); ok {
+		if ex, ok := existing.(base.VersionedModel); ok {
+			m.SetVersion(ex.GetVersion())
+		}
+	}
+}
+
+// isUnchanged checks whether the item would be stored as the existing document
+func isUnchanged(item interface{}, existing interface{}) (bool, error) {
+	a, err := bson.Marshal(item)
+	if err != nil {
+		return false, err
+	}
+	b, err := bson.Marshal(existing)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(a, b), nil
+}
@@ -0,0 +1,193 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/memdb"
+	"github.com/archy-bold/mongo-go-helper/migration/schema"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_SeedData_MemDB(t *testing.T) {
+	ctx := context.Background()
+	db := memdb.NewDatabase(testDB)
+
+	testSeedData(t, ctx, db, base.NewHelper(db))
+}
+
+var seedDataStatsTests = map[string]struct {
+	batchSize int
+}{
+	"one at a time": {0},
+	"in batches":    {2},
+}
+
+func Test_SeedData_Stats_MemDB(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range seedDataStatsTests {
+		db := memdb.NewDatabase(testDB)
+		helper := base.NewHelper(db)
+		s := newSeeder(helper)
+		newTask := func(changed string) *schema.SeedTableTask {
+			return &schema.SeedTableTask{
+				Task: schema.Task{Collection: "test"},
+				Items: []base.ModelInterface{
+					&exampleModel{Str: "one", Num: 1},
+					&exampleModel{Str: changed, Num: 2},
+					&exampleModel{Str: "three", Num: 3},
+				},
+				FindFilterFn: findFilterFn,
+				Model:        &exampleModel{},
+				BatchSize:    tt.batchSize,
+			}
+		}
+
+		first := newTask("two")
+		err := s.SeedData(ctx, first)
+		assert.Nilf(t, err, "Expected nil err for the first SeedData on test '%s'", tn)
+		assert.Equalf(t, schema.SeedStats{Inserted: 3}, first.Stats, "Expected stats to match for the first SeedData on test '%s'", tn)
+
+		second := newTask("changed")
+		err = s.SeedData(ctx, second)
+		assert.Nilf(t, err, "Expected nil err for the second SeedData on test '%s'", tn)
+		assert.Equalf(t, schema.SeedStats{Updated: 1, Unchanged: 2}, second.Stats, "Expected stats to match for the second SeedData on test '%s'", tn)
+
+		var item exampleModel
+		res, _ := helper.Find(ctx, "test", bson.M{}, item, base.FindOptions{})
+		assert.Equalf(t, int32(3), res.Total, "Expected no duplicates after re-seeding on test '%s'", tn)
+		helper.FindOne(ctx, "test", bson.M{"num": 2}, &item)
+		assert.Equalf(t, "changed", item.Str, "Expected the changed item to be updated on test '%s'", tn)
+	}
+}
+
+func Test_SeedData_RepeatedFilter_MemDB(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range seedDataStatsTests {
+		db := memdb.NewDatabase(testDB)
+		helper := base.NewHelper(db)
+		task := &schema.SeedTableTask{
+			Task: schema.Task{Collection: "test"},
+			Items: []base.ModelInterface{
+				&exampleModel{Str: "first", Num: 1},
+				&exampleModel{Str: "second", Num: 1},
+				&exampleModel{Str: "other", Num: 2},
+			},
+			FindFilterFn: findFilterFn,
+			Model:        &exampleModel{},
+			BatchSize:    tt.batchSize,
+		}
+
+		err := newSeeder(helper).SeedData(ctx, task)
+
+		assert.Nilf(t, err, "Expected nil err on test '%s'", tn)
+		assert.Equalf(t, schema.SeedStats{Inserted: 2, Updated: 1}, task.Stats, "Expected the repeated item to update the first on test '%s'", tn)
+		count, _ := db.Collection("test").CountDocuments(ctx, bson.M{"num": 1})
+		assert.Equalf(t, int64(1), count, "Expected no duplicates on test '%s'", tn)
+		var item exampleModel
+		helper.FindOne(ctx, "test", bson.M{"num": 1}, &item)
+		assert.Equalf(t, "second", item.Str, "Expected the last item to be stored on test '%s'", tn)
+	}
+}
+
+type timestampedModel struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Str       string             `bson:"str"`
+	Num       int                `bson:"num"`
+	CreatedAt time.Time          `bson:"createdAt"`
+	UpdatedAt time.Time          `bson:"updatedAt"`
+}
+
+func (m timestampedModel) Exists() bool          { return !m.ID.IsZero() }
+func (m timestampedModel) GetID() interface{}    { return m.ID }
+func (m *timestampedModel) SetID(id interface{}) { m.ID = id.(primitive.ObjectID) }
+
+func (m *timestampedModel) GetCreatedAt() time.Time  { return m.CreatedAt }
+func (m *timestampedModel) SetCreatedAt(t time.Time) { m.CreatedAt = t }
+func (m *timestampedModel) GetUpdatedAt() time.Time  { return m.UpdatedAt }
+func (m *timestampedModel) SetUpdatedAt(t time.Time) { m.UpdatedAt = t }
+
+func Test_SeedData_Timestamps_MemDB(t *testing.T) {
+	ctx := context.Background()
+	created := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	updated := created.Add(time.Hour)
+
+	for tn, tt := range seedDataStatsTests {
+		db := memdb.NewDatabase(testDB)
+		clock := created
+		helper := base.NewHelperWithOptions(db, base.HelperOptions{Now: func() time.Time { return clock }})
+		s := newSeeder(helper)
+		newTask := func(changed string) *schema.SeedTableTask {
+			return &schema.SeedTableTask{
+				Task: schema.Task{Collection: "test"},
+				Items: []base.ModelInterface{
+					&timestampedModel{Str: "one", Num: 1},
+					&timestampedModel{Str: changed, Num: 2},
+				},
+				FindFilterFn: func(item interface{}) (interface{}, error) {
+					return bson.M{"num": int32(item.(*timestampedModel).Num)}, nil
+				},
+				Model:     &timestampedModel{},
+				BatchSize: tt.batchSize,
+			}
+		}
+
+		err := s.SeedData(ctx, newTask("two"))
+		assert.Nilf(t, err, "Expected nil err for the first SeedData on test '%s'", tn)
+
+		clock = updated
+		second := newTask("changed")
+		err = s.SeedData(ctx, second)
+		assert.Nilf(t, err, "Expected nil err for the second SeedData on test '%s'", tn)
+		assert.Equalf(t, schema.SeedStats{Updated: 1, Unchanged: 1}, second.Stats, "Expected timestamps not to count as changes on test '%s'", tn)
+
+		var item timestampedModel
+		helper.FindOne(ctx, "test", bson.M{"num": 1}, &item)
+		assert.Equalf(t, created, item.UpdatedAt, "Expected the unchanged item not to be written on test '%s'", tn)
+		helper.FindOne(ctx, "test", bson.M{"num": 2}, &item)
+		assert.Equalf(t, created, item.CreatedAt, "Expected the creation time to be kept on test '%s'", tn)
+		assert.Equalf(t, updated, item.UpdatedAt, "Expected the update time to be set on test '%s'", tn)
+	}
+}
+
+type validatedModel struct {
+	ID  primitive.ObjectID `bson:"_id,omitempty"`
+	Str string             `bson:"str" validate:"required"`
+	Num int                `bson:"num" validate:"min=1"`
+}
+
+func (m validatedModel) Exists() bool          { return !m.ID.IsZero() }
+func (m validatedModel) GetID() interface{}    { return m.ID }
+func (m *validatedModel) SetID(id interface{}) { m.ID = id.(primitive.ObjectID) }
+
+func Test_SeedData_Validation_MemDB(t *testing.T) {
+	ctx := context.Background()
+	db := memdb.NewDatabase(testDB)
+	s := newSeeder(base.NewHelper(db))
+	task := &schema.SeedTableTask{
+		Task: schema.Task{Collection: "test"},
+		Items: []base.ModelInterface{
+			&validatedModel{Str: "one", Num: 1},
+			&validatedModel{Num: 2},
+			&validatedModel{Str: "three", Num: 0},
+		},
+		FindFilterFn: func(item interface{}) (interface{}, error) {
+			return bson.M{"num": item.(*validatedModel).Num}, nil
+		},
+		Model: &validatedModel{},
+	}
+
+	err := s.SeedData(ctx, task)
+
+	assert.EqualError(t, err, "validation failed: items[1].str is required; items[2].num must be at least 1")
+	assert.True(t, errors.Is(err, base.ErrValidation), "Expected a validation error")
+	count, _ := db.Collection("test").CountDocuments(ctx, bson.M{})
+	assert.Equal(t, int64(0), count, "Expected no items to be seeded when any are invalid")
+}
@@ -0,0 +1,56 @@
+package migration
+
+import (
+	"context"
+	"flag"
+	"testing"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/memdb"
+	"github.com/archy-bold/mongo-go-helper/migration/schema"
+	"github.com/archy-bold/mongo-go-helper/replay"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// replayHelper gets a helper playing the golden file, or recording it from
+// an in-memory database with -update
+func replayHelper(t *testing.T, path string) base.MongoHelper {
+	if *update {
+		return replay.Record(t, base.NewHelper(memdb.NewDatabase(testDB)), path)
+	}
+	return replay.Play(t, path)
+}
+
+func Test_SeedData_Replay(t *testing.T) {
+	ctx := context.Background()
+	helper := replayHelper(t, "testdata/seed_data.json")
+	s := newSeeder(helper)
+	newTask := func(nums ...int) *schema.SeedTableTask {
+		task := &schema.SeedTableTask{
+			Task:         schema.Task{Collection: "test2"},
+			FindFilterFn: findFilterFn2,
+			Model:        &exampleModelStrID{},
+		}
+		for i, num := range nums {
+			task.Items = append(task.Items, &exampleModelStrID{ID: []string{"a", "b", "c"}[i], Num: num})
+		}
+		return task
+	}
+
+	first := newTask(1, 2)
+	err := s.SeedData(ctx, first)
+	assert.Nil(t, err, "Expected nil err for the first SeedData")
+	assert.Equal(t, schema.SeedStats{Inserted: 2}, first.Stats)
+
+	second := newTask(1, 20, 3)
+	err = s.SeedData(ctx, second)
+	assert.Nil(t, err, "Expected nil err for the second SeedData")
+	assert.Equal(t, schema.SeedStats{Inserted: 1, Updated: 1, Unchanged: 1}, second.Stats)
+
+	var item exampleModelStrID
+	helper.FindOne(ctx, "test2", bson.M{"_id": "b"}, &item)
+	assert.Equal(t, 20, item.Num, "Expected the changed item to be updated")
+}
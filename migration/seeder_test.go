@@ -6,6 +6,7 @@ import (
 
 	"github.com/archy-bold/mongo-go-helper/base"
 	"github.com/archy-bold/mongo-go-helper/migration/schema"
+	bMocks "github.com/archy-bold/mongo-go-helper/mocks/base"
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -19,6 +20,7 @@ var (
 	testDB     = "wata-delivery-test"
 	defaultURI = "mongodb://localhost:27017"
 	cMock      = &callbackMock{}
+	sampleID   = primitive.NewObjectID()
 )
 
 type callbackMock struct {
@@ -182,13 +184,18 @@ func Test_DeliveriesRepository_SeedData(t *testing.T) {
 	opt := options.Client()
 	opt.ApplyURI(defaultURI)
 	client, _ := mongo.NewClient(opt)
-	db = client.Database(testDB)
+	db = base.WrapDatabase(client.Database(testDB))
 	helper := base.NewHelper(db)
 	client.Connect(ctx)
 	defer cleanTests(ctx, db)
 
+	testSeedData(t, ctx, db, helper)
+}
+
+// testSeedData runs the seedDataTests against the database
+func testSeedData(t *testing.T, ctx context.Context, db base.MongoDB, helper base.MongoHelper) {
 	// Create the seeder too
-	s := &seeder{helper}
+	s := newSeeder(helper)
 
 	for _, tt := range seedDataTests {
 		// Expect the callback to be mocked
@@ -246,6 +253,95 @@ func Test_DeliveriesRepository_SeedData(t *testing.T) {
 	}
 }
 
+var seedDataChangesTests = map[string]struct {
+	existing *exampleModel
+	item     *exampleModel
+	inserts  int
+	updates  int
+	expected schema.SeedStats
+}{
+	"new item": {
+		nil,
+		&exampleModel{Str: "test1", Num: 999},
+		1, 0,
+		schema.SeedStats{Inserted: 1},
+	},
+	"changed item": {
+		&exampleModel{ID: sampleID, Str: "test1", Num: 999},
+		&exampleModel{Str: "changed", Num: 999},
+		0, 1,
+		schema.SeedStats{Updated: 1},
+	},
+	"unchanged item": {
+		&exampleModel{ID: sampleID, Str: "test1", Num: 999},
+		&exampleModel{Str: "test1", Num: 999},
+		0, 0,
+		schema.SeedStats{Unchanged: 1},
+	},
+}
+
+func Test_SeedData_Changes(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range seedDataChangesTests {
+		// Set up the mock to return the existing document
+		helper := &bMocks.MongoHelper{}
+		existing := tt.existing
+		helper.On("FindOne", ctx, "test", mock.Anything, mock.AnythingOfType("*migration.exampleModel")).
+			Run(func(args mock.Arguments) {
+				if existing != nil {
+					*(args.Get(3).(*exampleModel)) = *existing
+				}
+			})
+		helper.On("InsertOne", ctx, "test", mock.Anything).Return(sampleID, nil)
+		helper.On("UpdateOne", ctx, "test", mock.Anything, mock.Anything).Return(nil)
+		s := newSeeder(helper)
+
+		task := &schema.SeedTableTask{
+			Task:         schema.Task{Collection: "test"},
+			Items:        []base.ModelInterface{tt.item},
+			FindFilterFn: findFilterFn,
+			Model:        &exampleModel{},
+		}
+		err := s.SeedData(ctx, task)
+
+		// The assertions
+		assert.Nilf(t, err, "Expected nil err for SeedData on test '%s'", tn)
+		helper.AssertNumberOfCalls(t, "InsertOne", tt.inserts)
+		helper.AssertNumberOfCalls(t, "UpdateOne", tt.updates)
+		assert.Equalf(t, tt.expected, task.Stats, "Expected stats to match for SeedData on test '%s'", tn)
+	}
+}
+
+func Test_SeedData_IDGenerator(t *testing.T) {
+	ctx := context.Background()
+	helper := &bMocks.MongoHelper{}
+	helper.On("FindOne", ctx, "test2", mock.Anything, mock.Anything)
+	var inserted []*exampleModelStrID
+	helper.On("InsertOne", ctx, "test2", mock.Anything).
+		Run(func(args mock.Arguments) {
+			inserted = append(inserted, args.Get(2).(*exampleModelStrID))
+		}).
+		Return("id", nil)
+	s := newSeeder(helper)
+
+	task := &schema.SeedTableTask{
+		Task:  schema.Task{Collection: "test2"},
+		Items: []base.ModelInterface{&exampleModelStrID{Num: 1}, &exampleModelStrID{ID: "fixed", Num: 2}},
+		FindFilterFn: func(item interface{}) (interface{}, error) {
+			return &bson.M{"num": item.(*exampleModelStrID).Num}, nil
+		},
+		Model:       &exampleModelStrID{},
+		IDGenerator: base.UUIDv4Generator,
+	}
+	err := s.SeedData(ctx, task)
+
+	assert.Nil(t, err, "Expected nil err for SeedData with an ID generator")
+	assert.Len(t, inserted, 2)
+	assert.Len(t, inserted[0].ID, 36, "Expected a generated UUID for the item without an ID")
+	assert.Equal(t, "fixed", inserted[1].ID, "Expected the seeded ID to be kept")
+}
+
 func findExampleModel(arr []interface{}, num int) *exampleModel {
 	for _, i := range arr {
 		if item, ok := i.(*exampleModel); ok {
@@ -0,0 +1,162 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/migration/schema"
+	multierror "github.com/hashicorp/go-multierror"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TenantOptions represents options for migrating each tenant's database
+type TenantOptions struct {
+	// Databases are the names of the tenant databases
+	Databases []string
+	// Pattern adds the client's databases with matching names. The admin,
+	// config and local databases are never matched.
+	Pattern *regexp.Regexp
+	// Concurrency is the most tenants migrated at once. Defaults to 1.
+	Concurrency int
+	// NewHelper gets the helper for a tenant's database. Defaults to
+	// base.NewHelper.
+	NewHelper func(db base.MongoDB) base.MongoHelper
+	// Migrator are the options of each tenant's migrator. Its hooks are
+	// shared by the tenants, so must be safe to call concurrently when
+	// Concurrency is over 1.
+	Migrator MigratorOptions
+}
+
+// TenantTasks gets the tasks to run against the tenant database. Tasks hold
+// the stats of their runs, so each tenant should get its own.
+type TenantTasks func(database string) map[string]schema.TaskContract
+
+// TenantResult represents the outcome of migrating a tenant database
+type TenantResult struct {
+	Database string
+	Err      error
+	Duration time.Duration
+}
+
+// TenantReport summarises migrating the tenant databases
+type TenantReport struct {
+	// Results are in database name order
+	Results []TenantResult
+}
+
+// Failed gets the results of the tenants that failed to migrate
+func (r *TenantReport) Failed() []TenantResult {
+	var failed []TenantResult
+	for _, res := range r.Results {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// Err gets the errors of the tenants that failed to migrate, or nil if all succeeded
+func (r *TenantReport) Err() error {
+	var errs *multierror.Error
+	for _, res := range r.Failed() {
+		errs = multierror.Append(errs, fmt.Errorf("could not migrate tenant '%s': %w", res.Database, res.Err))
+	}
+	return errs.ErrorOrNil()
+}
+
+type tenantKey struct{}
+
+// WithTenant gets a context for migrating the tenant database
+func WithTenant(ctx context.Context, database string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, database)
+}
+
+// TenantFromContext gets the tenant database being migrated, or an empty
+// string outside of RunTenants
+func TenantFromContext(ctx context.Context) string {
+	database, _ := ctx.Value(tenantKey{}).(string)
+	return database
+}
+
+// RunTenants runs the tasks against each tenant database with its own
+// migrator, so backfill history is kept in each tenant's database. Every
+// tenant is attempted; the report lists each outcome and the error combines
+// those that failed. Tenants not started before the context is done fail
+// with its error.
+func RunTenants(ctx context.Context, client base.MongoClient, tasks TenantTasks, opts TenantOptions) (*TenantReport, error) {
+	names, err := tenantDatabases(ctx, client, opts)
+	if err != nil {
+		return nil, err
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	newHelper := opts.NewHelper
+	if newHelper == nil {
+		newHelper = base.NewHelper
+	}
+
+	report := &TenantReport{Results: make([]TenantResult, len(names))}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			report.Results[i] = TenantResult{Database: name, Err: ctx.Err()}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, name string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			start := time.Now()
+			m := NewMigratorWithOptions(newHelper(client.Database(name)), opts.Migrator)
+			err := m.Run(WithTenant(ctx, name), tasks(name))
+			report.Results[i] = TenantResult{Database: name, Err: err, Duration: time.Since(start)}
+		}(i, name)
+	}
+	wg.Wait()
+
+	return report, report.Err()
+}
+
+// tenantDatabases gets the sorted names of the listed databases and those
+// matching the pattern
+func tenantDatabases(ctx context.Context, client base.MongoClient, opts TenantOptions) ([]string, error) {
+	if len(opts.Databases) == 0 && opts.Pattern == nil {
+		return nil, ErrNoTenants
+	}
+
+	set := make(map[string]bool, len(opts.Databases))
+	for _, name := range opts.Databases {
+		set[name] = true
+	}
+	if opts.Pattern != nil {
+		all, err := client.ListDatabaseNames(ctx, bson.D{})
+		if err != nil {
+			return nil, base.MapError(err)
+		}
+		for _, name := range all {
+			if name != "admin" && name != "config" && name != "local" && opts.Pattern.MatchString(name) {
+				set[name] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,158 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/memdb"
+	"github.com/archy-bold/mongo-go-helper/migration/schema"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func tenantTasks(database string) map[string]schema.TaskContract {
+	return map[string]schema.TaskContract{
+		"01_events": &schema.CreateCollectionTask{Task: schema.Task{Collection: "events"}},
+		"02_recent": &schema.CreateViewTask{Task: schema.Task{Collection: "recent"}, ViewOn: "events", Pipeline: recentPipeline},
+		"03_names": &schema.BackfillTask{
+			Task: schema.Task{Collection: "people"},
+			Transform: func(doc bson.M) (interface{}, error) {
+				return bson.M{"$set": bson.M{"tenant": database}}, nil
+			},
+		},
+	}
+}
+
+func Test_RunTenants_MemDB(t *testing.T) {
+	ctx := context.Background()
+	client := memdb.NewClient()
+	for _, name := range []string{"tenant_a", "tenant_b", "tenant_c", "other"} {
+		client.Database(name).Collection("people").InsertOne(ctx, bson.M{"_id": 1})
+	}
+	// A collection takes the view's name in one tenant
+	client.Database("tenant_b").Collection("recent").InsertOne(ctx, bson.M{"a": 1})
+
+	report, err := RunTenants(ctx, client, tenantTasks, TenantOptions{
+		Databases:   []string{"tenant_d"},
+		Pattern:     regexp.MustCompile("^tenant_"),
+		Concurrency: 2,
+	})
+
+	tenantErr := buildMultiError([]error{ErrNotView})
+	assert.Equal(t, buildMultiError([]error{fmt.Errorf("could not migrate tenant 'tenant_b': %w", tenantErr)}), err)
+	if assert.NotNil(t, report) {
+		var names []string
+		for _, res := range report.Results {
+			names = append(names, res.Database)
+		}
+		assert.Equal(t, []string{"tenant_a", "tenant_b", "tenant_c", "tenant_d"}, names, "Expected the listed and matching tenants in name order")
+		failed := report.Failed()
+		if assert.Len(t, failed, 1) {
+			assert.Equal(t, "tenant_b", failed[0].Database)
+			assert.Equal(t, tenantErr, failed[0].Err)
+		}
+	}
+
+	for _, name := range []string{"tenant_a", "tenant_c"} {
+		db := client.Database(name)
+		assertCount(t, db, "people", bson.M{"_id": 1, "tenant": name}, 1)
+		assertCount(t, db, DefaultHistoryCollection, bson.M{"_id": "03_names", "done": true}, 1)
+		names, _ := db.ListCollectionNames(ctx, bson.M{"name": "events"})
+		assert.Equal(t, []string{"events"}, names, "Expected the collection to be created in '%s'", name)
+	}
+	assertCount(t, client.Database("other"), "people", bson.M{"tenant": bson.M{"$exists": true}}, 0)
+	names, _ := client.Database("tenant_d").ListCollectionNames(ctx, bson.M{})
+	assert.Equal(t, []string{"events", "migrations", "recent"}, names, "Expected the listed tenant to be migrated")
+}
+
+// concurrencyHook records the tenants run and the most run at once
+type concurrencyHook struct {
+	mu      sync.Mutex
+	running int
+	most    int
+	tenants []string
+}
+
+func (h *concurrencyHook) BeforeRun(ctx context.Context, names []string) context.Context {
+	h.mu.Lock()
+	h.running++
+	if h.running > h.most {
+		h.most = h.running
+	}
+	h.tenants = append(h.tenants, TenantFromContext(ctx))
+	h.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	return ctx
+}
+
+func (h *concurrencyHook) AfterRun(ctx context.Context, err error) {
+	h.mu.Lock()
+	h.running--
+	h.mu.Unlock()
+}
+
+func (h *concurrencyHook) BeforeTask(ctx context.Context, name string, task schema.TaskContract) context.Context {
+	return ctx
+}
+
+func (h *concurrencyHook) AfterTask(ctx context.Context, name string, task schema.TaskContract, err error) {
+}
+
+func Test_RunTenants_Concurrency(t *testing.T) {
+	ctx := context.Background()
+	client := memdb.NewClient()
+	hook := &concurrencyHook{}
+
+	report, err := RunTenants(ctx, client, tenantTasks, TenantOptions{
+		Databases:   []string{"t1", "t2", "t3", "t4", "t5"},
+		Concurrency: 2,
+		Migrator:    MigratorOptions{Hooks: []schema.TaskHook{hook}},
+	})
+
+	assert.Nil(t, err)
+	assert.Len(t, report.Results, 5)
+	assert.Empty(t, report.Failed())
+	assert.Equal(t, 2, hook.most, "Expected at most 2 tenants to be migrated at once")
+	assert.ElementsMatch(t, []string{"t1", "t2", "t3", "t4", "t5"}, hook.tenants, "Expected the hooks to get each tenant from the context")
+}
+
+func Test_RunTenants_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report, err := RunTenants(ctx, memdb.NewClient(), tenantTasks, TenantOptions{Databases: []string{"t1", "t2", "t3"}})
+
+	assert.NotNil(t, err)
+	if assert.NotNil(t, report) {
+		assert.Len(t, report.Failed(), 3, "Expected every tenant to fail once the context is done")
+	}
+}
+
+var runTenantsErrorsTests = map[string]struct {
+	client base.MongoClient
+	opts   TenantOptions
+	err    error
+}{
+	"no tenants": {memdb.NewClient(), TenantOptions{Concurrency: 2}, ErrNoTenants},
+	"no matches": {memdb.NewClient(), TenantOptions{Pattern: regexp.MustCompile("^tenant_")}, nil},
+}
+
+func Test_RunTenants_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range runTenantsErrorsTests {
+		report, err := RunTenants(ctx, tt.client, tenantTasks, tt.opts)
+
+		assert.Equalf(t, tt.err, err, "Expected err to match on test '%s'", tn)
+		if tt.err == nil {
+			assert.Emptyf(t, report.Results, "Expected no results on test '%s'", tn)
+		} else {
+			assert.Nilf(t, report, "Expected nil report on test '%s'", tn)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package migration
+
+import (
+	"context"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/migration/schema"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// applySchema sets the collection's validator to the schema of the task's
+// model, unless the collection already has it
+func (m *migrator) applySchema(ctx context.Context, task *schema.JSONSchemaTask) error {
+	task.Updated = false
+	if task.Collection == "" {
+		return ErrNoCollection
+	}
+	if task.Model == nil {
+		return ErrNoModel
+	}
+
+	jsonSchema, err := base.JSONSchema(task.Model)
+	if err != nil {
+		return err
+	}
+	v := base.CollectionValidator{
+		Validator: bson.D{{Key: "$jsonSchema", Value: jsonSchema}},
+		Level:     task.ValidationLevel,
+		Action:    task.ValidationAction,
+	}
+
+	current, err := m.helper.GetValidator(ctx, task.Collection)
+	if err != nil {
+		return err
+	}
+	if current != nil && current.Equal(v) {
+		return nil
+	}
+
+	if err := m.helper.SetValidator(ctx, task.Collection, v); err != nil {
+		return err
+	}
+	task.Updated = true
+	return nil
+}
@@ -0,0 +1,76 @@
+package migration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/memdb"
+	"github.com/archy-bold/mongo-go-helper/migration/schema"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type userV1 struct {
+	Name string `bson:"name" validate:"required"`
+}
+
+type userV2 struct {
+	Name string `bson:"name" validate:"required"`
+	Role string `bson:"role" validate:"required,enum=admin|member"`
+}
+
+var jsonSchemaTaskTests = map[string]struct {
+	task            *schema.JSONSchemaTask
+	expectedUpdated bool
+	expectedAction  string
+	err             error
+}{
+	"unchanged": {
+		&schema.JSONSchemaTask{Task: schema.Task{Collection: "users"}, Model: &userV1{}},
+		false, base.ValidationActionError, nil,
+	},
+	"changed model": {
+		&schema.JSONSchemaTask{Task: schema.Task{Collection: "users"}, Model: &userV2{}},
+		true, base.ValidationActionError, nil,
+	},
+	"changed action": {
+		&schema.JSONSchemaTask{Task: schema.Task{Collection: "users"}, Model: &userV1{}, ValidationAction: base.ValidationActionWarn},
+		true, base.ValidationActionWarn, nil,
+	},
+	"no collection": {&schema.JSONSchemaTask{Model: &userV1{}}, false, base.ValidationActionError, ErrNoCollection},
+	"no model":      {&schema.JSONSchemaTask{Task: schema.Task{Collection: "users"}}, false, base.ValidationActionError, ErrNoModel},
+}
+
+func Test_JSONSchemaTask_MemDB(t *testing.T) {
+	ctx := context.Background()
+
+	for tn, tt := range jsonSchemaTaskTests {
+		helper := base.NewHelper(memdb.NewDatabase(testDB))
+		m := NewMigrator(helper)
+
+		first := &schema.JSONSchemaTask{Task: schema.Task{Collection: "users"}, Model: &userV1{}}
+		err := m.Run(ctx, map[string]schema.TaskContract{"01_users": first})
+		assert.Nilf(t, err, "Expected nil err for the first run on test '%s'", tn)
+		assert.Truef(t, first.Updated, "Expected the collection to be created on test '%s'", tn)
+
+		err = m.Run(ctx, map[string]schema.TaskContract{"01_users": tt.task})
+		if tt.err != nil {
+			assert.Equalf(t, buildMultiError([]error{tt.err}), err, "Expected err to match on test '%s'", tn)
+		} else {
+			assert.Nilf(t, err, "Expected nil err for the second run on test '%s'", tn)
+		}
+		assert.Equalf(t, tt.expectedUpdated, tt.task.Updated, "Expected Updated to match on test '%s'", tn)
+
+		v, _ := helper.GetValidator(ctx, "users")
+		model := tt.task.Model
+		if !tt.expectedUpdated {
+			model = first.Model
+		}
+		jsonSchema, _ := base.JSONSchema(model)
+		expected := base.CollectionValidator{Validator: bson.D{{Key: "$jsonSchema", Value: jsonSchema}}, Action: tt.expectedAction}
+		if assert.NotNilf(t, v, "Expected a validator on test '%s'", tn) {
+			assert.Truef(t, v.Equal(expected), "Expected the validator to match on test '%s'", tn)
+		}
+	}
+}
@@ -5,10 +5,12 @@ package mocks
 import (
 	context "context"
 
+	base "github.com/archy-bold/mongo-go-helper/base"
 	mock "github.com/stretchr/testify/mock"
-	mongo "go.mongodb.org/mongo-driver/mongo"
 
 	options "go.mongodb.org/mongo-driver/mongo/options"
+
+	readpref "go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 // MongoClient is an autogenerated mock type for the MongoClient type
@@ -31,7 +33,7 @@ func (_m *MongoClient) Connect(_a0 context.Context) error {
 }
 
 // Database provides a mock function with given fields: _a0, _a1
-func (_m *MongoClient) Database(_a0 string, _a1 ...*options.DatabaseOptions) *mongo.Database {
+func (_m *MongoClient) Database(_a0 string, _a1 ...*options.DatabaseOptions) base.MongoDB {
 	_va := make([]interface{}, len(_a1))
 	for _i := range _a1 {
 		_va[_i] = _a1[_i]
@@ -41,14 +43,72 @@ func (_m *MongoClient) Database(_a0 string, _a1 ...*options.DatabaseOptions) *mo
 	_ca = append(_ca, _va...)
 	ret := _m.Called(_ca...)
 
-	var r0 *mongo.Database
-	if rf, ok := ret.Get(0).(func(string, ...*options.DatabaseOptions) *mongo.Database); ok {
+	var r0 base.MongoDB
+	if rf, ok := ret.Get(0).(func(string, ...*options.DatabaseOptions) base.MongoDB); ok {
 		r0 = rf(_a0, _a1...)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*mongo.Database)
+			r0 = ret.Get(0).(base.MongoDB)
 		}
 	}
 
 	return r0
 }
+
+// Disconnect provides a mock function with given fields: _a0
+func (_m *MongoClient) Disconnect(_a0 context.Context) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ListDatabaseNames provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MongoClient) ListDatabaseNames(_a0 context.Context, _a1 interface{}, _a2 ...*options.ListDatabasesOptions) ([]string, error) {
+	_va := make([]interface{}, len(_a2))
+	for _i := range _a2 {
+		_va[_i] = _a2[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _a0, _a1)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, ...*options.ListDatabasesOptions) []string); ok {
+		r0 = rf(_a0, _a1, _a2...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}, ...*options.ListDatabasesOptions) error); ok {
+		r1 = rf(_a0, _a1, _a2...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Ping provides a mock function with given fields: _a0, _a1
+func (_m *MongoClient) Ping(_a0 context.Context, _a1 *readpref.ReadPref) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *readpref.ReadPref) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
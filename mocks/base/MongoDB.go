@@ -3,8 +3,10 @@
 package mocks
 
 import (
+	context "context"
+
+	base "github.com/archy-bold/mongo-go-helper/base"
 	mock "github.com/stretchr/testify/mock"
-	mongo "go.mongodb.org/mongo-driver/mongo"
 
 	options "go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -14,8 +16,24 @@ type MongoDB struct {
 	mock.Mock
 }
 
+// Client provides a mock function with given fields:
+func (_m *MongoDB) Client() base.MongoClient {
+	ret := _m.Called()
+
+	var r0 base.MongoClient
+	if rf, ok := ret.Get(0).(func() base.MongoClient); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(base.MongoClient)
+		}
+	}
+
+	return r0
+}
+
 // Collection provides a mock function with given fields: _a0, _a1
-func (_m *MongoDB) Collection(_a0 string, _a1 ...*options.CollectionOptions) *mongo.Collection {
+func (_m *MongoDB) Collection(_a0 string, _a1 ...*options.CollectionOptions) base.MongoCollection {
 	_va := make([]interface{}, len(_a1))
 	for _i := range _a1 {
 		_va[_i] = _a1[_i]
@@ -25,12 +43,93 @@ func (_m *MongoDB) Collection(_a0 string, _a1 ...*options.CollectionOptions) *mo
 	_ca = append(_ca, _va...)
 	ret := _m.Called(_ca...)
 
-	var r0 *mongo.Collection
-	if rf, ok := ret.Get(0).(func(string, ...*options.CollectionOptions) *mongo.Collection); ok {
+	var r0 base.MongoCollection
+	if rf, ok := ret.Get(0).(func(string, ...*options.CollectionOptions) base.MongoCollection); ok {
 		r0 = rf(_a0, _a1...)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*mongo.Collection)
+			r0 = ret.Get(0).(base.MongoCollection)
+		}
+	}
+
+	return r0
+}
+
+// Drop provides a mock function with given fields: _a0
+func (_m *MongoDB) Drop(_a0 context.Context) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ListCollectionNames provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MongoDB) ListCollectionNames(_a0 context.Context, _a1 interface{}, _a2 ...*options.ListCollectionsOptions) ([]string, error) {
+	_va := make([]interface{}, len(_a2))
+	for _i := range _a2 {
+		_va[_i] = _a2[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _a0, _a1)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, ...*options.ListCollectionsOptions) []string); ok {
+		r0 = rf(_a0, _a1, _a2...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}, ...*options.ListCollectionsOptions) error); ok {
+		r1 = rf(_a0, _a1, _a2...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Name provides a mock function with given fields:
+func (_m *MongoDB) Name() string {
+	ret := _m.Called()
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// RunCommand provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MongoDB) RunCommand(_a0 context.Context, _a1 interface{}, _a2 ...*options.RunCmdOptions) base.SingleResult {
+	_va := make([]interface{}, len(_a2))
+	for _i := range _a2 {
+		_va[_i] = _a2[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _a0, _a1)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 base.SingleResult
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, ...*options.RunCmdOptions) base.SingleResult); ok {
+		r0 = rf(_a0, _a1, _a2...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(base.SingleResult)
 		}
 	}
 
@@ -10,6 +10,7 @@ import (
 	mock "github.com/stretchr/testify/mock"
 
 	mongo "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	pagination "github.com/archy-bold/mongo-go-helper/pagination"
 
@@ -58,6 +59,78 @@ func (_m *MongoHelper) Aggregate(ctx context.Context, coll string, pipeline mong
 	return r0, r1
 }
 
+// BulkWrite provides a mock function with given fields: ctx, coll, models, opts
+func (_m *MongoHelper) BulkWrite(ctx context.Context, coll string, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, coll, models)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *mongo.BulkWriteResult
+	if rf, ok := ret.Get(0).(func(context.Context, string, []mongo.WriteModel, ...*options.BulkWriteOptions) *mongo.BulkWriteResult); ok {
+		r0 = rf(ctx, coll, models, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*mongo.BulkWriteResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, []mongo.WriteModel, ...*options.BulkWriteOptions) error); ok {
+		r1 = rf(ctx, coll, models, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateCollection provides a mock function with given fields: ctx, name, opts
+func (_m *MongoHelper) CreateCollection(ctx context.Context, name string, opts base.CollectionOptions) error {
+	ret := _m.Called(ctx, name, opts)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, base.CollectionOptions) error); ok {
+		r0 = rf(ctx, name, opts)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteOne provides a mock function with given fields: ctx, coll, filter, item
+func (_m *MongoHelper) DeleteOne(ctx context.Context, coll string, filter interface{}, item interface{}) error {
+	ret := _m.Called(ctx, coll, filter, item)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, interface{}, interface{}) error); ok {
+		r0 = rf(ctx, coll, filter, item)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DropCollection provides a mock function with given fields: ctx, name
+func (_m *MongoHelper) DropCollection(ctx context.Context, name string) error {
+	ret := _m.Called(ctx, name)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Find provides a mock function with given fields: ctx, coll, filter, item, opts
 func (_m *MongoHelper) Find(ctx context.Context, coll string, filter interface{}, item interface{}, opts base.FindOptions) (pagination.Result, error) {
 	ret := _m.Called(ctx, coll, filter, item, opts)
@@ -84,16 +157,39 @@ func (_m *MongoHelper) FindOne(ctx context.Context, coll string, filter interfac
 	_m.Called(ctx, coll, filter, item)
 }
 
+// GetCollection provides a mock function with given fields: ctx, name
+func (_m *MongoHelper) GetCollection(ctx context.Context, name string) (*base.CollectionInfo, error) {
+	ret := _m.Called(ctx, name)
+
+	var r0 *base.CollectionInfo
+	if rf, ok := ret.Get(0).(func(context.Context, string) *base.CollectionInfo); ok {
+		r0 = rf(ctx, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*base.CollectionInfo)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetIDFromInsertOneResult provides a mock function with given fields: _a0
-func (_m *MongoHelper) GetIDFromInsertOneResult(_a0 *mongo.InsertOneResult) (primitive.ObjectID, error) {
+func (_m *MongoHelper) GetIDFromInsertOneResult(_a0 *mongo.InsertOneResult) (interface{}, error) {
 	ret := _m.Called(_a0)
 
-	var r0 primitive.ObjectID
-	if rf, ok := ret.Get(0).(func(*mongo.InsertOneResult) primitive.ObjectID); ok {
+	var r0 interface{}
+	if rf, ok := ret.Get(0).(func(*mongo.InsertOneResult) interface{}); ok {
 		r0 = rf(_a0)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(primitive.ObjectID)
+			r0 = ret.Get(0).(interface{})
 		}
 	}
 
@@ -130,6 +226,29 @@ func (_m *MongoHelper) GetIndex(ctx context.Context, coll string, index string)
 	return r0, r1
 }
 
+// GetValidator provides a mock function with given fields: ctx, coll
+func (_m *MongoHelper) GetValidator(ctx context.Context, coll string) (*base.CollectionValidator, error) {
+	ret := _m.Called(ctx, coll)
+
+	var r0 *base.CollectionValidator
+	if rf, ok := ret.Get(0).(func(context.Context, string) *base.CollectionValidator); ok {
+		r0 = rf(ctx, coll)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*base.CollectionValidator)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, coll)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // HasIndex provides a mock function with given fields: ctx, coll, index
 func (_m *MongoHelper) HasIndex(ctx context.Context, coll string, index string) (bool, error) {
 	ret := _m.Called(ctx, coll, index)
@@ -152,15 +271,15 @@ func (_m *MongoHelper) HasIndex(ctx context.Context, coll string, index string)
 }
 
 // InsertOne provides a mock function with given fields: ctx, coll, item
-func (_m *MongoHelper) InsertOne(ctx context.Context, coll string, item interface{}) (primitive.ObjectID, error) {
+func (_m *MongoHelper) InsertOne(ctx context.Context, coll string, item interface{}) (interface{}, error) {
 	ret := _m.Called(ctx, coll, item)
 
-	var r0 primitive.ObjectID
-	if rf, ok := ret.Get(0).(func(context.Context, string, interface{}) primitive.ObjectID); ok {
+	var r0 interface{}
+	if rf, ok := ret.Get(0).(func(context.Context, string, interface{}) interface{}); ok {
 		r0 = rf(ctx, coll, item)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(primitive.ObjectID)
+			r0 = ret.Get(0).(interface{})
 		}
 	}
 
@@ -197,6 +316,34 @@ func (_m *MongoHelper) NewClient(uri string) (base.MongoClient, error) {
 	return r0, r1
 }
 
+// RenameCollection provides a mock function with given fields: ctx, from, to, dropTarget
+func (_m *MongoHelper) RenameCollection(ctx context.Context, from string, to string, dropTarget bool) error {
+	ret := _m.Called(ctx, from, to, dropTarget)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, bool) error); ok {
+		r0 = rf(ctx, from, to, dropTarget)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetValidator provides a mock function with given fields: ctx, coll, v
+func (_m *MongoHelper) SetValidator(ctx context.Context, coll string, v base.CollectionValidator) error {
+	ret := _m.Called(ctx, coll, v)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, base.CollectionValidator) error); ok {
+		r0 = rf(ctx, coll, v)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpdateOne provides a mock function with given fields: ctx, coll, filter, item
 func (_m *MongoHelper) UpdateOne(ctx context.Context, coll string, filter interface{}, item interface{}) error {
 	ret := _m.Called(ctx, coll, filter, item)
@@ -14,6 +14,20 @@ type Migrator struct {
 	mock.Mock
 }
 
+// Rollback provides a mock function with given fields: ctx, tasks
+func (_m *Migrator) Rollback(ctx context.Context, tasks map[string]schema.TaskContract) error {
+	ret := _m.Called(ctx, tasks)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, map[string]schema.TaskContract) error); ok {
+		r0 = rf(ctx, tasks)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Run provides a mock function with given fields: ctx, tasks
 func (_m *Migrator) Run(ctx context.Context, tasks map[string]schema.TaskContract) error {
 	ret := _m.Called(ctx, tasks)
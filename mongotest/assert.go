@@ -0,0 +1,52 @@
+package mongotest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// AssertDocumentExists asserts a document in the collection matches the filter
+func AssertDocumentExists(t testing.TB, db base.MongoDB, coll string, filter interface{}, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	n, err := db.Collection(coll).CountDocuments(context.Background(), filter)
+	if !assert.NoError(t, err, msgAndArgs...) {
+		return false
+	}
+	if n == 0 {
+		return assert.Fail(t, fmt.Sprintf("Expected a document in '%s' matching %s", coll, formatFilter(filter)), msgAndArgs...)
+	}
+	return true
+}
+
+// AssertNoDocument asserts no document in the collection matches the filter
+func AssertNoDocument(t testing.TB, db base.MongoDB, coll string, filter interface{}, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	return AssertCount(t, db, coll, filter, 0, msgAndArgs...)
+}
+
+// AssertCount asserts the number of documents in the collection matching the filter
+func AssertCount(t testing.TB, db base.MongoDB, coll string, filter interface{}, expected int64, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	n, err := db.Collection(coll).CountDocuments(context.Background(), filter)
+	if !assert.NoError(t, err, msgAndArgs...) {
+		return false
+	}
+	if n != expected {
+		return assert.Fail(t, fmt.Sprintf("Expected %d documents in '%s' matching %s, found %d", expected, coll, formatFilter(filter), n), msgAndArgs...)
+	}
+	return true
+}
+
+// formatFilter gets the filter as Extended JSON for failure messages
+func formatFilter(filter interface{}) string {
+	b, err := bson.MarshalExtJSON(filter, false, false)
+	if err != nil {
+		return fmt.Sprintf("%v", filter)
+	}
+	return string(b)
+}
@@ -0,0 +1,89 @@
+package mongotest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/archy-bold/mongo-go-helper/migration"
+	"github.com/archy-bold/mongo-go-helper/migration/schema"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Fixture holds the documents to load into a collection
+type Fixture struct {
+	Collection string
+	Documents  []interface{}
+}
+
+// ReadFixtures reads the fixtures in an Extended JSON file. The file is
+// either an object of collection names to arrays of documents, or a single
+// array of documents for the collection named after the file.
+func ReadFixtures(path string) ([]Fixture, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) {
+		coll := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		data = append([]byte(fmt.Sprintf(`{"%s":`, coll)), append(data, '}')...)
+	}
+
+	var doc bson.D
+	if err := bson.UnmarshalExtJSON(data, false, &doc); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse fixtures '%s'", path)
+	}
+
+	fixtures := make([]Fixture, 0, len(doc))
+	for _, e := range doc {
+		docs, ok := e.Value.(primitive.A)
+		if !ok {
+			return nil, fmt.Errorf("failed to parse fixtures '%s': collection '%s' is not an array", path, e.Key)
+		}
+		fixtures = append(fixtures, Fixture{e.Key, docs})
+	}
+
+	return fixtures, nil
+}
+
+// LoadFixtures inserts the documents of each fixture file, failing the test
+// on error
+func (d *DB) LoadFixtures(paths ...string) {
+	d.t.Helper()
+	ctx := context.Background()
+
+	for _, path := range paths {
+		fixtures, err := ReadFixtures(path)
+		if err != nil {
+			d.t.Fatal(err)
+		}
+		for _, f := range fixtures {
+			if len(f.Documents) == 0 {
+				continue
+			}
+			if _, err := d.Collection(f.Collection).InsertMany(ctx, f.Documents); err != nil {
+				d.t.Fatalf("failed to load fixtures '%s' into '%s': %v", path, f.Collection, err)
+			}
+		}
+	}
+}
+
+// Seed runs the seed tasks in order, failing the test on error. The tasks'
+// stats and callbacks work as they do in migrations.
+func (d *DB) Seed(tasks ...*schema.SeedTableTask) {
+	d.t.Helper()
+
+	m := migration.NewMigrator(d.Helper)
+	for i, task := range tasks {
+		if err := m.Run(context.Background(), map[string]schema.TaskContract{fmt.Sprint(i): task}); err != nil {
+			d.t.Fatalf("failed to seed '%s': %v", task.Collection, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Package mongotest gives each test its own uniquely named database, loads
+// fixtures into it and drops it when the test finishes, so tests and packages
+// running in parallel never see each other's documents.
+//
+// Databases are in-memory by default. Pass a connected client in the options
+// to run the same tests against a real server.
+package mongotest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/memdb"
+)
+
+// maxNameLength is the longest database name the server accepts
+const maxNameLength = 63
+
+// Options configures a test database
+type Options struct {
+	// Client creates the database. Defaults to a new in-memory client.
+	Client base.MongoClient
+	// HelperOptions are used for the database's helper
+	HelperOptions base.HelperOptions
+}
+
+// DB is a database belonging to a single test
+type DB struct {
+	base.MongoDB
+	// Helper is a helper over the database
+	Helper base.MongoHelper
+	t      testing.TB
+}
+
+// New gets an empty in-memory database for the test, dropped on cleanup
+func New(t testing.TB) *DB {
+	return NewWithOptions(t, Options{})
+}
+
+// NewWithOptions gets an empty database for the test using the given options,
+// dropped on cleanup
+func NewWithOptions(t testing.TB, opts Options) *DB {
+	t.Helper()
+	client := opts.Client
+	if client == nil {
+		client = memdb.NewClient()
+	}
+
+	db := client.Database(DatabaseName(t.Name()))
+	t.Cleanup(func() {
+		if err := db.Drop(context.Background()); err != nil {
+			t.Errorf("failed to drop test database '%s': %v", db.Name(), err)
+		}
+	})
+
+	return &DB{
+		MongoDB: db,
+		Helper:  base.NewHelperWithOptions(db, opts.HelperOptions),
+		t:       t,
+	}
+}
+
+// DatabaseName gets a unique database name for the test name. Characters
+// the server doesn't allow are replaced and a random suffix is added.
+func DatabaseName(name string) string {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	tag := "_" + hex.EncodeToString(suffix)
+
+	name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '_'
+	}, name)
+	if len(name) > maxNameLength-len(tag) {
+		name = name[:maxNameLength-len(tag)]
+	}
+
+	return name + tag
+}
@@ -0,0 +1,188 @@
+package mongotest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/memdb"
+	"github.com/archy-bold/mongo-go-helper/migration/schema"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type exampleModel struct {
+	ID  primitive.ObjectID `bson:"_id,omitempty"`
+	Str string             `bson:"str"`
+	Num int                `bson:"num"`
+}
+
+func (m exampleModel) Exists() bool {
+	return !m.ID.IsZero()
+}
+
+func (m exampleModel) GetID() interface{} {
+	return m.ID
+}
+
+func (m *exampleModel) SetID(id interface{}) {
+	m.ID = id.(primitive.ObjectID)
+}
+
+// recordingT records failures instead of failing the test
+type recordingT struct {
+	testing.TB
+	failures []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+var databaseNameTests = map[string]struct {
+	name     string
+	expected string
+}{
+	"plain":     {"Test_Find", "Test_Find_"},
+	"subtest":   {"Test_Find/no matches", "Test_Find_no_matches_"},
+	"forbidden": {`Test/a.b$c"d*e<f>g:h|i?j\k`, "Test_a_b_c_d_e_f_g_h_i_j_k_"},
+	"long":      {strings.Repeat("a", 100), strings.Repeat("a", 54) + "_"},
+}
+
+func Test_DatabaseName(t *testing.T) {
+	for tn, tt := range databaseNameTests {
+		name := DatabaseName(tt.name)
+
+		assert.Truef(t, strings.HasPrefix(name, tt.expected), "Expected name '%s' to start with '%s' on test '%s'", name, tt.expected, tn)
+		assert.Lenf(t, name, len(tt.expected)+8, "Expected an 8 character suffix on test '%s'", tn)
+		assert.NotEqualf(t, name, DatabaseName(tt.name), "Expected unique names on test '%s'", tn)
+	}
+}
+
+func Test_New(t *testing.T) {
+	ctx := context.Background()
+	client := memdb.NewClient()
+
+	var first, second *DB
+	t.Run("first", func(t *testing.T) {
+		first = NewWithOptions(t, Options{Client: client})
+		first.Collection("test").InsertOne(ctx, bson.M{"a": 1})
+		assert.True(t, strings.HasPrefix(first.Name(), "Test_New_first_"))
+	})
+	t.Run("second", func(t *testing.T) {
+		second = NewWithOptions(t, Options{Client: client})
+		AssertNoDocument(t, second, "test", bson.M{}, "Expected databases to be isolated")
+	})
+
+	names, _ := client.Database(first.Name()).ListCollectionNames(ctx, bson.M{})
+	assert.Empty(t, names, "Expected the database to be dropped after the test")
+}
+
+var readFixturesTests = map[string]struct {
+	path     string
+	expected map[string]int
+	err      string
+}{
+	"collections": {"testdata/fixtures.json", map[string]int{"users": 2, "orders": 1}, ""},
+	"array":       {"testdata/products.json", map[string]int{"products": 2}, ""},
+	"not array":   {"testdata/invalid.json", nil, "failed to parse fixtures 'testdata/invalid.json': collection 'users' is not an array"},
+	"missing":     {"testdata/missing.json", nil, "open testdata/missing.json: no such file or directory"},
+}
+
+func Test_ReadFixtures(t *testing.T) {
+	for tn, tt := range readFixturesTests {
+		fixtures, err := ReadFixtures(tt.path)
+
+		if tt.err == "" {
+			assert.Nilf(t, err, "Expected nil err for ReadFixtures on test '%s'", tn)
+			counts := make(map[string]int)
+			for _, f := range fixtures {
+				counts[f.Collection] = len(f.Documents)
+			}
+			assert.Equalf(t, tt.expected, counts, "Expected documents to match for ReadFixtures on test '%s'", tn)
+		} else {
+			assert.EqualErrorf(t, err, tt.err, "Expected error for ReadFixtures on test '%s'", tn)
+		}
+	}
+}
+
+func Test_LoadFixtures(t *testing.T) {
+	db := New(t)
+	db.LoadFixtures("testdata/fixtures.json", "testdata/products.json")
+
+	id, _ := primitive.ObjectIDFromHex("5e8f8f8f8f8f8f8f8f8f8f81")
+	AssertDocumentExists(t, db, "users", bson.M{"_id": id, "name": "alice"})
+	AssertCount(t, db, "users", bson.M{}, 2)
+	AssertDocumentExists(t, db, "orders", bson.M{"user": id})
+	AssertCount(t, db, "products", bson.M{"price": bson.M{"$gt": 100}}, 1)
+}
+
+func Test_Seed(t *testing.T) {
+	db := New(t)
+	task := &schema.SeedTableTask{
+		Task:  schema.Task{Collection: "test"},
+		Items: []base.ModelInterface{&exampleModel{Str: "one", Num: 1}, &exampleModel{Str: "two", Num: 2}},
+		FindFilterFn: func(item interface{}) (interface{}, error) {
+			return bson.M{"num": item.(*exampleModel).Num}, nil
+		},
+		Model: &exampleModel{},
+	}
+
+	db.Seed(task)
+
+	assert.Equal(t, schema.SeedStats{Inserted: 2}, task.Stats)
+	AssertDocumentExists(t, db, "test", bson.M{"str": "two", "num": 2})
+	AssertCount(t, db, "test", bson.M{}, 2)
+}
+
+var assertTests = map[string]struct {
+	assert   func(t testing.TB, db base.MongoDB) bool
+	expected string
+}{
+	"exists": {
+		func(t testing.TB, db base.MongoDB) bool { return AssertDocumentExists(t, db, "test", bson.M{"num": 1}) },
+		"",
+	},
+	"does not exist": {
+		func(t testing.TB, db base.MongoDB) bool { return AssertDocumentExists(t, db, "test", bson.M{"num": 3}) },
+		`Expected a document in 'test' matching {"num":3}`,
+	},
+	"no document": {
+		func(t testing.TB, db base.MongoDB) bool { return AssertNoDocument(t, db, "test", bson.M{"num": 3}) },
+		"",
+	},
+	"count": {
+		func(t testing.TB, db base.MongoDB) bool { return AssertCount(t, db, "test", bson.M{}, 2) },
+		"",
+	},
+	"wrong count": {
+		func(t testing.TB, db base.MongoDB) bool { return AssertCount(t, db, "test", bson.M{"num": 1}, 2) },
+		`Expected 2 documents in 'test' matching {"num":1}, found 1`,
+	},
+	"bad filter": {
+		func(t testing.TB, db base.MongoDB) bool { return AssertCount(t, db, "test", bson.M{"$bad": 1}, 0) },
+		"unknown top level operator: $bad",
+	},
+}
+
+func Test_Assertions(t *testing.T) {
+	db := New(t)
+	db.Collection("test").InsertMany(context.Background(), []interface{}{bson.M{"num": 1}, bson.M{"num": 2}})
+
+	for tn, tt := range assertTests {
+		rt := &recordingT{TB: t}
+		ok := tt.assert(rt, db)
+
+		assert.Equalf(t, tt.expected == "", ok, "Expected the assertion result to match on test '%s'", tn)
+		if tt.expected == "" {
+			assert.Emptyf(t, rt.failures, "Expected no failures on test '%s'", tn)
+		} else if assert.Lenf(t, rt.failures, 1, "Expected one failure on test '%s'", tn) {
+			assert.Containsf(t, rt.failures[0], tt.expected, "Expected the failure message to match on test '%s'", tn)
+		}
+	}
+}
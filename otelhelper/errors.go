@@ -0,0 +1,62 @@
+package otelhelper
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// errorKinds are the helper's kinds of error and their descriptions
+var errorKinds = []struct {
+	err  error
+	name string
+}{
+	{base.ErrDuplicateKey, "DuplicateKey"},
+	{base.ErrValidation, "Validation"},
+	{base.ErrTimeout, "Timeout"},
+	{base.ErrConflict, "Conflict"},
+	{base.ErrWriteConcern, "WriteConcern"},
+	{base.ErrNotFound, "NotFound"},
+}
+
+// errorType gets a low cardinality description of the error for metrics
+func errorType(err error) string {
+	switch err {
+	case base.ErrNoMatches:
+		return "NoMatches"
+	case mongo.ErrNoDocuments:
+		return "NoDocuments"
+	}
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind.err) {
+			return kind.name
+		}
+	}
+
+	var (
+		ce  mongo.CommandError
+		we  mongo.WriteException
+		bwe mongo.BulkWriteException
+	)
+	switch {
+	case errors.As(err, &ce):
+		if ce.Name != "" {
+			return ce.Name
+		}
+		return strconv.Itoa(int(ce.Code))
+	case errors.As(err, &we):
+		if len(we.WriteErrors) > 0 {
+			return strconv.Itoa(we.WriteErrors[0].Code)
+		}
+		return "WriteConcernError"
+	case errors.As(err, &bwe):
+		if len(bwe.WriteErrors) > 0 {
+			return strconv.Itoa(bwe.WriteErrors[0].Code)
+		}
+		return "WriteConcernError"
+	}
+	return fmt.Sprintf("%T", err)
+}
@@ -0,0 +1,209 @@
+// Package otelhelper instruments MongoHelpers and migrators with
+// OpenTelemetry. Each helper operation gets a client span with the
+// db.system, db.operation and db.collection attributes, a latency histogram
+// and an error counter, and each migration task gets a span.
+//
+// It's a separate module so the helper doesn't depend on OpenTelemetry.
+package otelhelper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/migration/schema"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the tracers and meters
+const ScopeName = "github.com/archy-bold/mongo-go-helper/otelhelper"
+
+// Options configures the instrumentation
+type Options struct {
+	// TracerProvider creates the tracer. Defaults to the global provider.
+	TracerProvider trace.TracerProvider
+	// MeterProvider creates the meter. Defaults to the global provider.
+	MeterProvider metric.MeterProvider
+	// DatabaseName is added to spans and metrics as db.name when set
+	DatabaseName string
+	// Statement adds the filter to spans as db.statement, redacted with
+	// base.RedactFilter unless Redact is set
+	Statement bool
+	// Redact is applied to filters before they're added to spans
+	Redact func(filter interface{}) interface{}
+}
+
+func (o Options) withDefaults() Options {
+	if o.TracerProvider == nil {
+		o.TracerProvider = otel.GetTracerProvider()
+	}
+	if o.MeterProvider == nil {
+		o.MeterProvider = otel.GetMeterProvider()
+	}
+	if o.Redact == nil {
+		o.Redact = base.RedactFilter
+	}
+	return o
+}
+
+// attributes gets the attributes common to every span and metric
+func (o Options) attributes(extra ...attribute.KeyValue) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{attribute.String("db.system", "mongodb")}
+	if o.DatabaseName != "" {
+		attrs = append(attrs, attribute.String("db.name", o.DatabaseName))
+	}
+	return append(attrs, extra...)
+}
+
+// spanKey is the context key of the span started by a hook
+type spanKey struct{}
+
+type hook struct {
+	opts     Options
+	tracer   trace.Tracer
+	duration metric.Float64Histogram
+	errors   metric.Int64Counter
+}
+
+// NewHook gets a hook tracing and measuring each helper operation
+func NewHook(opts Options) (base.Hook, error) {
+	opts = opts.withDefaults()
+	meter := opts.MeterProvider.Meter(ScopeName)
+
+	duration, err := meter.Float64Histogram("db.client.operation.duration",
+		metric.WithDescription("Duration of MongoHelper operations"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	errors, err := meter.Int64Counter("db.client.operation.errors",
+		metric.WithDescription("Number of failed MongoHelper operations"),
+		metric.WithUnit("{error}"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &hook{opts, opts.TracerProvider.Tracer(ScopeName), duration, errors}, nil
+}
+
+// Wrap gets the helper with each operation traced and measured
+func Wrap(h base.MongoHelper, opts Options) (base.MongoHelper, error) {
+	hk, err := NewHook(opts)
+	if err != nil {
+		return nil, err
+	}
+	return base.WithHooks(h, hk), nil
+}
+
+func (h *hook) Before(ctx context.Context, op *base.Operation) context.Context {
+	attrs := h.opts.attributes(
+		attribute.String("db.operation", op.Name),
+		attribute.String("db.collection", op.Collection),
+	)
+	if op.Index != "" {
+		attrs = append(attrs, attribute.String("db.mongodb.index", op.Index))
+	}
+	if h.opts.Statement && op.Filter != nil {
+		attrs = append(attrs, attribute.String("db.statement", base.FormatValue(h.opts.Redact(op.Filter))))
+	}
+
+	ctx, span := h.tracer.Start(ctx, op.Name+" "+op.Collection,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(op.Start),
+		trace.WithAttributes(attrs...))
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+func (h *hook) After(ctx context.Context, op *base.Operation) {
+	attrs := h.opts.attributes(
+		attribute.String("db.operation", op.Name),
+		attribute.String("db.collection", op.Collection),
+	)
+	if op.Err != nil {
+		attrs = append(attrs, attribute.String("error.type", errorType(op.Err)))
+		h.errors.Add(ctx, 1, metric.WithAttributes(attrs...))
+	}
+	h.duration.Record(ctx, op.Duration.Seconds(), metric.WithAttributes(attrs...))
+
+	span, ok := ctx.Value(spanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+	for _, c := range []struct {
+		key string
+		n   int64
+	}{
+		{"db.mongodb.returned", op.Returned},
+		{"db.mongodb.inserted", op.Inserted},
+		{"db.mongodb.matched", op.Matched},
+		{"db.mongodb.modified", op.Modified},
+		{"db.mongodb.deleted", op.Deleted},
+		{"db.mongodb.upserted", op.Upserted},
+	} {
+		if c.n > 0 {
+			span.SetAttributes(attribute.Int64(c.key, c.n))
+		}
+	}
+	endSpan(span, op.Err, op.Start.Add(op.Duration))
+}
+
+type taskHook struct {
+	opts   Options
+	tracer trace.Tracer
+}
+
+// NewTaskHook gets a migrator hook tracing each migration task
+func NewTaskHook(opts Options) schema.TaskHook {
+	opts = opts.withDefaults()
+	return &taskHook{opts, opts.TracerProvider.Tracer(ScopeName)}
+}
+
+func (h *taskHook) BeforeTask(ctx context.Context, name string, task schema.TaskContract) context.Context {
+	attrs := h.opts.attributes(
+		attribute.String("migration.task", name),
+		attribute.String("migration.task.type", fmt.Sprintf("%T", task)),
+	)
+	if coll := taskCollection(task); coll != "" {
+		attrs = append(attrs, attribute.String("db.collection", coll))
+	}
+
+	ctx, span := h.tracer.Start(ctx, "migration "+name, trace.WithAttributes(attrs...))
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+func (h *taskHook) AfterTask(ctx context.Context, name string, task schema.TaskContract, err error) {
+	span, ok := ctx.Value(spanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+	if seed, ok := task.(*schema.SeedTableTask); ok {
+		span.SetAttributes(
+			attribute.Int("migration.seed.inserted", seed.Stats.Inserted),
+			attribute.Int("migration.seed.updated", seed.Stats.Updated),
+			attribute.Int("migration.seed.unchanged", seed.Stats.Unchanged),
+		)
+	}
+	endSpan(span, err, time.Now())
+}
+
+// endSpan ends the span, recording the error if there is one
+func endSpan(span trace.Span, err error, end time.Time) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(end))
+}
+
+// taskCollection gets the collection of tasks embedding schema.Task
+func taskCollection(task schema.TaskContract) string {
+	if t, ok := task.(schema.CollectionTask); ok {
+		return t.GetCollection()
+	}
+	return ""
+}
@@ -0,0 +1,213 @@
+package otelhelper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/memdb"
+	"github.com/archy-bold/mongo-go-helper/migration"
+	"github.com/archy-bold/mongo-go-helper/migration/schema"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type exampleModel struct {
+	ID  primitive.ObjectID `bson:"_id,omitempty"`
+	Str string             `bson:"str"`
+	Num int                `bson:"num"`
+}
+
+func (m exampleModel) Exists() bool {
+	return !m.ID.IsZero()
+}
+
+func (m exampleModel) GetID() interface{} {
+	return m.ID
+}
+
+func (m *exampleModel) SetID(id interface{}) {
+	m.ID = id.(primitive.ObjectID)
+}
+
+// setupProviders gets providers recording spans and metrics in memory
+func setupProviders() (Options, *tracetest.SpanRecorder, *sdkmetric.ManualReader) {
+	sr := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	opts := Options{
+		TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)),
+		MeterProvider:  sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+		DatabaseName:   "test",
+	}
+	return opts, sr, reader
+}
+
+// spanAttributes gets the span's attributes as a map
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+// findMetric gets the named metric from the collected metrics
+func findMetric(rm metricdata.ResourceMetrics, name string) *metricdata.Metrics {
+	for _, sm := range rm.ScopeMetrics {
+		for i, m := range sm.Metrics {
+			if m.Name == name {
+				return &sm.Metrics[i]
+			}
+		}
+	}
+	return nil
+}
+
+func Test_Wrap(t *testing.T) {
+	ctx := context.Background()
+	opts, sr, reader := setupProviders()
+	opts.Statement = true
+	h, err := Wrap(base.NewHelper(memdb.NewDatabase("test")), opts)
+	assert.Nil(t, err)
+
+	h.InsertOne(ctx, "items", &exampleModel{Str: "a", Num: 1})
+	h.Find(ctx, "items", bson.M{"str": "a"}, exampleModel{}, base.FindOptions{})
+	err = h.UpdateOne(ctx, "items", bson.M{"str": "z"}, &exampleModel{})
+	assert.Equal(t, base.ErrNoMatches, err)
+
+	// Spans
+	spans := sr.Ended()
+	if !assert.Len(t, spans, 3) {
+		return
+	}
+	assert.Equal(t, "InsertOne items", spans[0].Name())
+	assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind())
+	assert.Equal(t, "Find items", spans[1].Name())
+	attrs := spanAttributes(spans[1])
+	assert.Equal(t, "mongodb", attrs["db.system"].AsString())
+	assert.Equal(t, "test", attrs["db.name"].AsString())
+	assert.Equal(t, "Find", attrs["db.operation"].AsString())
+	assert.Equal(t, "items", attrs["db.collection"].AsString())
+	assert.Equal(t, `{"str":"?"}`, attrs["db.statement"].AsString())
+	assert.Equal(t, int64(1), attrs["db.mongodb.returned"].AsInt64())
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+	assert.Equal(t, codes.Error, spans[2].Status().Code)
+	assert.Equal(t, base.ErrNoMatches.Error(), spans[2].Status().Description)
+
+	// Metrics
+	var rm metricdata.ResourceMetrics
+	reader.Collect(ctx, &rm)
+	duration := findMetric(rm, "db.client.operation.duration")
+	if assert.NotNil(t, duration, "Expected a duration histogram") {
+		hist := duration.Data.(metricdata.Histogram[float64])
+		assert.Len(t, hist.DataPoints, 3, "Expected a data point per operation")
+		assert.Equal(t, "s", duration.Unit)
+	}
+	errs := findMetric(rm, "db.client.operation.errors")
+	if assert.NotNil(t, errs, "Expected an error counter") {
+		sum := errs.Data.(metricdata.Sum[int64])
+		if assert.Len(t, sum.DataPoints, 1) {
+			assert.Equal(t, int64(1), sum.DataPoints[0].Value)
+			errType, _ := sum.DataPoints[0].Attributes.Value("error.type")
+			assert.Equal(t, "NoMatches", errType.AsString())
+			op, _ := sum.DataPoints[0].Attributes.Value("db.operation")
+			assert.Equal(t, "UpdateOne", op.AsString())
+		}
+	}
+}
+
+func Test_Wrap_ParentSpan(t *testing.T) {
+	opts, sr, _ := setupProviders()
+	h, _ := Wrap(base.NewHelper(memdb.NewDatabase("test")), opts)
+	ctx, parent := opts.TracerProvider.Tracer("test").Start(context.Background(), "parent")
+
+	h.HasIndex(ctx, "items", "str_index")
+	parent.End()
+
+	spans := sr.Ended()
+	if assert.Len(t, spans, 2) {
+		assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID(), "Expected the operation span to be a child")
+		assert.Equal(t, "str_index", spanAttributes(spans[0])["db.mongodb.index"].AsString())
+		_, hasStatement := spanAttributes(spans[0])["db.statement"]
+		assert.False(t, hasStatement, "Expected no statement by default")
+	}
+}
+
+func Test_TaskHook(t *testing.T) {
+	ctx := context.Background()
+	opts, sr, _ := setupProviders()
+	helper, _ := Wrap(base.NewHelper(memdb.NewDatabase("test")), opts)
+	m := migration.NewMigratorWithOptions(helper, migration.MigratorOptions{
+		Hooks: []schema.TaskHook{NewTaskHook(opts)},
+	})
+
+	m.Run(ctx, map[string]schema.TaskContract{
+		"01_index": &schema.CreateIndexTask{Task: schema.Task{Collection: "items"}, IndexName: "num_index", Keys: bson.M{"num": 1}},
+		"02_seed": &schema.SeedTableTask{
+			Task:  schema.Task{Collection: "items"},
+			Items: []base.ModelInterface{&exampleModel{Str: "a", Num: 1}},
+			FindFilterFn: func(item interface{}) (interface{}, error) {
+				return bson.M{"num": item.(*exampleModel).Num}, nil
+			},
+			Model: &exampleModel{},
+		},
+		"03_unknown": &schema.Task{},
+		"04_drop":    &schema.DropCollectionTask{Task: schema.Task{Collection: "old"}},
+	})
+
+	var tasks []sdktrace.ReadOnlySpan
+	for _, span := range sr.Ended() {
+		if _, ok := spanAttributes(span)["migration.task"]; ok {
+			tasks = append(tasks, span)
+		}
+	}
+	if !assert.Len(t, tasks, 4) {
+		return
+	}
+	assert.Equal(t, "migration 01_index", tasks[0].Name())
+	assert.Equal(t, "*schema.CreateIndexTask", spanAttributes(tasks[0])["migration.task.type"].AsString())
+	assert.Equal(t, "items", spanAttributes(tasks[0])["db.collection"].AsString())
+	assert.Equal(t, int64(1), spanAttributes(tasks[1])["migration.seed.inserted"].AsInt64())
+	assert.Equal(t, codes.Error, tasks[2].Status().Code)
+	assert.Equal(t, "old", spanAttributes(tasks[3])["db.collection"].AsString(), "Expected every task type to get its collection")
+
+	// The helper's spans are children of the task spans
+	for _, span := range sr.Ended() {
+		if span.Name() == "AddIndexIfNotExists items" {
+			assert.Equal(t, tasks[0].SpanContext().SpanID(), span.Parent().SpanID())
+		}
+	}
+}
+
+var errorTypeTests = map[string]struct {
+	err      error
+	expected string
+}{
+	"no matches":   {base.ErrNoMatches, "NoMatches"},
+	"no documents": {mongo.ErrNoDocuments, "NoDocuments"},
+	"duplicate key": {
+		base.MapError(mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}),
+		"DuplicateKey",
+	},
+	"timeout":       {base.MapError(context.DeadlineExceeded), "Timeout"},
+	"command":       {mongo.CommandError{Code: 73, Name: "InvalidNamespace"}, "InvalidNamespace"},
+	"command code":  {mongo.CommandError{Code: 73}, "73"},
+	"write error":   {mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 2}}}, "2"},
+	"write concern": {mongo.BulkWriteException{}, "WriteConcernError"},
+	"other":         {context.Canceled, "*errors.errorString"},
+}
+
+func Test_ErrorType(t *testing.T) {
+	for tn, tt := range errorTypeTests {
+		assert.Equalf(t, tt.expected, errorType(tt.err), "Expected error type to match on test '%s'", tn)
+	}
+}
@@ -0,0 +1,210 @@
+// Package promhelper exposes Prometheus metrics for MongoHelpers, the driver's
+// connection pools and migrators. A Collector is registered like any other
+// collector and plugged in as a helper hook, a pool monitor and a migrator
+// hook:
+//
+//	c := promhelper.NewCollector(promhelper.Options{})
+//	prometheus.MustRegister(c)
+//
+//	h := base.NewHelperWithOptions(db, base.HelperOptions{PoolMonitor: c.PoolMonitor()})
+//	h = base.WithHooks(h, c)
+//	m := migration.NewMigratorWithOptions(h, migration.MigratorOptions{Hooks: []schema.TaskHook{c}})
+//
+// It's a separate module so the helper doesn't depend on Prometheus.
+package promhelper
+
+import (
+	"context"
+	"time"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/migration"
+	"github.com/archy-bold/mongo-go-helper/migration/schema"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// Options configures the collector
+type Options struct {
+	// Namespace prefixes the metric names. Defaults to "mongo".
+	Namespace string
+	// ConstLabels are added to every metric
+	ConstLabels prometheus.Labels
+	// Buckets are the operation duration histogram buckets in seconds.
+	// Defaults to prometheus.DefBuckets.
+	Buckets []float64
+}
+
+// Collector collects the metrics of helper operations, connection pools and
+// migrations. It's a base.Hook and a schema.TaskHook.
+type Collector struct {
+	operations *prometheus.CounterVec
+	errors     *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+
+	poolCreated    *prometheus.CounterVec
+	poolClosed     *prometheus.CounterVec
+	poolCheckouts  *prometheus.CounterVec
+	poolFailures   *prometheus.CounterVec
+	poolCleared    *prometheus.CounterVec
+	poolInUse      *prometheus.GaugeVec
+	poolConnsOpen  *prometheus.GaugeVec
+	migrationTasks *prometheus.GaugeVec
+	lastRunSuccess *prometheus.GaugeVec
+	lastRunTime    *prometheus.GaugeVec
+	lastRunLength  *prometheus.GaugeVec
+}
+
+type runStartKey struct{}
+
+// NewCollector gets a new collector
+func NewCollector(opts Options) *Collector {
+	if opts.Namespace == "" {
+		opts.Namespace = "mongo"
+	}
+	if opts.Buckets == nil {
+		opts.Buckets = prometheus.DefBuckets
+	}
+
+	counter := func(subsystem, name, help string, labels ...string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: opts.Namespace, Subsystem: subsystem, Name: name, Help: help, ConstLabels: opts.ConstLabels,
+		}, labels)
+	}
+	gauge := func(subsystem, name, help string) prometheus.GaugeOpts {
+		return prometheus.GaugeOpts{
+			Namespace: opts.Namespace, Subsystem: subsystem, Name: name, Help: help, ConstLabels: opts.ConstLabels,
+		}
+	}
+
+	return &Collector{
+		operations: counter("helper", "operations_total", "Number of MongoHelper operations.", "method", "collection"),
+		errors:     counter("helper", "operation_errors_total", "Number of failed MongoHelper operations.", "method", "collection"),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   opts.Namespace,
+			Subsystem:   "helper",
+			Name:        "operation_duration_seconds",
+			Help:        "Duration of MongoHelper operations.",
+			ConstLabels: opts.ConstLabels,
+			Buckets:     opts.Buckets,
+		}, []string{"method", "collection"}),
+
+		poolCreated:   counter("pool", "connections_created_total", "Number of connections created.", "address"),
+		poolClosed:    counter("pool", "connections_closed_total", "Number of connections closed.", "address", "reason"),
+		poolCheckouts: counter("pool", "checkouts_total", "Number of connections checked out.", "address"),
+		poolFailures:  counter("pool", "checkout_failures_total", "Number of failed connection checkouts.", "address", "reason"),
+		poolCleared:   counter("pool", "cleared_total", "Number of times the pool was cleared.", "address"),
+		poolInUse:     prometheus.NewGaugeVec(gauge("pool", "connections_in_use", "Number of connections checked out."), []string{"address"}),
+		poolConnsOpen: prometheus.NewGaugeVec(gauge("pool", "connections_open", "Number of open connections."), []string{"address"}),
+
+		migrationTasks: prometheus.NewGaugeVec(gauge("migration", "tasks", "Number of tasks in the last run by state."), []string{"tenant", "state"}),
+		lastRunSuccess: prometheus.NewGaugeVec(gauge("migration", "last_run_success", "Whether the last run succeeded."), []string{"tenant"}),
+		lastRunTime:    prometheus.NewGaugeVec(gauge("migration", "last_run_timestamp_seconds", "When the last run finished."), []string{"tenant"}),
+		lastRunLength:  prometheus.NewGaugeVec(gauge("migration", "last_run_duration_seconds", "Duration of the last run."), []string{"tenant"}),
+	}
+}
+
+// collectors gets every metric of the collector
+func (c *Collector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.operations, c.errors, c.duration,
+		c.poolCreated, c.poolClosed, c.poolCheckouts, c.poolFailures, c.poolCleared, c.poolInUse, c.poolConnsOpen,
+		c.migrationTasks, c.lastRunSuccess, c.lastRunTime, c.lastRunLength,
+	}
+}
+
+// Describe sends the descriptions of the metrics
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range c.collectors() {
+		m.Describe(ch)
+	}
+}
+
+// Collect sends the metrics
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range c.collectors() {
+		m.Collect(ch)
+	}
+}
+
+// Before does nothing as the operation is measured once it has finished
+func (c *Collector) Before(ctx context.Context, op *base.Operation) context.Context {
+	return ctx
+}
+
+// After records the helper operation
+func (c *Collector) After(ctx context.Context, op *base.Operation) {
+	c.operations.WithLabelValues(op.Name, op.Collection).Inc()
+	c.duration.WithLabelValues(op.Name, op.Collection).Observe(op.Duration.Seconds())
+	if op.Err != nil {
+		c.errors.WithLabelValues(op.Name, op.Collection).Inc()
+	}
+}
+
+// PoolMonitor gets a pool monitor recording connection pool events, to be
+// set as the PoolMonitor of the helper's options
+func (c *Collector) PoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{Event: c.poolEvent}
+}
+
+func (c *Collector) poolEvent(e *event.PoolEvent) {
+	switch e.Type {
+	case event.ConnectionCreated:
+		c.poolCreated.WithLabelValues(e.Address).Inc()
+		c.poolConnsOpen.WithLabelValues(e.Address).Inc()
+	case event.ConnectionClosed:
+		c.poolClosed.WithLabelValues(e.Address, e.Reason).Inc()
+		c.poolConnsOpen.WithLabelValues(e.Address).Dec()
+	case event.GetSucceeded:
+		c.poolCheckouts.WithLabelValues(e.Address).Inc()
+		c.poolInUse.WithLabelValues(e.Address).Inc()
+	case event.ConnectionReturned:
+		c.poolInUse.WithLabelValues(e.Address).Dec()
+	case event.GetFailed:
+		c.poolFailures.WithLabelValues(e.Address, e.Reason).Inc()
+	case event.PoolCleared:
+		c.poolCleared.WithLabelValues(e.Address).Inc()
+	}
+}
+
+// BeforeRun resets the tenant's task gauges with every task pending. The
+// tenant is the database migrated by migration.RunTenants, or empty for
+// other runs.
+func (c *Collector) BeforeRun(ctx context.Context, names []string) context.Context {
+	tenant := migration.TenantFromContext(ctx)
+	c.migrationTasks.WithLabelValues(tenant, "pending").Set(float64(len(names)))
+	c.migrationTasks.WithLabelValues(tenant, "applied").Set(0)
+	c.migrationTasks.WithLabelValues(tenant, "failed").Set(0)
+	return context.WithValue(ctx, runStartKey{}, time.Now())
+}
+
+// BeforeTask does nothing as the task is recorded once it has finished
+func (c *Collector) BeforeTask(ctx context.Context, name string, task schema.TaskContract) context.Context {
+	return ctx
+}
+
+// AfterTask moves the task from pending to applied or failed
+func (c *Collector) AfterTask(ctx context.Context, name string, task schema.TaskContract, err error) {
+	tenant := migration.TenantFromContext(ctx)
+	c.migrationTasks.WithLabelValues(tenant, "pending").Dec()
+	if err != nil {
+		c.migrationTasks.WithLabelValues(tenant, "failed").Inc()
+	} else {
+		c.migrationTasks.WithLabelValues(tenant, "applied").Inc()
+	}
+}
+
+// AfterRun records the status of the tenant's run
+func (c *Collector) AfterRun(ctx context.Context, err error) {
+	tenant := migration.TenantFromContext(ctx)
+	now := time.Now()
+	if err != nil {
+		c.lastRunSuccess.WithLabelValues(tenant).Set(0)
+	} else {
+		c.lastRunSuccess.WithLabelValues(tenant).Set(1)
+	}
+	c.lastRunTime.WithLabelValues(tenant).Set(float64(now.UnixNano()) / 1e9)
+	if start, ok := ctx.Value(runStartKey{}).(time.Time); ok {
+		c.lastRunLength.WithLabelValues(tenant).Set(now.Sub(start).Seconds())
+	}
+}
@@ -0,0 +1,200 @@
+package promhelper
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/memdb"
+	"github.com/archy-bold/mongo-go-helper/migration"
+	"github.com/archy-bold/mongo-go-helper/migration/schema"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+type exampleModel struct {
+	ID  primitive.ObjectID `bson:"_id,omitempty"`
+	Str string             `bson:"str"`
+	Num int                `bson:"num"`
+}
+
+func (m exampleModel) Exists() bool {
+	return !m.ID.IsZero()
+}
+
+func (m exampleModel) GetID() interface{} {
+	return m.ID
+}
+
+func (m *exampleModel) SetID(id interface{}) {
+	m.ID = id.(primitive.ObjectID)
+}
+
+func Test_Collector_Register(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	err := reg.Register(NewCollector(Options{ConstLabels: prometheus.Labels{"app": "test"}}))
+	assert.Nil(t, err, "Expected nil err registering the collector")
+
+	problems, err := testutil.CollectAndLint(NewCollector(Options{}))
+	assert.Nil(t, err)
+	assert.Empty(t, problems, "Expected the metrics to follow the naming conventions")
+}
+
+func Test_Collector_Helper(t *testing.T) {
+	ctx := context.Background()
+	c := NewCollector(Options{Namespace: "app"})
+	h := base.WithHooks(base.NewHelper(memdb.NewDatabase("test")), c)
+
+	h.InsertOne(ctx, "items", &exampleModel{Str: "a", Num: 1})
+	h.InsertOne(ctx, "items", &exampleModel{Str: "b", Num: 2})
+	h.Find(ctx, "items", bson.M{}, exampleModel{}, base.FindOptions{})
+	h.UpdateOne(ctx, "items", bson.M{"str": "z"}, &exampleModel{})
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(c.operations.WithLabelValues("InsertOne", "items")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.operations.WithLabelValues("Find", "items")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.errors.WithLabelValues("UpdateOne", "items")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(c.errors.WithLabelValues("InsertOne", "items")))
+	assert.Equal(t, 3, testutil.CollectAndCount(c, "app_helper_operation_duration_seconds"), "Expected a histogram per method and collection")
+}
+
+func Test_Collector_Pool(t *testing.T) {
+	c := NewCollector(Options{})
+	monitor := c.PoolMonitor()
+	addr := "localhost:27017"
+
+	for _, e := range []*event.PoolEvent{
+		{Type: event.PoolCreated, Address: addr},
+		{Type: event.ConnectionCreated, Address: addr},
+		{Type: event.ConnectionCreated, Address: addr},
+		{Type: event.GetSucceeded, Address: addr},
+		{Type: event.GetSucceeded, Address: addr},
+		{Type: event.ConnectionReturned, Address: addr},
+		{Type: event.GetFailed, Address: addr, Reason: event.ReasonTimedOut},
+		{Type: event.ConnectionClosed, Address: addr, Reason: event.ReasonIdle},
+		{Type: event.PoolCleared, Address: addr},
+	} {
+		monitor.Event(e)
+	}
+
+	expected := `
+# HELP mongo_pool_checkout_failures_total Number of failed connection checkouts.
+# TYPE mongo_pool_checkout_failures_total counter
+mongo_pool_checkout_failures_total{address="localhost:27017",reason="timeout"} 1
+# HELP mongo_pool_checkouts_total Number of connections checked out.
+# TYPE mongo_pool_checkouts_total counter
+mongo_pool_checkouts_total{address="localhost:27017"} 2
+# HELP mongo_pool_cleared_total Number of times the pool was cleared.
+# TYPE mongo_pool_cleared_total counter
+mongo_pool_cleared_total{address="localhost:27017"} 1
+# HELP mongo_pool_connections_closed_total Number of connections closed.
+# TYPE mongo_pool_connections_closed_total counter
+mongo_pool_connections_closed_total{address="localhost:27017",reason="idle"} 1
+# HELP mongo_pool_connections_created_total Number of connections created.
+# TYPE mongo_pool_connections_created_total counter
+mongo_pool_connections_created_total{address="localhost:27017"} 2
+# HELP mongo_pool_connections_in_use Number of connections checked out.
+# TYPE mongo_pool_connections_in_use gauge
+mongo_pool_connections_in_use{address="localhost:27017"} 1
+# HELP mongo_pool_connections_open Number of open connections.
+# TYPE mongo_pool_connections_open gauge
+mongo_pool_connections_open{address="localhost:27017"} 1
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"mongo_pool_checkout_failures_total", "mongo_pool_checkouts_total", "mongo_pool_cleared_total",
+		"mongo_pool_connections_closed_total", "mongo_pool_connections_created_total",
+		"mongo_pool_connections_in_use", "mongo_pool_connections_open")
+	assert.Nil(t, err, "Expected the pool metrics to match")
+}
+
+func Test_Collector_Migrations(t *testing.T) {
+	ctx := context.Background()
+	c := NewCollector(Options{})
+	m := migration.NewMigratorWithOptions(base.NewHelper(memdb.NewDatabase("test")), migration.MigratorOptions{
+		Hooks: []schema.TaskHook{c},
+	})
+	tasks := map[string]schema.TaskContract{
+		"01_index": &schema.CreateIndexTask{Task: schema.Task{Collection: "items"}, IndexName: "num_index", Keys: bson.M{"num": 1}},
+		"02_index": &schema.CreateIndexTask{Task: schema.Task{Collection: "items"}, IndexName: "str_index", Keys: bson.M{"str": 1}},
+	}
+
+	// Successful run
+	err := m.Run(ctx, tasks)
+	assert.Nil(t, err)
+	assert.Equal(t, 2.0, testutil.ToFloat64(c.migrationTasks.WithLabelValues("", "applied")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(c.migrationTasks.WithLabelValues("", "pending")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.lastRunSuccess.WithLabelValues("")))
+	assert.NotZero(t, testutil.ToFloat64(c.lastRunTime.WithLabelValues("")), "Expected the last run time to be set")
+
+	// Failing run
+	tasks["03_unknown"] = &schema.Task{}
+	err = m.Run(ctx, tasks)
+	assert.NotNil(t, err)
+	assert.Equal(t, 2.0, testutil.ToFloat64(c.migrationTasks.WithLabelValues("", "applied")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.migrationTasks.WithLabelValues("", "failed")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(c.lastRunSuccess.WithLabelValues("")))
+}
+
+// slowTask is a hook making each tenant's run take at least the delay
+type slowTask struct {
+	delay time.Duration
+}
+
+func (h slowTask) BeforeTask(ctx context.Context, name string, task schema.TaskContract) context.Context {
+	time.Sleep(h.delay)
+	return ctx
+}
+
+func (h slowTask) AfterTask(ctx context.Context, name string, task schema.TaskContract, err error) {}
+
+func Test_Collector_ConcurrentTenants(t *testing.T) {
+	ctx := context.Background()
+	c := NewCollector(Options{})
+	tenants := []string{"tenant_a", "tenant_b", "tenant_c"}
+
+	start := time.Now()
+	_, err := migration.RunTenants(ctx, memdb.NewClient(), func(database string) map[string]schema.TaskContract {
+		tasks := map[string]schema.TaskContract{
+			"01_index": &schema.CreateIndexTask{Task: schema.Task{Collection: "items"}, IndexName: "num_index", Keys: bson.M{"num": 1}},
+		}
+		if database == "tenant_b" {
+			tasks["02_unknown"] = &schema.Task{}
+		}
+		return tasks
+	}, migration.TenantOptions{
+		Databases:   tenants,
+		Concurrency: len(tenants),
+		Migrator:    migration.MigratorOptions{Hooks: []schema.TaskHook{c, slowTask{20 * time.Millisecond}}},
+	})
+	elapsed := time.Since(start).Seconds()
+
+	assert.NotNil(t, err)
+	for _, tenant := range tenants {
+		failed, success := 0.0, 1.0
+		if tenant == "tenant_b" {
+			failed, success = 1.0, 0.0
+		}
+		assert.Equalf(t, 1.0, testutil.ToFloat64(c.migrationTasks.WithLabelValues(tenant, "applied")), "Expected the applied tasks of '%s'", tenant)
+		assert.Equalf(t, failed, testutil.ToFloat64(c.migrationTasks.WithLabelValues(tenant, "failed")), "Expected the failed tasks of '%s'", tenant)
+		assert.Equalf(t, 0.0, testutil.ToFloat64(c.migrationTasks.WithLabelValues(tenant, "pending")), "Expected no pending tasks for '%s'", tenant)
+		assert.Equalf(t, success, testutil.ToFloat64(c.lastRunSuccess.WithLabelValues(tenant)), "Expected the run status of '%s'", tenant)
+		length := testutil.ToFloat64(c.lastRunLength.WithLabelValues(tenant))
+		assert.Truef(t, length >= 0.02 && length <= elapsed, "Expected the run duration of '%s' to be its own, got %v", tenant, length)
+	}
+}
+
+func Test_Collector_PendingDuringRun(t *testing.T) {
+	ctx := context.Background()
+	c := NewCollector(Options{})
+
+	c.BeforeRun(ctx, []string{"01", "02", "03"})
+	c.AfterTask(ctx, "01", &schema.Task{}, nil)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(c.migrationTasks.WithLabelValues("", "pending")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.migrationTasks.WithLabelValues("", "applied")))
+}
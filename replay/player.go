@@ -0,0 +1,336 @@
+package replay
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/pagination"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Player is a MongoHelper serving recorded calls back in order. Calls that
+// don't match the recording fail the test and return ErrUnexpectedCall.
+type Player struct {
+	t     testing.TB
+	path  string
+	mu    sync.Mutex
+	calls []Call
+	pos   int
+}
+
+// Play gets a player for the calls in the golden file, failing the test if
+// any recorded calls haven't been made when it finishes
+func Play(t testing.TB, path string) *Player {
+	t.Helper()
+	calls, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPlayer(t, calls)
+	p.path = path
+	t.Cleanup(p.finish)
+	return p
+}
+
+// NewPlayer gets a player for the calls, reporting mismatches to the test
+func NewPlayer(t testing.TB, calls []Call) *Player {
+	return &Player{t: t, calls: calls}
+}
+
+// next gets the next recorded call, checking it matches the call being made
+func (p *Player) next(method string, args bson.D) (*Call, bool) {
+	p.t.Helper()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	raw, err := bson.Marshal(args)
+	if err != nil {
+		p.t.Errorf("failed to marshal the arguments of %s: %v", method, err)
+		return nil, false
+	}
+	if p.pos >= len(p.calls) {
+		p.t.Errorf("Unexpected call %d to %s after the end of the recording '%s' with arguments %s",
+			p.pos, method, p.path, formatRaw(raw))
+		return nil, false
+	}
+
+	c := &p.calls[p.pos]
+	p.pos++
+	if c.Method != method {
+		p.t.Errorf("Expected call %d to be %s from the recording '%s' but got %s with arguments %s",
+			p.pos-1, c.Method, p.path, method, formatRaw(raw))
+		return nil, false
+	}
+	if !assert.Equalf(p.t, formatRaw(c.Args), formatRaw(raw),
+		"Expected the arguments of call %d to %s to match the recording '%s'", p.pos-1, method, p.path) {
+		return nil, false
+	}
+
+	return c, true
+}
+
+// finish fails the test if any recorded calls haven't been made
+func (p *Player) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.pos < len(p.calls) {
+		p.t.Errorf("Expected %d more calls from the recording '%s', the next is call %d to %s",
+			len(p.calls)-p.pos, p.path, p.pos, p.calls[p.pos].Method)
+	}
+}
+
+// decode decodes the named recorded result into v
+func (p *Player) decode(c *Call, key string, v interface{}) {
+	p.t.Helper()
+	val, err := c.Results.LookupErr(key)
+	if err == nil && val.Type != bsontype.Null {
+		err = val.Unmarshal(v)
+	}
+	if err != nil {
+		p.t.Errorf("failed to decode the recorded %s of %s: %v", key, c.Method, err)
+	}
+}
+
+// decodeItem gives the item the changes the helper made to it when recorded,
+// such as its generated ID. Items that aren't pointers can't be changed, and
+// older recordings of UpdateOne don't have the item.
+func (p *Player) decodeItem(c *Call, item interface{}) {
+	p.t.Helper()
+	if v := reflect.ValueOf(item); c.Results != nil && v.Kind() == reflect.Ptr && !v.IsNil() {
+		p.decode(c, "item", item)
+	}
+}
+
+// NewClient gets a new unconnected client as it isn't recorded
+func (p *Player) NewClient(uri string) (base.MongoClient, error) {
+	return base.NewHelper(nil).NewClient(uri)
+}
+
+func (p *Player) Find(ctx context.Context, coll string, filter interface{}, item interface{}, opts base.FindOptions) (res pagination.Result, err error) {
+	p.t.Helper()
+	c, ok := p.next("Find", bson.D{
+		{Key: "coll", Value: coll},
+		{Key: "filter", Value: normalize(filter)},
+		{Key: "opts", Value: findOptions(opts)},
+	})
+	if !ok {
+		return res, ErrUnexpectedCall
+	}
+
+	var rec struct {
+		Items         []bson.Raw `bson:"items"`
+		Total         int32      `bson:"total"`
+		PageSize      int32      `bson:"pageSize"`
+		CurrentPage   int32      `bson:"currentPage"`
+		NumberOfPages int32      `bson:"numberOfPages"`
+	}
+	if err := bson.Unmarshal(c.Results, &rec); err != nil {
+		p.t.Errorf("failed to decode the recorded results of Find: %v", err)
+	}
+	res = pagination.Result{
+		Total:         rec.Total,
+		PageSize:      rec.PageSize,
+		CurrentPage:   rec.CurrentPage,
+		NumberOfPages: rec.NumberOfPages,
+	}
+	if rec.Items != nil {
+		res.Items = make([]interface{}, 0, len(rec.Items))
+		for _, doc := range rec.Items {
+			newItem := reflect.New(reflect.TypeOf(item)).Interface()
+			if err := bson.Unmarshal(doc, newItem); err != nil {
+				p.t.Errorf("failed to decode a recorded item of Find: %v", err)
+			}
+			res.Items = append(res.Items, newItem)
+		}
+	}
+
+	return res, c.Error.Err()
+}
+
+func (p *Player) FindOne(ctx context.Context, coll string, filter interface{}, item interface{}) {
+	p.t.Helper()
+	c, ok := p.next("FindOne", bson.D{{Key: "coll", Value: coll}, {Key: "filter", Value: normalize(filter)}})
+	if ok {
+		p.decode(c, "item", item)
+	}
+}
+
+func (p *Player) InsertOne(ctx context.Context, coll string, item interface{}) (interface{}, error) {
+	p.t.Helper()
+	c, ok := p.next("InsertOne", bson.D{{Key: "coll", Value: coll}, {Key: "item", Value: normalize(item)}})
+	if !ok {
+		return nil, ErrUnexpectedCall
+	}
+
+	var rec struct {
+		ID interface{} `bson:"id"`
+	}
+	bson.Unmarshal(c.Results, &rec)
+	p.decodeItem(c, item)
+
+	return rec.ID, c.Error.Err()
+}
+
+// GetIDFromInsertOneResult gets the ID as the helper would as it isn't recorded
+func (p *Player) GetIDFromInsertOneResult(res *mongo.InsertOneResult) (interface{}, error) {
+	return base.NewHelper(nil).GetIDFromInsertOneResult(res)
+}
+
+func (p *Player) UpdateOne(ctx context.Context, coll string, filter interface{}, item interface{}) error {
+	p.t.Helper()
+	c, ok := p.next("UpdateOne", bson.D{{Key: "coll", Value: coll}, {Key: "filter", Value: normalize(filter)}, {Key: "item", Value: normalize(item)}})
+	if !ok {
+		return ErrUnexpectedCall
+	}
+	p.decodeItem(c, item)
+	return c.Error.Err()
+}
+
+func (p *Player) DeleteOne(ctx context.Context, coll string, filter interface{}, item interface{}) error {
+	p.t.Helper()
+	c, ok := p.next("DeleteOne", bson.D{{Key: "coll", Value: coll}, {Key: "filter", Value: normalize(filter)}})
+	if !ok {
+		return ErrUnexpectedCall
+	}
+	return c.Error.Err()
+}
+
+func (p *Player) BulkWrite(ctx context.Context, coll string, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	p.t.Helper()
+	c, ok := p.next("BulkWrite", bulkWriteArgs(coll, models, opts))
+	if !ok {
+		return nil, ErrUnexpectedCall
+	}
+	if c.Results == nil {
+		return nil, c.Error.Err()
+	}
+
+	var rec bulkWriteResult
+	p.decode(c, "result", &rec)
+	res := &mongo.BulkWriteResult{
+		InsertedCount: rec.InsertedCount,
+		MatchedCount:  rec.MatchedCount,
+		ModifiedCount: rec.ModifiedCount,
+		DeletedCount:  rec.DeletedCount,
+		UpsertedCount: rec.UpsertedCount,
+		UpsertedIDs:   make(map[int64]interface{}, len(rec.UpsertedIDs)),
+	}
+	for _, u := range rec.UpsertedIDs {
+		res.UpsertedIDs[u.Index] = u.ID
+	}
+
+	return res, c.Error.Err()
+}
+
+func (p *Player) GetIndex(ctx context.Context, coll string, index string) (*bson.M, error) {
+	p.t.Helper()
+	c, ok := p.next("GetIndex", bson.D{{Key: "coll", Value: coll}, {Key: "index", Value: index}})
+	if !ok {
+		return nil, ErrUnexpectedCall
+	}
+
+	var idx *bson.M
+	p.decode(c, "index", &idx)
+	return idx, c.Error.Err()
+}
+
+func (p *Player) HasIndex(ctx context.Context, coll string, index string) (bool, error) {
+	p.t.Helper()
+	c, ok := p.next("HasIndex", bson.D{{Key: "coll", Value: coll}, {Key: "index", Value: index}})
+	if !ok {
+		return false, ErrUnexpectedCall
+	}
+
+	var has bool
+	p.decode(c, "has", &has)
+	return has, c.Error.Err()
+}
+
+func (p *Player) AddIndexIfNotExists(ctx context.Context, coll string, name string, keys interface{}) error {
+	p.t.Helper()
+	c, ok := p.next("AddIndexIfNotExists", bson.D{{Key: "coll", Value: coll}, {Key: "name", Value: name}, {Key: "keys", Value: normalize(keys)}})
+	if !ok {
+		return ErrUnexpectedCall
+	}
+	return c.Error.Err()
+}
+
+func (p *Player) Aggregate(ctx context.Context, coll string, pipeline mongo.Pipeline) ([]bson.M, error) {
+	p.t.Helper()
+	c, ok := p.next("Aggregate", bson.D{{Key: "coll", Value: coll}, {Key: "pipeline", Value: normalize(pipeline)}})
+	if !ok {
+		return nil, ErrUnexpectedCall
+	}
+
+	var res []bson.M
+	p.decode(c, "results", &res)
+	return res, c.Error.Err()
+}
+
+func (p *Player) GetValidator(ctx context.Context, coll string) (*base.CollectionValidator, error) {
+	p.t.Helper()
+	c, ok := p.next("GetValidator", bson.D{{Key: "coll", Value: coll}})
+	if !ok {
+		return nil, ErrUnexpectedCall
+	}
+
+	var v *base.CollectionValidator
+	p.decode(c, "validator", &v)
+	return v, c.Error.Err()
+}
+
+func (p *Player) SetValidator(ctx context.Context, coll string, v base.CollectionValidator) error {
+	p.t.Helper()
+	c, ok := p.next("SetValidator", validatorArgs(coll, v))
+	if !ok {
+		return ErrUnexpectedCall
+	}
+	return c.Error.Err()
+}
+
+func (p *Player) GetCollection(ctx context.Context, name string) (*base.CollectionInfo, error) {
+	p.t.Helper()
+	c, ok := p.next("GetCollection", bson.D{{Key: "name", Value: name}})
+	if !ok {
+		return nil, ErrUnexpectedCall
+	}
+
+	var info *base.CollectionInfo
+	p.decode(c, "info", &info)
+	return info, c.Error.Err()
+}
+
+func (p *Player) CreateCollection(ctx context.Context, name string, opts base.CollectionOptions) error {
+	p.t.Helper()
+	c, ok := p.next("CreateCollection", bson.D{{Key: "name", Value: name}, {Key: "options", Value: opts}})
+	if !ok {
+		return ErrUnexpectedCall
+	}
+	return c.Error.Err()
+}
+
+func (p *Player) RenameCollection(ctx context.Context, from string, to string, dropTarget bool) error {
+	p.t.Helper()
+	c, ok := p.next("RenameCollection", bson.D{{Key: "from", Value: from}, {Key: "to", Value: to}, {Key: "dropTarget", Value: dropTarget}})
+	if !ok {
+		return ErrUnexpectedCall
+	}
+	return c.Error.Err()
+}
+
+func (p *Player) DropCollection(ctx context.Context, name string) error {
+	p.t.Helper()
+	c, ok := p.next("DropCollection", bson.D{{Key: "name", Value: name}})
+	if !ok {
+		return ErrUnexpectedCall
+	}
+	return c.Error.Err()
+}
@@ -0,0 +1,267 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/pagination"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Recorder is a MongoHelper recording the calls made to the helper it wraps
+type Recorder struct {
+	helper base.MongoHelper
+	mu     sync.Mutex
+	calls  []Call
+	err    error
+}
+
+// NewRecorder gets a recorder wrapping the helper
+func NewRecorder(h base.MongoHelper) *Recorder {
+	return &Recorder{helper: h}
+}
+
+// Record gets a recorder wrapping the helper that writes its calls to the
+// golden file when the test finishes
+func Record(t testing.TB, h base.MongoHelper, path string) *Recorder {
+	r := NewRecorder(h)
+	t.Cleanup(func() {
+		if err := r.Save(path); err != nil {
+			t.Errorf("failed to save recording '%s': %v", path, err)
+		}
+	})
+	return r
+}
+
+// Calls gets the calls recorded so far
+func (r *Recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// Save writes the recorded calls to the golden file
+func (r *Recorder) Save(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	return WriteFile(path, r.calls)
+}
+
+// callArgs are a call's arguments as they were when it was made
+type callArgs struct {
+	raw bson.Raw
+	err error
+}
+
+// snapshot marshals the arguments before the call is made, as the helper
+// may change the items passed to it
+func snapshot(args bson.D) callArgs {
+	raw, err := bson.Marshal(args)
+	return callArgs{raw, err}
+}
+
+// record adds the call, keeping the first error marshalling a call
+func (r *Recorder) record(method string, args callArgs, results bson.D, err error) {
+	c := Call{Method: method, Args: args.raw}
+	var merr error
+	if args.err != nil {
+		merr = fmt.Errorf("failed to record the arguments of %s: %v", method, args.err)
+	} else if results != nil {
+		if c.Results, merr = bson.Marshal(results); merr != nil {
+			merr = fmt.Errorf("failed to record the results of %s: %v", method, merr)
+		}
+	}
+	c.Error = newCallError(err)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if merr != nil && r.err == nil {
+		r.err = merr
+	}
+	r.calls = append(r.calls, c)
+}
+
+// NewClient isn't recorded
+func (r *Recorder) NewClient(uri string) (base.MongoClient, error) {
+	return r.helper.NewClient(uri)
+}
+
+func (r *Recorder) Find(ctx context.Context, coll string, filter interface{}, item interface{}, opts base.FindOptions) (pagination.Result, error) {
+	args := snapshot(bson.D{
+		{Key: "coll", Value: coll},
+		{Key: "filter", Value: normalize(filter)},
+		{Key: "opts", Value: findOptions(opts)},
+	})
+	res, err := r.helper.Find(ctx, coll, filter, item, opts)
+	r.record("Find", args, bson.D{
+		{Key: "items", Value: normalizeAll(res.Items)},
+		{Key: "total", Value: res.Total},
+		{Key: "pageSize", Value: res.PageSize},
+		{Key: "currentPage", Value: res.CurrentPage},
+		{Key: "numberOfPages", Value: res.NumberOfPages},
+	}, err)
+	return res, err
+}
+
+func (r *Recorder) FindOne(ctx context.Context, coll string, filter interface{}, item interface{}) {
+	args := snapshot(bson.D{{Key: "coll", Value: coll}, {Key: "filter", Value: normalize(filter)}})
+	r.helper.FindOne(ctx, coll, filter, item)
+	r.record("FindOne", args, bson.D{{Key: "item", Value: normalize(item)}}, nil)
+}
+
+func (r *Recorder) InsertOne(ctx context.Context, coll string, item interface{}) (interface{}, error) {
+	args := snapshot(bson.D{{Key: "coll", Value: coll}, {Key: "item", Value: normalize(item)}})
+	id, err := r.helper.InsertOne(ctx, coll, item)
+	// The item is recorded too as the helper may have set its ID
+	r.record("InsertOne", args, bson.D{{Key: "id", Value: id}, {Key: "item", Value: normalize(item)}}, err)
+	return id, err
+}
+
+// GetIDFromInsertOneResult isn't recorded
+func (r *Recorder) GetIDFromInsertOneResult(res *mongo.InsertOneResult) (interface{}, error) {
+	return r.helper.GetIDFromInsertOneResult(res)
+}
+
+func (r *Recorder) UpdateOne(ctx context.Context, coll string, filter interface{}, item interface{}) error {
+	args := snapshot(bson.D{{Key: "coll", Value: coll}, {Key: "filter", Value: normalize(filter)}, {Key: "item", Value: normalize(item)}})
+	err := r.helper.UpdateOne(ctx, coll, filter, item)
+	// The item is recorded too as the helper may have set its version and timestamps
+	r.record("UpdateOne", args, bson.D{{Key: "item", Value: normalize(item)}}, err)
+	return err
+}
+
+func (r *Recorder) DeleteOne(ctx context.Context, coll string, filter interface{}, item interface{}) error {
+	args := snapshot(bson.D{{Key: "coll", Value: coll}, {Key: "filter", Value: normalize(filter)}})
+	err := r.helper.DeleteOne(ctx, coll, filter, item)
+	r.record("DeleteOne", args, nil, err)
+	return err
+}
+
+func (r *Recorder) BulkWrite(ctx context.Context, coll string, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	args := snapshot(bulkWriteArgs(coll, models, opts))
+	res, err := r.helper.BulkWrite(ctx, coll, models, opts...)
+
+	var results bson.D
+	if res != nil {
+		br := bulkWriteResult{
+			InsertedCount: res.InsertedCount,
+			MatchedCount:  res.MatchedCount,
+			ModifiedCount: res.ModifiedCount,
+			DeletedCount:  res.DeletedCount,
+			UpsertedCount: res.UpsertedCount,
+			UpsertedIDs:   make([]upsertedID, 0, len(res.UpsertedIDs)),
+		}
+		for i, id := range res.UpsertedIDs {
+			br.UpsertedIDs = append(br.UpsertedIDs, upsertedID{i, id})
+		}
+		sort.Slice(br.UpsertedIDs, func(i, j int) bool { return br.UpsertedIDs[i].Index < br.UpsertedIDs[j].Index })
+		results = bson.D{{Key: "result", Value: br}}
+	}
+	r.record("BulkWrite", args, results, err)
+	return res, err
+}
+
+func (r *Recorder) GetIndex(ctx context.Context, coll string, index string) (*bson.M, error) {
+	args := snapshot(bson.D{{Key: "coll", Value: coll}, {Key: "index", Value: index}})
+	idx, err := r.helper.GetIndex(ctx, coll, index)
+	r.record("GetIndex", args, bson.D{{Key: "index", Value: normalize(idx)}}, err)
+	return idx, err
+}
+
+func (r *Recorder) HasIndex(ctx context.Context, coll string, index string) (bool, error) {
+	args := snapshot(bson.D{{Key: "coll", Value: coll}, {Key: "index", Value: index}})
+	has, err := r.helper.HasIndex(ctx, coll, index)
+	r.record("HasIndex", args, bson.D{{Key: "has", Value: has}}, err)
+	return has, err
+}
+
+func (r *Recorder) AddIndexIfNotExists(ctx context.Context, coll string, name string, keys interface{}) error {
+	args := snapshot(bson.D{{Key: "coll", Value: coll}, {Key: "name", Value: name}, {Key: "keys", Value: normalize(keys)}})
+	err := r.helper.AddIndexIfNotExists(ctx, coll, name, keys)
+	r.record("AddIndexIfNotExists", args, nil, err)
+	return err
+}
+
+func (r *Recorder) Aggregate(ctx context.Context, coll string, pipeline mongo.Pipeline) ([]bson.M, error) {
+	args := snapshot(bson.D{{Key: "coll", Value: coll}, {Key: "pipeline", Value: normalize(pipeline)}})
+	res, err := r.helper.Aggregate(ctx, coll, pipeline)
+	var docs bson.A
+	if res != nil {
+		docs = make(bson.A, len(res))
+		for i, doc := range res {
+			docs[i] = normalize(doc)
+		}
+	}
+	r.record("Aggregate", args, bson.D{{Key: "results", Value: docs}}, err)
+	return res, err
+}
+
+func (r *Recorder) GetValidator(ctx context.Context, coll string) (*base.CollectionValidator, error) {
+	args := snapshot(bson.D{{Key: "coll", Value: coll}})
+	v, err := r.helper.GetValidator(ctx, coll)
+	r.record("GetValidator", args, bson.D{{Key: "validator", Value: v}}, err)
+	return v, err
+}
+
+func (r *Recorder) SetValidator(ctx context.Context, coll string, v base.CollectionValidator) error {
+	args := snapshot(validatorArgs(coll, v))
+	err := r.helper.SetValidator(ctx, coll, v)
+	r.record("SetValidator", args, nil, err)
+	return err
+}
+
+func (r *Recorder) GetCollection(ctx context.Context, name string) (*base.CollectionInfo, error) {
+	args := snapshot(bson.D{{Key: "name", Value: name}})
+	info, err := r.helper.GetCollection(ctx, name)
+	r.record("GetCollection", args, bson.D{{Key: "info", Value: info}}, err)
+	return info, err
+}
+
+func (r *Recorder) CreateCollection(ctx context.Context, name string, opts base.CollectionOptions) error {
+	args := snapshot(bson.D{{Key: "name", Value: name}, {Key: "options", Value: opts}})
+	err := r.helper.CreateCollection(ctx, name, opts)
+	r.record("CreateCollection", args, nil, err)
+	return err
+}
+
+func (r *Recorder) RenameCollection(ctx context.Context, from string, to string, dropTarget bool) error {
+	args := snapshot(bson.D{{Key: "from", Value: from}, {Key: "to", Value: to}, {Key: "dropTarget", Value: dropTarget}})
+	err := r.helper.RenameCollection(ctx, from, to, dropTarget)
+	r.record("RenameCollection", args, nil, err)
+	return err
+}
+
+func (r *Recorder) DropCollection(ctx context.Context, name string) error {
+	args := snapshot(bson.D{{Key: "name", Value: name}})
+	err := r.helper.DropCollection(ctx, name)
+	r.record("DropCollection", args, nil, err)
+	return err
+}
+
+// validatorArgs describes the arguments of SetValidator for recording
+func validatorArgs(coll string, v base.CollectionValidator) bson.D {
+	return bson.D{
+		{Key: "coll", Value: coll},
+		{Key: "validator", Value: normalize(v.Validator)},
+		{Key: "level", Value: v.Level},
+		{Key: "action", Value: v.Action},
+	}
+}
+
+// findOptions describes the find options for recording
+func findOptions(opts base.FindOptions) bson.D {
+	return bson.D{
+		{Key: "pageSize", Value: opts.PageSize},
+		{Key: "page", Value: opts.Page},
+		{Key: "sorting", Value: normalize(opts.Sorting)},
+	}
+}
@@ -0,0 +1,318 @@
+// Package replay records the calls made to a MongoHelper and their results
+// to golden files, then serves them back in tests without a database.
+//
+// Record a run against a real server or an in-memory database once, commit
+// the file, and play it back in the test. Playing fails the test with a diff
+// when the code under test makes different calls or passes different
+// arguments than it did when recorded.
+//
+//	var update = flag.Bool("update", false, "update the golden files")
+//
+//	func Test_Orders(t *testing.T) {
+//		var h base.MongoHelper
+//		if *update {
+//			h = replay.Record(t, base.NewHelper(memdb.NewDatabase("test")), "testdata/orders.json")
+//		} else {
+//			h = replay.Play(t, "testdata/orders.json")
+//		}
+//		// ... run the code under test with h
+//	}
+//
+// Arguments are compared as canonical Extended JSON with map keys sorted, so
+// recordings only replay when the code under test is deterministic. Use
+// fixed IDs or a deterministic IDGenerator rather than new ObjectIDs. Calls
+// are recorded in the order they finish, so concurrent calls may not replay.
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrUnexpectedCall is returned when a played call doesn't match the recording
+var ErrUnexpectedCall = errors.New("call does not match the recording")
+
+// knownErrors are played back as themselves
+var knownErrors = []error{
+	base.ErrNoMatches,
+	base.ErrVersionConflict,
+	base.ErrUnexpectedInsertResult,
+	mongo.ErrNoDocuments,
+	mongo.ErrEmptySlice,
+	mongo.ErrNilDocument,
+}
+
+// Call is a recorded call to a MongoHelper method
+type Call struct {
+	Method  string     `bson:"method"`
+	Args    bson.Raw   `bson:"args"`
+	Results bson.Raw   `bson:"results,omitempty"`
+	Error   *CallError `bson:"error,omitempty"`
+}
+
+// CallError is an error returned by a recorded call. The driver's write and
+// command errors keep their type and codes, other errors only their message.
+type CallError struct {
+	Message           string                   `bson:"message"`
+	Type              string                   `bson:"type,omitempty"`
+	Code              int32                    `bson:"code,omitempty"`
+	Name              string                   `bson:"name,omitempty"`
+	WriteErrors       []mongo.WriteError       `bson:"writeErrors,omitempty"`
+	WriteConcernError *mongo.WriteConcernError `bson:"writeConcernError,omitempty"`
+}
+
+// newCallError gets the error for recording
+func newCallError(err error) *CallError {
+	if err == nil {
+		return nil
+	}
+
+	e := &CallError{Message: err.Error()}
+	var (
+		we  mongo.WriteException
+		bwe mongo.BulkWriteException
+		ce  mongo.CommandError
+	)
+	switch {
+	case errors.As(err, &we):
+		e.Type = "WriteException"
+		e.WriteErrors = we.WriteErrors
+		e.WriteConcernError = we.WriteConcernError
+	case errors.As(err, &bwe):
+		e.Type = "BulkWriteException"
+		for _, we := range bwe.WriteErrors {
+			e.WriteErrors = append(e.WriteErrors, we.WriteError)
+		}
+		e.WriteConcernError = bwe.WriteConcernError
+	case errors.As(err, &ce):
+		e.Type = "CommandError"
+		e.Code = ce.Code
+		e.Name = ce.Name
+		e.Message = ce.Message
+	}
+	return e
+}
+
+// Err gets the error to play back. Known errors are played back as
+// themselves so callers can compare them, and driver errors are mapped to
+// the helper's kinds as the helper does.
+func (e *CallError) Err() error {
+	if e == nil {
+		return nil
+	}
+
+	switch e.Type {
+	case "WriteException":
+		return base.MapError(mongo.WriteException{WriteErrors: e.WriteErrors, WriteConcernError: e.WriteConcernError})
+	case "BulkWriteException":
+		bwe := mongo.BulkWriteException{WriteConcernError: e.WriteConcernError}
+		for _, we := range e.WriteErrors {
+			bwe.WriteErrors = append(bwe.WriteErrors, mongo.BulkWriteError{WriteError: we})
+		}
+		return base.MapError(bwe)
+	case "CommandError":
+		return base.MapError(mongo.CommandError{Code: e.Code, Name: e.Name, Message: e.Message})
+	}
+	for _, err := range knownErrors {
+		if err.Error() == e.Message {
+			return err
+		}
+	}
+	return errors.New(e.Message)
+}
+
+// ReadFile reads the calls recorded in a golden file
+func ReadFile(path string) ([]Call, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Calls []Call `bson:"calls"`
+	}
+	data = append(append([]byte(`{"calls":`), data...), '}')
+	if err := bson.UnmarshalExtJSON(data, true, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse recording '%s': %v", path, err)
+	}
+
+	return file.Calls, nil
+}
+
+// WriteFile writes the calls to a golden file, one indented call at a time
+func WriteFile(path string, calls []Call) error {
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for i, c := range calls {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n  ")
+		b, err := bson.MarshalExtJSON(c, true, false)
+		if err != nil {
+			return fmt.Errorf("failed to write call %d to %s: %v", i, c.Method, err)
+		}
+		buf.Write(indent(b, "  "))
+	}
+	buf.WriteString("\n]\n")
+
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// formatRaw gets the document as indented canonical Extended JSON
+func formatRaw(doc bson.Raw) string {
+	b, err := bson.MarshalExtJSON(doc, true, false)
+	if err != nil {
+		return fmt.Sprintf("%v", doc)
+	}
+	return string(indent(b, ""))
+}
+
+// wrapperPattern matches indented objects with a single string field, such as
+// {"$numberInt": "1"}
+var wrapperPattern = regexp.MustCompile(`\{\n\s*("[^"\n]*": "[^"\n]*")\n\s*\}`)
+
+// indent indents the JSON, keeping single string field objects on one line
+func indent(b []byte, prefix string) []byte {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, b, prefix, "  "); err != nil {
+		return b
+	}
+	return wrapperPattern.ReplaceAll(buf.Bytes(), []byte("{$1}"))
+}
+
+// normalize sorts the keys of maps in the value so it marshals the same way
+// every time
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case bson.M:
+		return sortedDoc(v)
+	case map[string]interface{}:
+		return sortedDoc(v)
+	case *bson.M:
+		if v == nil {
+			return nil
+		}
+		return sortedDoc(*v)
+	case bson.D:
+		d := make(bson.D, len(v))
+		for i, e := range v {
+			d[i] = bson.E{Key: e.Key, Value: normalize(e.Value)}
+		}
+		return d
+	case *bson.D:
+		if v == nil {
+			return nil
+		}
+		return normalize(*v)
+	case bson.A:
+		return normalizeAll(v)
+	case []interface{}:
+		return normalizeAll(v)
+	case mongo.Pipeline:
+		a := make(bson.A, len(v))
+		for i, stage := range v {
+			a[i] = normalize(stage)
+		}
+		return a
+	}
+	return v
+}
+
+func normalizeAll(vs []interface{}) bson.A {
+	if vs == nil {
+		return nil
+	}
+	a := make(bson.A, len(vs))
+	for i, v := range vs {
+		a[i] = normalize(v)
+	}
+	return a
+}
+
+func sortedDoc(m map[string]interface{}) bson.D {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	d := make(bson.D, len(keys))
+	for i, k := range keys {
+		d[i] = bson.E{Key: k, Value: normalize(m[k])}
+	}
+	return d
+}
+
+// bulkWriteArgs describes the arguments of a bulk write for recording,
+// including whether it's ordered only when set
+func bulkWriteArgs(coll string, models []mongo.WriteModel, opts []*options.BulkWriteOptions) bson.D {
+	ms := make(bson.A, len(models))
+	for i, m := range models {
+		ms[i] = writeModel(m)
+	}
+	args := bson.D{{Key: "coll", Value: coll}, {Key: "models", Value: ms}}
+	for _, o := range opts {
+		if o != nil && o.Ordered != nil {
+			args = append(args[:2], bson.E{Key: "ordered", Value: *o.Ordered})
+		}
+	}
+	return args
+}
+
+// writeModel describes the write model for recording
+func writeModel(m mongo.WriteModel) bson.D {
+	switch m := m.(type) {
+	case *mongo.InsertOneModel:
+		return bson.D{{Key: "insertOne", Value: bson.D{{Key: "document", Value: normalize(m.Document)}}}}
+	case *mongo.UpdateOneModel:
+		return bson.D{{Key: "updateOne", Value: bson.D{
+			{Key: "filter", Value: normalize(m.Filter)},
+			{Key: "update", Value: normalize(m.Update)},
+			{Key: "upsert", Value: m.Upsert},
+		}}}
+	case *mongo.UpdateManyModel:
+		return bson.D{{Key: "updateMany", Value: bson.D{
+			{Key: "filter", Value: normalize(m.Filter)},
+			{Key: "update", Value: normalize(m.Update)},
+			{Key: "upsert", Value: m.Upsert},
+		}}}
+	case *mongo.ReplaceOneModel:
+		return bson.D{{Key: "replaceOne", Value: bson.D{
+			{Key: "filter", Value: normalize(m.Filter)},
+			{Key: "replacement", Value: normalize(m.Replacement)},
+			{Key: "upsert", Value: m.Upsert},
+		}}}
+	case *mongo.DeleteOneModel:
+		return bson.D{{Key: "deleteOne", Value: bson.D{{Key: "filter", Value: normalize(m.Filter)}}}}
+	case *mongo.DeleteManyModel:
+		return bson.D{{Key: "deleteMany", Value: bson.D{{Key: "filter", Value: normalize(m.Filter)}}}}
+	}
+	return bson.D{{Key: "unknown", Value: fmt.Sprintf("%T", m)}}
+}
+
+// upsertedID is an upserted ID of a bulk write result with its model index
+type upsertedID struct {
+	Index int64       `bson:"index"`
+	ID    interface{} `bson:"id"`
+}
+
+// bulkWriteResult is a recorded bulk write result
+type bulkWriteResult struct {
+	InsertedCount int64        `bson:"insertedCount"`
+	MatchedCount  int64        `bson:"matchedCount"`
+	ModifiedCount int64        `bson:"modifiedCount"`
+	DeletedCount  int64        `bson:"deletedCount"`
+	UpsertedCount int64        `bson:"upsertedCount"`
+	UpsertedIDs   []upsertedID `bson:"upsertedIds"`
+}
@@ -0,0 +1,240 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/archy-bold/mongo-go-helper/base"
+	"github.com/archy-bold/mongo-go-helper/memdb"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+const goldenFile = "testdata/helper.json"
+
+type exampleModel struct {
+	ID  int64  `bson:"_id"`
+	Str string `bson:"str"`
+	Num int    `bson:"num"`
+}
+
+func (m exampleModel) Exists() bool {
+	return m.ID != 0
+}
+
+func (m exampleModel) GetID() interface{} {
+	return m.ID
+}
+
+func (m *exampleModel) SetID(id interface{}) {
+	m.ID = id.(int64)
+}
+
+// recordingT records failures instead of failing the test
+type recordingT struct {
+	testing.TB
+	failures []string
+}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+// setupHelper gets a helper on an in-memory database with three models
+func setupHelper(ctx context.Context) base.MongoHelper {
+	h := base.NewHelper(memdb.NewDatabase("test"))
+	for i, str := range []string{"c", "a", "b"} {
+		h.InsertOne(ctx, "test", &exampleModel{ID: int64(i + 1), Str: str, Num: i + 1})
+	}
+	return h
+}
+
+// runCalls makes a call to each recorded helper method and gets the results
+func runCalls(ctx context.Context, h base.MongoHelper) []interface{} {
+	var res []interface{}
+	add := func(vs ...interface{}) {
+		res = append(res, vs...)
+	}
+
+	add(h.Find(ctx, "test", bson.M{"num": bson.M{"$gte": 2}, "str": bson.M{"$ne": "z"}}, exampleModel{}, base.FindOptions{
+		PageSize: 1,
+		Sorting:  map[string]interface{}{"str": 1},
+	}))
+	var item exampleModel
+	h.FindOne(ctx, "test", &bson.M{"str": "a"}, &item)
+	add(item)
+	add(h.InsertOne(ctx, "test", &exampleModel{ID: 4, Str: "d", Num: 4}))
+	add(h.InsertOne(ctx, "test", &exampleModel{ID: 4, Str: "d", Num: 4}))
+	add(h.UpdateOne(ctx, "test", bson.M{"_id": 4}, &exampleModel{ID: 4, Str: "e", Num: 5}))
+	add(h.UpdateOne(ctx, "test", bson.M{"_id": 5}, &exampleModel{ID: 5}))
+	add(h.BulkWrite(ctx, "test", []mongo.WriteModel{
+		mongo.NewUpdateManyModel().SetFilter(bson.M{"num": bson.M{"$lt": 3}}).SetUpdate(bson.M{"$inc": bson.M{"num": 10}}),
+		mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": 6}).SetReplacement(bson.M{"str": "f"}).SetUpsert(true),
+		mongo.NewDeleteOneModel().SetFilter(bson.M{"_id": 3}),
+	}))
+	add(h.AddIndexIfNotExists(ctx, "test", "str_index", bson.M{"str": 1}))
+	add(h.HasIndex(ctx, "test", "str_index"))
+	add(h.GetIndex(ctx, "test", "str_index"))
+	add(h.Aggregate(ctx, "test", mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{{Key: "_id", Value: nil}, {Key: "total", Value: bson.M{"$sum": "$num"}}}}},
+	}))
+	add(h.GetValidator(ctx, "validated"))
+	add(h.SetValidator(ctx, "validated", base.CollectionValidator{
+		Validator: bson.D{{Key: "$jsonSchema", Value: bson.D{{Key: "required", Value: bson.A{"str"}}}}},
+		Action:    base.ValidationActionWarn,
+	}))
+	add(h.GetValidator(ctx, "validated"))
+	add(h.CreateCollection(ctx, "capped", base.CollectionOptions{Capped: true, Size: 1024}))
+	add(h.RenameCollection(ctx, "capped", "renamed", false))
+	add(h.GetCollection(ctx, "renamed"))
+	add(h.DropCollection(ctx, "renamed"))
+	add(h.GetCollection(ctx, "renamed"))
+
+	return res
+}
+
+func Test_RecordAndPlay(t *testing.T) {
+	ctx := context.Background()
+	dir, _ := ioutil.TempDir("", "replay")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "helper.json")
+
+	// Record against the in-memory database
+	r := NewRecorder(setupHelper(ctx))
+	expected := runCalls(ctx, r)
+	err := r.Save(path)
+	assert.Nil(t, err, "Expected nil err saving the recording")
+	if *update {
+		r.Save(goldenFile)
+	}
+
+	recorded, _ := ioutil.ReadFile(path)
+	golden, _ := ioutil.ReadFile(goldenFile)
+	assert.Equal(t, string(golden), string(recorded), "Expected the recording to match the golden file, run with -update if the change is intended")
+
+	// Play back without a database
+	actual := runCalls(ctx, Play(t, goldenFile))
+	assert.Equal(t, expected, actual, "Expected the played results to match the recorded ones")
+}
+
+func Test_RecordAndPlay_GeneratedIDs(t *testing.T) {
+	ctx := context.Background()
+	db := memdb.NewDatabase("test")
+	gen := base.NewCounterIDGenerator(base.NewCounter(db, base.CounterOptions{}))
+	calls := func(h base.MongoHelper) []interface{} {
+		item := &exampleModel{Str: "new", Num: 1}
+		id, err := h.InsertOne(ctx, "test", item)
+		item.Str = "changed"
+		updateErr := h.UpdateOne(ctx, "test", bson.M{"_id": item.ID}, item)
+		return []interface{}{id, err, updateErr, *item}
+	}
+
+	// Record with a helper setting the IDs, then play back without one
+	r := NewRecorder(base.NewHelperWithOptions(db, base.HelperOptions{IDGenerator: gen}))
+	recorded := calls(r)
+	rt := &recordingT{TB: t}
+	actual := calls(NewPlayer(rt, r.Calls()))
+
+	assert.Equal(t, exampleModel{ID: 1, Str: "changed", Num: 1}, recorded[3], "Expected the helper to set the ID")
+	assert.Empty(t, rt.failures, "Expected the calls to match the recording made before the helper changed the items")
+	assert.Equal(t, recorded, actual, "Expected the played items to get the recorded changes")
+}
+
+var playMismatchTests = map[string]struct {
+	call     func(ctx context.Context, h base.MongoHelper) error
+	expected string
+}{
+	"matching": {
+		func(ctx context.Context, h base.MongoHelper) error {
+			return h.UpdateOne(ctx, "test", bson.M{"_id": 4}, &exampleModel{ID: 4, Str: "e", Num: 5})
+		},
+		"",
+	},
+	"changed argument": {
+		func(ctx context.Context, h base.MongoHelper) error {
+			return h.UpdateOne(ctx, "test", bson.M{"_id": 4}, &exampleModel{ID: 4, Str: "changed", Num: 5})
+		},
+		`+    "str": "changed",`,
+	},
+	"changed method": {
+		func(ctx context.Context, h base.MongoHelper) error {
+			return h.AddIndexIfNotExists(ctx, "test", "str_index", bson.M{"str": 1})
+		},
+		"Expected call 0 to be UpdateOne from the recording '' but got AddIndexIfNotExists",
+	},
+}
+
+func Test_Play_Mismatch(t *testing.T) {
+	ctx := context.Background()
+	r := NewRecorder(setupHelper(ctx))
+	r.UpdateOne(ctx, "test", bson.M{"_id": 4}, &exampleModel{ID: 4, Str: "e", Num: 5})
+
+	for tn, tt := range playMismatchTests {
+		rt := &recordingT{TB: t}
+		err := tt.call(ctx, NewPlayer(rt, r.Calls()))
+
+		if tt.expected == "" {
+			assert.Emptyf(t, rt.failures, "Expected no failures on test '%s'", tn)
+		} else if assert.Lenf(t, rt.failures, 1, "Expected one failure on test '%s'", tn) {
+			assert.Equalf(t, ErrUnexpectedCall, err, "Expected err to match on test '%s'", tn)
+			assert.Containsf(t, rt.failures[0], tt.expected, "Expected the failure message to match on test '%s'", tn)
+		}
+	}
+}
+
+func Test_Play_CallCount(t *testing.T) {
+	ctx := context.Background()
+	r := NewRecorder(setupHelper(ctx))
+	r.HasIndex(ctx, "test", "str_index")
+	r.HasIndex(ctx, "other", "str_index")
+
+	// Too few calls
+	rt := &recordingT{TB: t}
+	p := NewPlayer(rt, r.Calls())
+	p.HasIndex(ctx, "test", "str_index")
+	p.finish()
+	assert.Equal(t, []string{"Expected 1 more calls from the recording '', the next is call 1 to HasIndex"}, rt.failures)
+
+	// Too many calls
+	rt = &recordingT{TB: t}
+	p = NewPlayer(rt, r.Calls())
+	p.HasIndex(ctx, "test", "str_index")
+	p.HasIndex(ctx, "other", "str_index")
+	_, err := p.HasIndex(ctx, "test", "str_index")
+	assert.Equal(t, ErrUnexpectedCall, err)
+	if assert.Len(t, rt.failures, 1) {
+		assert.Contains(t, rt.failures[0], "Unexpected call 2 to HasIndex after the end of the recording")
+	}
+}
+
+func Test_Play_Errors(t *testing.T) {
+	ctx := context.Background()
+	r := NewRecorder(setupHelper(ctx))
+	r.UpdateOne(ctx, "test", bson.M{"_id": 5}, &exampleModel{ID: 5})
+	r.InsertOne(ctx, "test", &exampleModel{ID: 1})
+	r.GetIndex(ctx, "$bad", "str_index")
+	r.DeleteOne(ctx, "test", bson.M{"_id": 5}, nil)
+
+	p := NewPlayer(t, r.Calls())
+	err := p.UpdateOne(ctx, "test", bson.M{"_id": 5}, &exampleModel{ID: 5})
+	assert.Equal(t, base.ErrNoMatches, err, "Expected known errors to be played back as themselves")
+	_, err = p.InsertOne(ctx, "test", &exampleModel{ID: 1})
+	assert.True(t, errors.Is(err, base.ErrDuplicateKey), "Expected write errors to be mapped to their kind")
+	var we mongo.WriteException
+	if assert.True(t, errors.As(err, &we), "Expected write errors to keep the driver error") {
+		assert.Equal(t, 11000, we.WriteErrors[0].Code)
+	}
+	_, err = p.GetIndex(ctx, "$bad", "str_index")
+	assert.Equal(t, mongo.CommandError{Code: 73, Name: "InvalidNamespace", Message: "Invalid collection name specified 'test.$bad'"}, err, "Expected command errors to keep their code")
+	err = p.DeleteOne(ctx, "test", bson.M{"_id": 5}, nil)
+	assert.Equal(t, base.ErrNoMatches, err)
+}